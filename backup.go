@@ -0,0 +1,821 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/ui"
+)
+
+// backupFormat names the archives gator writes so restore can refuse any other json
+const backupFormat = "gator-backup"
+
+// backupVersion is the archive version backup writes, restore reads it and every older version.
+// Bump it when a field is renamed or its meaning changes, new fields can be added without a bump.
+// Version 2 records who added a feed in added_by, version 1 called it user_id.
+const backupVersion = 2
+
+// archive is everything gator stores, rows refer to each other by the ids they had in the database
+// the backup was made from. Handles are left out, restore hands them out again as things are shown.
+type archive struct {
+	Format           string                  `json:"format"`
+	Version          int                     `json:"version"`
+	CreatedAt        time.Time               `json:"created_at"`
+	Users            []archiveUser           `json:"users"`
+	Feeds            []archiveFeed           `json:"feeds"`
+	Follows          []archiveFollow         `json:"follows"`
+	Categories       []archiveCategory       `json:"categories"`
+	FollowCategories []archiveFollowCategory `json:"follow_categories"`
+	Posts            []archivePost           `json:"posts"`
+	PostStates       []archivePostState      `json:"post_states"`
+	Rules            []archiveRule           `json:"rules"`
+}
+
+type archiveUser struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Admin     bool      `json:"admin,omitempty"`
+}
+
+type archiveFeed struct {
+	ID                uuid.UUID  `json:"id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	Name              string     `json:"name"`
+	URL               string     `json:"url"`
+	AddedBy           *uuid.UUID `json:"added_by,omitempty"`
+	UserID            *uuid.UUID `json:"user_id,omitempty"`
+	LastFetchedAt     *time.Time `json:"last_fetched_at,omitempty"`
+	RetentionMaxAge   *string    `json:"retention_max_age,omitempty"`
+	RetentionMaxPosts *int32     `json:"retention_max_posts,omitempty"`
+}
+
+type archiveFollow struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UserID      uuid.UUID `json:"user_id"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	DisplayName *string   `json:"display_name,omitempty"`
+	Priority    int32     `json:"priority"`
+	Muted       bool      `json:"muted"`
+	Notify      bool      `json:"notify"`
+}
+
+type archiveCategory struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+type archiveFollowCategory struct {
+	FollowID   uuid.UUID `json:"follow_id"`
+	CategoryID uuid.UUID `json:"category_id"`
+}
+
+type archivePost struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Title        string    `json:"title"`
+	URL          string    `json:"url"`
+	Description  *string   `json:"description,omitempty"`
+	PublishedAt  time.Time `json:"published_at"`
+	FeedID       uuid.UUID `json:"feed_id"`
+	Author       *string   `json:"author,omitempty"`
+	Category     *string   `json:"category,omitempty"`
+	CanonicalURL string    `json:"canonical_url"`
+	Simhash      *int64    `json:"simhash,omitempty"`
+	ClusterID    uuid.UUID `json:"cluster_id"`
+}
+
+type archivePostState struct {
+	UserID    uuid.UUID `json:"user_id"`
+	PostID    uuid.UUID `json:"post_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Hidden    bool      `json:"hidden"`
+	Deleted   bool      `json:"deleted"`
+	Flagged   bool      `json:"flagged"`
+	Title     *string   `json:"title,omitempty"`
+}
+
+type archiveRule struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	UserID        uuid.UUID `json:"user_id"`
+	Field         string    `json:"field"`
+	MatchType     string    `json:"match_type"`
+	Pattern       string    `json:"pattern"`
+	CaseSensitive bool      `json:"case_sensitive"`
+	Action        string    `json:"action"`
+	Replacement   *string   `json:"replacement,omitempty"`
+}
+
+// handlerBackup writes every user, feed, follow, category, post, post state and rule to a gzipped json
+// archive. The file is written next to the target and renamed over it, a failed backup leaves no partial file.
+// The archive holds every user's settings so only admins may write one.
+func handlerBackup(s *state, cmd command, user database.User) error {
+	if !user.IsAdmin {
+		return errNotAdmin("back up the database")
+	}
+	path := cmd.String("file")
+	a, err := exportArchive(s)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gator-backup-*")
+	if err != nil {
+		return fmt.Errorf("unable to create the backup file %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	if err := json.NewEncoder(zw).Encode(a); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write the backup %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("unable to write the backup %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write the backup %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to save the backup to %s %v", path, err)
+	}
+
+	t := ui.Table{Title: "Backup", Columns: []string{"table", "rows"}}
+	for _, c := range a.counts() {
+		t.Add(c.table, c.rows)
+	}
+	if err := s.ui.Table(t); err != nil {
+		return err
+	}
+	s.ui.Info(fmt.Sprintf("backup saved to %s", path))
+	return nil
+}
+
+// exportArchive reads every table into an archive
+func exportArchive(s *state) (archive, error) {
+	ctx := context.Background()
+	a := archive{Format: backupFormat, Version: backupVersion, CreatedAt: time.Now().UTC()}
+
+	users, err := s.db.GetUsers(ctx)
+	if err != nil {
+		return a, fmt.Errorf("unable to get users %v", err)
+	}
+	for _, u := range users {
+		a.Users = append(a.Users, archiveUser{ID: u.ID, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt, Name: u.Name, Admin: u.IsAdmin})
+	}
+
+	feeds, err := s.db.GetAllFeeds(ctx)
+	if err != nil {
+		return a, fmt.Errorf("unable to get feeds %v", err)
+	}
+	for _, f := range feeds {
+		a.Feeds = append(a.Feeds, archiveFeed{
+			ID:                f.ID,
+			CreatedAt:         f.CreatedAt,
+			UpdatedAt:         f.UpdatedAt,
+			Name:              f.Name,
+			URL:               f.Url,
+			AddedBy:           fromNullUUID(f.AddedBy),
+			LastFetchedAt:     fromNullTime(f.LastFetchedAt),
+			RetentionMaxAge:   fromNullString(f.RetentionMaxAge),
+			RetentionMaxPosts: fromNullInt32(f.RetentionMaxPosts),
+		})
+	}
+
+	follows, err := s.db.GetAllFeedFollows(ctx)
+	if err != nil {
+		return a, fmt.Errorf("unable to get feed follows %v", err)
+	}
+	for _, f := range follows {
+		a.Follows = append(a.Follows, archiveFollow{
+			ID:          f.ID,
+			CreatedAt:   f.CreatedAt,
+			UpdatedAt:   f.UpdatedAt,
+			UserID:      f.UserID,
+			FeedID:      f.FeedID,
+			DisplayName: fromNullString(f.DisplayName),
+			Priority:    f.Priority,
+			Muted:       f.Muted,
+			Notify:      f.Notify,
+		})
+	}
+
+	categories, err := s.db.GetAllCategories(ctx)
+	if err != nil {
+		return a, fmt.Errorf("unable to get categories %v", err)
+	}
+	for _, c := range categories {
+		a.Categories = append(a.Categories, archiveCategory{ID: c.ID, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt, Name: c.Name, UserID: c.UserID})
+	}
+
+	assigned, err := s.db.GetAllFeedFollowCategories(ctx)
+	if err != nil {
+		return a, fmt.Errorf("unable to get feed categories %v", err)
+	}
+	for _, fc := range assigned {
+		a.FollowCategories = append(a.FollowCategories, archiveFollowCategory{FollowID: fc.FeedFollowID, CategoryID: fc.CategoryID})
+	}
+
+	posts, err := s.db.GetAllPosts(ctx)
+	if err != nil {
+		return a, fmt.Errorf("unable to get posts %v", err)
+	}
+	for _, p := range posts {
+		a.Posts = append(a.Posts, archivePost{
+			ID:           p.ID,
+			CreatedAt:    p.CreatedAt,
+			UpdatedAt:    p.UpdatedAt,
+			Title:        p.Title,
+			URL:          p.Url,
+			Description:  fromNullString(p.Description),
+			PublishedAt:  p.PublishedAt,
+			FeedID:       p.FeedID,
+			Author:       fromNullString(p.Author),
+			Category:     fromNullString(p.Category),
+			CanonicalURL: p.CanonicalUrl,
+			Simhash:      fromNullInt64(p.Simhash),
+			ClusterID:    p.ClusterID,
+		})
+	}
+
+	states, err := s.db.GetAllPostStates(ctx)
+	if err != nil {
+		return a, fmt.Errorf("unable to get post states %v", err)
+	}
+	for _, st := range states {
+		a.PostStates = append(a.PostStates, archivePostState{
+			UserID:    st.UserID,
+			PostID:    st.PostID,
+			UpdatedAt: st.UpdatedAt,
+			Hidden:    st.Hidden,
+			Deleted:   st.Deleted,
+			Flagged:   st.Flagged,
+			Title:     fromNullString(st.Title),
+		})
+	}
+
+	rules, err := s.db.GetAllRules(ctx)
+	if err != nil {
+		return a, fmt.Errorf("unable to get rules %v", err)
+	}
+	for _, r := range rules {
+		a.Rules = append(a.Rules, archiveRule{
+			ID:            r.ID,
+			CreatedAt:     r.CreatedAt,
+			UpdatedAt:     r.UpdatedAt,
+			UserID:        r.UserID,
+			Field:         r.Field,
+			MatchType:     r.MatchType,
+			Pattern:       r.Pattern,
+			CaseSensitive: r.CaseSensitive,
+			Action:        r.Action,
+			Replacement:   fromNullString(r.Replacement),
+		})
+	}
+	return a, nil
+}
+
+// tableCount is the number of rows of one table in an archive or a restore
+type tableCount struct {
+	table string
+	rows  int
+}
+
+func (a archive) counts() []tableCount {
+	return []tableCount{
+		{"users", len(a.Users)},
+		{"feeds", len(a.Feeds)},
+		{"follows", len(a.Follows)},
+		{"categories", len(a.Categories)},
+		{"follow categories", len(a.FollowCategories)},
+		{"posts", len(a.Posts)},
+		{"post states", len(a.PostStates)},
+		{"rules", len(a.Rules)},
+	}
+}
+
+// readArchive opens a backup and checks it is one this version of gator can restore
+func readArchive(path string) (archive, error) {
+	var a archive
+	f, err := os.Open(path)
+	if err != nil {
+		return a, fmt.Errorf("unable to open the backup %v", err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return a, fmt.Errorf("%s is not a gator backup %v", path, err)
+	}
+	defer zr.Close()
+	if err := json.NewDecoder(zr).Decode(&a); err != nil {
+		return a, fmt.Errorf("%s is not a gator backup %v", path, err)
+	}
+	if a.Format != backupFormat {
+		return a, fmt.Errorf("%s is not a gator backup", path)
+	}
+	if a.Version > backupVersion {
+		return a, fmt.Errorf("%s was made by a newer gator (backup version %d, this gator reads up to %d), upgrade gator to restore it", path, a.Version, backupVersion)
+	}
+	return a, nil
+}
+
+// the ways restore handles a row that is already in the database
+const (
+	conflictSkip = "skip"
+	conflictFail = "fail"
+)
+
+// errRestoreConflict stops a restore with --on-conflict fail
+var errRestoreConflict = errors.New("already exists")
+
+// restorer imports an archive into a database that may already hold data. Rows are matched to existing
+// ones by what makes them unique, ex a user by name and a feed by url, and the ids of the archive are
+// mapped to the ids of the rows they matched so the rows that refer to them stay linked.
+type restorer struct {
+	s        *state
+	ctx      context.Context
+	conflict string
+	existing archive
+	// user is who runs the restore, only an admin's restore makes the archive's admins admins again
+	user database.User
+	// ids maps an id in the archive to the id of the row it was restored as or matched
+	ids map[uuid.UUID]uuid.UUID
+	// taken holds the ids in use, a new row that would reuse one gets a new id
+	taken   map[uuid.UUID]bool
+	added   map[string]int
+	skipped map[string]int
+}
+
+// handlerRestore imports a backup in a single transaction, the database is left as it was when the restore fails.
+// Rows that already exist are kept as they are, or stop the restore with --on-conflict fail.
+// An archive can create users and admins so only admins may restore one.
+func handlerRestore(s *state, cmd command, user database.User) error {
+	if !user.IsAdmin {
+		return errNotAdmin("restore a backup")
+	}
+	path := cmd.String("file")
+	a, err := readArchive(path)
+	if err != nil {
+		return err
+	}
+
+	var r *restorer
+	err = inTx(s, func(tx *state) error {
+		existing, err := exportArchive(tx)
+		if err != nil {
+			return err
+		}
+		r = newRestorer(tx, user, existing, cmd.String("on-conflict"))
+		return r.restore(a)
+	})
+	if err != nil {
+		return err
+	}
+
+	t := ui.Table{Title: "Restore", Columns: []string{"table", "added", "skipped"}}
+	for _, c := range a.counts() {
+		t.Add(c.table, r.added[c.table], r.skipped[c.table])
+	}
+	if err := s.ui.Table(t); err != nil {
+		return err
+	}
+	s.ui.Info(fmt.Sprintf("restored %s made %s", path, a.CreatedAt.Local().Format(time.DateTime)))
+	return nil
+}
+
+func newRestorer(s *state, user database.User, existing archive, conflict string) *restorer {
+	r := &restorer{
+		s:        s,
+		user:     user,
+		ctx:      context.Background(),
+		conflict: conflict,
+		existing: existing,
+		ids:      map[uuid.UUID]uuid.UUID{},
+		taken:    map[uuid.UUID]bool{},
+		added:    map[string]int{},
+		skipped:  map[string]int{},
+	}
+	for _, u := range existing.Users {
+		r.taken[u.ID] = true
+	}
+	for _, f := range existing.Feeds {
+		r.taken[f.ID] = true
+	}
+	for _, f := range existing.Follows {
+		r.taken[f.ID] = true
+	}
+	for _, c := range existing.Categories {
+		r.taken[c.ID] = true
+	}
+	for _, p := range existing.Posts {
+		r.taken[p.ID] = true
+	}
+	for _, rule := range existing.Rules {
+		r.taken[rule.ID] = true
+	}
+	return r
+}
+
+// match records that a row of the archive is already in the database as id
+func (r *restorer) match(table string, archived, id uuid.UUID, what string) error {
+	if r.conflict == conflictFail {
+		return fmt.Errorf("unable to restore, %s %w", what, errRestoreConflict)
+	}
+	r.ids[archived] = id
+	r.skipped[table]++
+	return nil
+}
+
+// newID keeps the id of the archive unless a row in the database already uses it
+func (r *restorer) newID(archived uuid.UUID) uuid.UUID {
+	id := archived
+	if r.taken[id] {
+		id = uuid.New()
+	}
+	r.taken[id] = true
+	r.ids[archived] = id
+	return id
+}
+
+// id maps an id of the archive, ids it never saw are kept as they are
+func (r *restorer) id(archived uuid.UUID) uuid.UUID {
+	if id, ok := r.ids[archived]; ok {
+		return id
+	}
+	return archived
+}
+
+// nullID maps an optional archived id like id
+func (r *restorer) nullID(archived *uuid.UUID) uuid.NullUUID {
+	if archived == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: r.id(*archived), Valid: true}
+}
+
+// restore adds the rows of a in the order they refer to each other
+func (r *restorer) restore(a archive) error {
+	for _, step := range []func(archive) error{
+		r.users, r.feeds, r.follows, r.categories, r.followCategories, r.posts, r.postStates, r.rules,
+	} {
+		if err := step(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *restorer) users(a archive) error {
+	byName := map[string]uuid.UUID{}
+	for _, u := range r.existing.Users {
+		byName[u.Name] = u.ID
+	}
+	for _, u := range a.Users {
+		if id, ok := byName[u.Name]; ok {
+			if err := r.match("users", u.ID, id, fmt.Sprintf("user %s", u.Name)); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := r.s.db.CreateUser(r.ctx, database.CreateUserParams{
+			ID:        r.newID(u.ID),
+			CreatedAt: u.CreatedAt,
+			UpdatedAt: u.UpdatedAt,
+			Name:      u.Name,
+			IsAdmin:   u.Admin && r.user.IsAdmin,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore user %s %v", u.Name, err)
+		}
+		r.added["users"]++
+	}
+	return nil
+}
+
+func (r *restorer) feeds(a archive) error {
+	byURL := map[string]uuid.UUID{}
+	for _, f := range r.existing.Feeds {
+		byURL[f.URL] = f.ID
+	}
+	for _, f := range a.Feeds {
+		addedBy := f.AddedBy
+		if a.Version < 2 {
+			addedBy = f.UserID
+		}
+		if id, ok := byURL[f.URL]; ok {
+			if err := r.match("feeds", f.ID, id, fmt.Sprintf("feed %s", f.URL)); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := r.s.db.RestoreFeed(r.ctx, database.RestoreFeedParams{
+			ID:                r.newID(f.ID),
+			CreatedAt:         f.CreatedAt,
+			UpdatedAt:         f.UpdatedAt,
+			Name:              f.Name,
+			Url:               f.URL,
+			AddedBy:           r.nullID(addedBy),
+			LastFetchedAt:     toNullTime(f.LastFetchedAt),
+			RetentionMaxAge:   toNullString(f.RetentionMaxAge),
+			RetentionMaxPosts: toNullInt32(f.RetentionMaxPosts),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore feed %s %v", f.URL, err)
+		}
+		r.added["feeds"]++
+	}
+	return nil
+}
+
+func (r *restorer) follows(a archive) error {
+	type key struct{ user, feed uuid.UUID }
+	existing := map[key]uuid.UUID{}
+	for _, f := range r.existing.Follows {
+		existing[key{f.UserID, f.FeedID}] = f.ID
+	}
+	for _, f := range a.Follows {
+		userID, feedID := r.id(f.UserID), r.id(f.FeedID)
+		if id, ok := existing[key{userID, feedID}]; ok {
+			if err := r.match("follows", f.ID, id, "a follow of a feed in the backup"); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := r.s.db.RestoreFeedFollow(r.ctx, database.RestoreFeedFollowParams{
+			ID:          r.newID(f.ID),
+			CreatedAt:   f.CreatedAt,
+			UpdatedAt:   f.UpdatedAt,
+			UserID:      userID,
+			FeedID:      feedID,
+			DisplayName: toNullString(f.DisplayName),
+			Priority:    f.Priority,
+			Muted:       f.Muted,
+			Notify:      f.Notify,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore feed follow %v", err)
+		}
+		r.added["follows"]++
+	}
+	return nil
+}
+
+func (r *restorer) categories(a archive) error {
+	type key struct {
+		user uuid.UUID
+		name string
+	}
+	existing := map[key]uuid.UUID{}
+	for _, c := range r.existing.Categories {
+		existing[key{c.UserID, c.Name}] = c.ID
+	}
+	for _, c := range a.Categories {
+		userID := r.id(c.UserID)
+		if id, ok := existing[key{userID, c.Name}]; ok {
+			if err := r.match("categories", c.ID, id, fmt.Sprintf("category %s", c.Name)); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := r.s.db.CreateCategory(r.ctx, database.CreateCategoryParams{
+			ID:        r.newID(c.ID),
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: c.UpdatedAt,
+			Name:      c.Name,
+			UserID:    userID,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore category %s %v", c.Name, err)
+		}
+		r.added["categories"]++
+	}
+	return nil
+}
+
+func (r *restorer) followCategories(a archive) error {
+	existing := map[archiveFollowCategory]bool{}
+	for _, fc := range r.existing.FollowCategories {
+		existing[fc] = true
+	}
+	for _, fc := range a.FollowCategories {
+		mapped := archiveFollowCategory{FollowID: r.id(fc.FollowID), CategoryID: r.id(fc.CategoryID)}
+		if existing[mapped] {
+			// nothing refers to these rows so there is no id to map
+			if r.conflict == conflictFail {
+				return fmt.Errorf("unable to restore, a feed category %w", errRestoreConflict)
+			}
+			r.skipped["follow categories"]++
+			continue
+		}
+		err := r.s.db.AddFeedFollowToCategory(r.ctx, database.AddFeedFollowToCategoryParams{
+			FeedFollowID: mapped.FollowID,
+			CategoryID:   mapped.CategoryID,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore feed category %v", err)
+		}
+		r.added["follow categories"]++
+	}
+	return nil
+}
+
+func (r *restorer) posts(a archive) error {
+	// a link is stored once per feed
+	type key struct {
+		feed uuid.UUID
+		url  string
+	}
+	byURL := map[key]uuid.UUID{}
+	for _, p := range r.existing.Posts {
+		byURL[key{p.FeedID, p.URL}] = p.ID
+	}
+	for _, p := range a.Posts {
+		if id, ok := byURL[key{r.id(p.FeedID), p.URL}]; ok {
+			if err := r.match("posts", p.ID, id, fmt.Sprintf("post %s", p.URL)); err != nil {
+				return err
+			}
+			continue
+		}
+		// posts come oldest first so the post a cluster is named after is mapped before the rest of it
+		_, err := r.s.db.CreatePost(r.ctx, database.CreatePostParams{
+			ID:           r.newID(p.ID),
+			CreatedAt:    p.CreatedAt,
+			UpdatedAt:    p.UpdatedAt,
+			Title:        p.Title,
+			Url:          p.URL,
+			Description:  toNullString(p.Description),
+			PublishedAt:  p.PublishedAt,
+			FeedID:       r.id(p.FeedID),
+			Author:       toNullString(p.Author),
+			Category:     toNullString(p.Category),
+			CanonicalUrl: p.CanonicalURL,
+			Simhash:      toNullInt64(p.Simhash),
+			ClusterID:    r.id(p.ClusterID),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore post %s %v", p.URL, err)
+		}
+		r.added["posts"]++
+	}
+	return nil
+}
+
+func (r *restorer) postStates(a archive) error {
+	type key struct{ user, post uuid.UUID }
+	existing := map[key]bool{}
+	for _, st := range r.existing.PostStates {
+		existing[key{st.UserID, st.PostID}] = true
+	}
+	for _, st := range a.PostStates {
+		userID, postID := r.id(st.UserID), r.id(st.PostID)
+		if existing[key{userID, postID}] {
+			if r.conflict == conflictFail {
+				return fmt.Errorf("unable to restore, a post state %w", errRestoreConflict)
+			}
+			r.skipped["post states"]++
+			continue
+		}
+		err := r.s.db.RestorePostState(r.ctx, database.RestorePostStateParams{
+			UserID:    userID,
+			PostID:    postID,
+			UpdatedAt: st.UpdatedAt,
+			Hidden:    st.Hidden,
+			Deleted:   st.Deleted,
+			Flagged:   st.Flagged,
+			Title:     toNullString(st.Title),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore post state %v", err)
+		}
+		r.added["post states"]++
+	}
+	return nil
+}
+
+func (r *restorer) rules(a archive) error {
+	existing := map[string]uuid.UUID{}
+	for _, rule := range r.existing.Rules {
+		existing[ruleKey(rule.UserID, rule)] = rule.ID
+	}
+	for _, rule := range a.Rules {
+		userID := r.id(rule.UserID)
+		if id, ok := existing[ruleKey(userID, rule)]; ok {
+			if err := r.match("rules", rule.ID, id, fmt.Sprintf("rule %s %s %q", rule.Field, rule.MatchType, rule.Pattern)); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := r.s.db.CreateRule(r.ctx, database.CreateRuleParams{
+			ID:            r.newID(rule.ID),
+			CreatedAt:     rule.CreatedAt,
+			UpdatedAt:     rule.UpdatedAt,
+			UserID:        userID,
+			Field:         rule.Field,
+			MatchType:     rule.MatchType,
+			Pattern:       rule.Pattern,
+			CaseSensitive: rule.CaseSensitive,
+			Action:        rule.Action,
+			Replacement:   toNullString(rule.Replacement),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore rule %v", err)
+		}
+		r.added["rules"]++
+	}
+	return nil
+}
+
+// ruleKey is the same for two rules of a user that match and act alike, whatever their ids
+func ruleKey(userID uuid.UUID, rule archiveRule) string {
+	replacement := "-"
+	if rule.Replacement != nil {
+		replacement = fmt.Sprintf("%q", *rule.Replacement)
+	}
+	return fmt.Sprintf("%s %s %s %q %t %s %s", userID, rule.Field, rule.MatchType, rule.Pattern, rule.CaseSensitive, rule.Action, replacement)
+}
+
+// the archive keeps sql nulls as missing fields
+
+func fromNullString(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func toNullString(v *string) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *v, Valid: true}
+}
+
+func fromNullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+func toNullTime(v *time.Time) sql.NullTime {
+	if v == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *v, Valid: true}
+}
+
+func fromNullInt32(v sql.NullInt32) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
+
+func toNullInt32(v *int32) sql.NullInt32 {
+	if v == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *v, Valid: true}
+}
+
+func fromNullInt64(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
+func toNullInt64(v *int64) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *v, Valid: true}
+}
+
+func fromNullUUID(v uuid.NullUUID) *uuid.UUID {
+	if !v.Valid {
+		return nil
+	}
+	return &v.UUID
+}
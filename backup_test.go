@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/config"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/ui"
+)
+
+// backupFixture fills s with a user who follows a feed with settings, a category, a post with a state and a rule
+func backupFixture(t *testing.T, s *state) {
+	t.Helper()
+	alice := addAdmin(t, s, "alice")
+	login(t, s, alice)
+	feed := addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+	addTestPost(t, s, feed, "First post", 1)
+	runCommand(t, s, "follow", "set", feed.Url, "priority", "7")
+	runCommand(t, s, "category", "add", "tech")
+	runCommand(t, s, "category", "assign", feed.Url, "tech")
+	runCommand(t, s, "rule", "add", "title", "contains", "First", "flag")
+	runCommand(t, s, "rule", "apply")
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	src, _ := newTestState(t)
+	backupFixture(t, src)
+	path := filepath.Join(t.TempDir(), "gator-backup.json.gz")
+	runCommand(t, src, "backup", path)
+
+	// restore into a different backend
+	dst := newSQLiteState(t)
+	out := &bytes.Buffer{}
+	dst.ui = ui.New(out)
+	dst.config = &config.Config{}
+	login(t, dst, addAdmin(t, dst, "root"))
+	runCommand(t, dst, "restore", path)
+
+	ctx := context.Background()
+	alice, err := dst.db.GetUser(ctx, "alice")
+	if err != nil || !alice.IsAdmin {
+		t.Fatalf("wanted alice restored as an admin got %+v %v", alice, err)
+	}
+	follows, err := dst.db.GetFeedFollowsForUserInCategory(ctx, database.GetFeedFollowsForUserInCategoryParams{
+		UserID:     alice.ID,
+		CategoryID: mustCategory(t, dst, alice, "tech").ID,
+	})
+	if err != nil || len(follows) != 1 || follows[0].Priority != 7 {
+		t.Fatalf("wanted the follow restored in tech with its priority got %+v %v", follows, err)
+	}
+	posts, err := dst.db.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: alice.ID, Limit: 10})
+	if err != nil || len(posts) != 1 || !posts[0].Flagged {
+		t.Errorf("wanted the flagged post restored got %+v %v", posts, err)
+	}
+
+	// a second restore finds everything in place
+	out.Reset()
+	runCommand(t, dst, "restore", path)
+	if !strings.Contains(out.String(), "users") {
+		t.Errorf("wanted the restore counts got %s", out)
+	}
+	users, _ := dst.db.GetUsers(ctx)
+	rules, _ := dst.db.GetAllRules(ctx)
+	if len(users) != 2 || len(rules) != 1 {
+		t.Errorf("wanted nothing added twice got %d users %d rules", len(users), len(rules))
+	}
+
+	err = runArgs(dst, "restore", path, "--on-conflict", "fail")
+	if err == nil || !strings.Contains(err.Error(), "user alice already exists") {
+		t.Errorf("wanted the restore to stop on alice got %v", err)
+	}
+}
+
+func TestRestoreMapsExistingRows(t *testing.T) {
+	src, _ := newTestState(t)
+	backupFixture(t, src)
+	path := filepath.Join(t.TempDir(), "gator-backup.json.gz")
+	runCommand(t, src, "backup", path)
+
+	// alice and the feed already exist here with other ids, the follow has to point at them
+	dst, _ := newTestState(t)
+	alice := addAdmin(t, dst, "alice")
+	login(t, dst, alice)
+	addFollowedFeed(t, dst, addUser(t, dst, "bob"), "news", "https://example.com/news")
+	runCommand(t, dst, "restore", path)
+
+	follows, err := dst.db.GetFeedFollowsForUser(context.Background(), alice.ID)
+	if err != nil || len(follows) != 1 || follows[0].Priority != 7 {
+		t.Errorf("wanted alice following the existing feed got %+v %v", follows, err)
+	}
+}
+
+func TestRestoreRejectsOtherFiles(t *testing.T) {
+	s, _ := newTestState(t)
+	login(t, s, addAdmin(t, s, "root"))
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "plain.json")
+	if err := os.WriteFile(plain, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runArgs(s, "restore", plain); err == nil || !strings.Contains(err.Error(), "not a gator backup") {
+		t.Errorf("wanted a plain file rejected got %v", err)
+	}
+
+	newer := filepath.Join(dir, "newer.json.gz")
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if err := json.NewEncoder(zw).Encode(archive{Format: backupFormat, Version: backupVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	if err := os.WriteFile(newer, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runArgs(s, "restore", newer); err == nil || !strings.Contains(err.Error(), "upgrade gator") {
+		t.Errorf("wanted a newer backup rejected got %v", err)
+	}
+}
+
+func TestRestoreVersionOne(t *testing.T) {
+	s, _ := newTestState(t)
+	login(t, s, addAdmin(t, s, "root"))
+	alice := uuid.New()
+	path := filepath.Join(t.TempDir(), "v1.json.gz")
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	// version 1 recorded who added a feed as user_id
+	err := json.NewEncoder(zw).Encode(map[string]any{
+		"format":  backupFormat,
+		"version": 1,
+		"users":   []map[string]any{{"id": alice, "name": "alice", "created_at": time.Now(), "updated_at": time.Now()}},
+		"feeds":   []map[string]any{{"id": uuid.New(), "name": "news", "url": "https://example.com/news", "user_id": alice, "created_at": time.Now(), "updated_at": time.Now()}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runCommand(t, s, "restore", path)
+
+	feeds, err := s.db.GetAllFeeds(context.Background())
+	if err != nil || len(feeds) != 1 || feeds[0].AddedBy.UUID != alice {
+		t.Errorf("wanted news added by alice got %+v %v", feeds, err)
+	}
+}
+
+func TestBackupAndRestoreNeedAnAdmin(t *testing.T) {
+	s, _ := newTestState(t)
+	addAdmin(t, s, "root")
+	login(t, s, addUser(t, s, "alice"))
+	path := filepath.Join(t.TempDir(), "gator-backup.json.gz")
+
+	if err := runArgs(s, "backup", path); err == nil || !strings.Contains(err.Error(), "only admins can back up the database") {
+		t.Errorf("wanted the backup refused got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("wanted no archive written got %v", err)
+	}
+
+	// nor can she restore a hand written archive that adds an admin
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	err := json.NewEncoder(zw).Encode(map[string]any{
+		"format":  backupFormat,
+		"version": backupVersion,
+		"users":   []map[string]any{{"id": uuid.New(), "name": "mallory", "admin": true, "created_at": time.Now(), "updated_at": time.Now()}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runArgs(s, "restore", path); err == nil || !strings.Contains(err.Error(), "only admins can restore a backup") {
+		t.Errorf("wanted the restore refused got %v", err)
+	}
+	if _, err := s.db.GetUser(context.Background(), "mallory"); err == nil {
+		t.Errorf("wanted mallory not restored")
+	}
+}
+
+func mustCategory(t *testing.T, s *state, user database.User, name string) database.Category {
+	t.Helper()
+	c, err := s.db.GetCategoryByName(context.Background(), database.GetCategoryByNameParams{UserID: user.ID, Name: name})
+	if err != nil {
+		t.Fatalf("unable to get category %s %v", name, err)
+	}
+	return c
+}
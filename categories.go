@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/ui"
+)
+
+// handlerAddCategory creates a new category owned by the current user.
+// Categories are per user, so two users may each have a category with the same name.
+func handlerAddCategory(s *state, cmd command, user database.User) error {
+	name := strings.TrimSpace(cmd.String("name"))
+	if name == "" {
+		return fmt.Errorf("the category name is blank")
+	}
+
+	s.ui.Header("Add Category")
+	category, err := s.db.CreateCategory(context.Background(), database.CreateCategoryParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("category %q already exists", name)
+		}
+		return fmt.Errorf("error creating category %v", err)
+	}
+
+	s.ui.Item("Category created: %s", category.Name)
+	return nil
+}
+
+// handlerListCategories lists the current user's categories along with the feeds assigned to each
+func handlerListCategories(s *state, cmd command, user database.User) error {
+	// the feeds of every category are counted in the same query
+	categories, err := s.db.GetCategoryFeedCountsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("unable to get categories for user %v", err)
+	}
+
+	if len(categories) == 0 && !s.ui.Structured() {
+		s.ui.Header("Categories")
+		s.ui.Info("No categories yet, create one with 'category add <name>'")
+		return nil
+	}
+
+	t := ui.Table{Title: "Categories", Columns: []string{"name", "feeds"}}
+	for _, c := range categories {
+		t.Add(c.Name, ui.Text{Value: c.Feeds, Display: fmt.Sprintf("%d feeds", c.Feeds)})
+	}
+	return s.ui.Table(t)
+}
+
+// handlerRenameCategory renames one of the current user's categories, feed assignments are kept
+func handlerRenameCategory(s *state, cmd command, user database.User) error {
+	newName := strings.TrimSpace(cmd.String("new"))
+	if newName == "" {
+		return fmt.Errorf("the new category name is blank")
+	}
+
+	category, err := getCategoryByName(s, user, cmd.String("old"))
+	if err != nil {
+		return err
+	}
+
+	s.ui.Header("Rename Category")
+	renamed, err := s.db.RenameCategory(context.Background(), database.RenameCategoryParams{
+		ID:   category.ID,
+		Name: newName,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("category %q already exists", newName)
+		}
+		return fmt.Errorf("error renaming category %v", err)
+	}
+
+	s.ui.Item("Category %s renamed to %s", category.Name, renamed.Name)
+	return nil
+}
+
+// handlerDeleteCategory deletes one of the current user's categories.
+// Only the category and its assignments are removed, the feeds stay followed.
+func handlerDeleteCategory(s *state, cmd command, user database.User) error {
+	category, err := getCategoryByName(s, user, cmd.String("name"))
+	if err != nil {
+		return err
+	}
+
+	s.ui.Header("Delete Category")
+	if err := s.db.DeleteCategory(context.Background(), category.ID); err != nil {
+		return fmt.Errorf("error deleting category %v", err)
+	}
+
+	s.ui.Item("Category deleted: %s", category.Name)
+	return nil
+}
+
+// handlerCategorize assigns a followed feed to a category, a feed may belong to several categories
+func handlerCategorize(s *state, cmd command, user database.User) error {
+	follow, err := getFeedFollowByRef(s, user, cmd.String("feed"))
+	if err != nil {
+		return err
+	}
+
+	category, err := getCategoryByName(s, user, cmd.String("category"))
+	if err != nil {
+		return err
+	}
+
+	s.ui.Header("Categorize Feed")
+	if err := s.db.AddFeedFollowToCategory(context.Background(), database.AddFeedFollowToCategoryParams{
+		FeedFollowID: follow.ID,
+		CategoryID:   category.ID,
+	}); err != nil {
+		return fmt.Errorf("error adding feed to category %v", err)
+	}
+
+	s.ui.Item("Feed %s added to category %s", follow.FeedName, category.Name)
+	return nil
+}
+
+// handlerUncategorize removes a followed feed from a category without unfollowing it
+func handlerUncategorize(s *state, cmd command, user database.User) error {
+	follow, err := getFeedFollowByRef(s, user, cmd.String("feed"))
+	if err != nil {
+		return err
+	}
+
+	category, err := getCategoryByName(s, user, cmd.String("category"))
+	if err != nil {
+		return err
+	}
+
+	s.ui.Header("Uncategorize Feed")
+	if err := s.db.RemoveFeedFollowFromCategory(context.Background(), database.RemoveFeedFollowFromCategoryParams{
+		FeedFollowID: follow.ID,
+		CategoryID:   category.ID,
+	}); err != nil {
+		return fmt.Errorf("error removing feed from category %v", err)
+	}
+
+	s.ui.Item("Feed %s removed from category %s", follow.FeedName, category.Name)
+	return nil
+}
+
+// getCategoryByName looks up one of the user's categories by name
+func getCategoryByName(s *state, user database.User, name string) (database.Category, error) {
+	category, err := s.db.GetCategoryByName(context.Background(), database.GetCategoryByNameParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return category, fmt.Errorf("category %q not found, see 'category list'", name)
+	}
+	if err != nil {
+		return category, fmt.Errorf("unable to get category %v", err)
+	}
+	return category, nil
+}
+
+// getFeedFollowByRef finds the user's follow for the feed with the given handle, id or url
+func getFeedFollowByRef(s *state, user database.User, ref string) (database.GetFeedFollowsForUserRow, error) {
+	feed, err := getFeedByRef(s, user, ref)
+	if err != nil {
+		return database.GetFeedFollowsForUserRow{}, err
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return database.GetFeedFollowsForUserRow{}, fmt.Errorf("unable to get feed follows %v", err)
+	}
+
+	for _, f := range follows {
+		if f.FeedID == feed.ID {
+			return f, nil
+		}
+	}
+	return database.GetFeedFollowsForUserRow{}, fmt.Errorf("you are not following %s, see 'follow list'", feed.Name)
+}
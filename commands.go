@@ -0,0 +1,532 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joshhartwig/gator/internal/ui"
+)
+
+// argKind is the type a positional argument or flag value has to parse as
+type argKind int
+
+const (
+	kindString argKind = iota
+	kindInt
+	kindBool
+	kindDuration
+	kindURL
+	// kindFeed is a feed handle, id or url
+	kindFeed
+)
+
+// argDef describes a positional argument, optional arguments must come after the required ones
+type argDef struct {
+	name     string
+	kind     argKind
+	help     string
+	optional bool
+	// variadic collects every remaining positional argument, it must be the last argument
+	variadic bool
+	// def is used when an optional argument is left out
+	def string
+	// choices limits the argument to a fixed set of values
+	choices []string
+	// complete offers values for shell completion when there are no choices
+	complete completer
+}
+
+// flagDef describes a named --flag, bool flags do not take a value
+type flagDef struct {
+	name     string
+	kind     argKind
+	help     string
+	def      string
+	choices  []string
+	complete completer
+}
+
+// commandDef declares a command, the arguments and flags it accepts and the handler that runs it.
+// Commands inside a group are named by their full path, ex "feed add".
+type commandDef struct {
+	name    string
+	summary string
+	args    []argDef
+	flags   []flagDef
+	handler func(*state, command) error
+	// hidden commands are left out of help and completion
+	hidden bool
+	// passthrough commands receive their arguments in cmd.args without parsing
+	passthrough bool
+	// plain commands write output meant for other programs, the banner is not printed
+	plain bool
+	// noSchemaCheck commands run without checking for pending migrations first
+	noSchemaCheck bool
+	// database is what the command needs from the database, by default a connection that answers
+	database databaseNeed
+}
+
+// command is an invocation of a command, args holds the raw positional arguments and
+// values holds every argument and flag by name once they have been parsed and validated
+type command struct {
+	name   string
+	args   []string
+	values map[string]string
+	lists  map[string][]string
+}
+
+// groupDef declares a group of related subcommands, ex feed add|list|status
+type groupDef struct {
+	name    string
+	summary string
+	// def is the subcommand run when the group is called on its own
+	def string
+}
+
+type commands struct {
+	names  map[string]*commandDef
+	groups map[string]*groupDef
+	// aliases maps the deprecated flat command names to their new path
+	aliases map[string]string
+	// inShell is set while an interactive shell session is running
+	inShell bool
+}
+
+func newCommands() *commands {
+	return &commands{
+		names:   make(map[string]*commandDef),
+		groups:  make(map[string]*groupDef),
+		aliases: make(map[string]string),
+	}
+}
+
+// usageError is returned when a command is called with arguments that do not match its definition
+type usageError struct {
+	def *commandDef
+	msg string
+}
+
+func (e usageError) Error() string {
+	return fmt.Sprintf("gator: %s\nusage: %s\nsee 'gator help %s' for more information", e.msg, e.def.usage(), e.def.name)
+}
+
+// globalFlags are accepted by every command, they are removed from the arguments before the command is resolved
+var globalFlags = []flagDef{
+	{name: "output", help: "output format of listings", def: string(ui.FormatTable), choices: ui.Formats},
+	{name: "no-color", kind: kindBool, help: "turns colors off, same as setting NO_COLOR", def: "false"},
+	{name: "no-pager", kind: kindBool, help: "writes long listings directly instead of through $PAGER", def: "false"},
+}
+
+// errHelp is returned by parse when the command was called with --help
+var errHelp = errors.New("help requested")
+
+// run a given command with the provided state, the raw arguments are parsed against the command definition first.
+// When cmd.name is a group the first argument selects the subcommand.
+func (c *commands) run(s *state, cmd command) error {
+	def, rest, alias, err := c.resolve(append([]string{cmd.name}, cmd.args...))
+	if err != nil {
+		return err
+	}
+	if alias != "" {
+		s.ui.Warn(fmt.Sprintf("'%s' is deprecated and will be removed, use 'gator %s' instead", alias, c.aliases[alias]))
+	}
+
+	if def.passthrough {
+		return def.handler(s, command{name: def.name, args: rest})
+	}
+
+	parsed, err := def.parse(rest)
+	if errors.Is(err, errHelp) {
+		printCommandHelp(s, def)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return def.handler(s, parsed)
+}
+
+// resolve finds the command named by the leading words, following groups and aliases,
+// and returns it along with the remaining arguments and the deprecated alias that was used if any.
+// A group wins over an alias of the same name when the next word is one of its subcommands.
+func (c *commands) resolve(words []string) (*commandDef, []string, string, error) {
+	if len(words) == 0 {
+		return nil, nil, "", fmt.Errorf("gator: no command given, see 'gator help'")
+	}
+
+	alias := ""
+	if path, ok := c.aliases[words[0]]; ok && !c.isSubcommand(words) {
+		alias = words[0]
+		words = append(strings.Fields(path), words[1:]...)
+	}
+
+	name, rest := words[0], words[1:]
+	if g, ok := c.groups[name]; ok {
+		sub := g.def
+		if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+			sub, rest = rest[0], rest[1:]
+		}
+		if sub == "" {
+			return nil, nil, "", fmt.Errorf("gator: %s needs a subcommand, see 'gator help %s'", name, name)
+		}
+		name = name + " " + sub
+	}
+
+	def, ok := c.names[name]
+	if !ok {
+		return nil, nil, "", fmt.Errorf("gator: error unable to find the requested command %s, see 'gator help'", name)
+	}
+	return def, rest, alias, nil
+}
+
+// isSubcommand reports whether the first two words name a command inside a group
+func (c *commands) isSubcommand(words []string) bool {
+	if len(words) < 2 {
+		return false
+	}
+	_, ok := c.names[words[0]+" "+words[1]]
+	return ok
+}
+
+// register a command definition under its name
+func (c *commands) register(def commandDef) {
+	_, ok := c.names[def.name]
+	if !ok {
+		c.names[def.name] = &def
+	}
+}
+
+// group registers a group, its subcommands are registered with names prefixed by the group name
+func (c *commands) group(g groupDef) {
+	c.groups[g.name] = &g
+}
+
+// alias keeps an old command name working, path is the command it now runs, ex "feed add"
+func (c *commands) alias(old, path string) {
+	c.aliases[old] = path
+}
+
+// subcommands returns the visible commands of a group sorted by name, an empty group returns the top level commands
+func (c *commands) subcommands(group string) []*commandDef {
+	defs := []*commandDef{}
+	for name, def := range c.names {
+		if def.hidden {
+			continue
+		}
+		g, _, ok := strings.Cut(name, " ")
+		if (ok && g == group) || (!ok && group == "") {
+			defs = append(defs, def)
+		}
+	}
+	slices.SortFunc(defs, func(a, b *commandDef) int { return strings.Compare(a.name, b.name) })
+	return defs
+}
+
+// String returns the value of a string, url or choice argument or flag
+func (c command) String(name string) string {
+	return c.values[name]
+}
+
+// Int returns the value of an int argument or flag
+func (c command) Int(name string) int {
+	n, _ := strconv.Atoi(c.values[name])
+	return n
+}
+
+// Bool returns the value of a bool flag
+func (c command) Bool(name string) bool {
+	b, _ := strconv.ParseBool(c.values[name])
+	return b
+}
+
+// Duration returns the value of a duration argument or flag
+func (c command) Duration(name string) time.Duration {
+	d, _ := time.ParseDuration(c.values[name])
+	return d
+}
+
+// List returns the values collected by a variadic argument
+func (c command) List(name string) []string {
+	return c.lists[name]
+}
+
+// Has reports whether an argument or flag was given or has a default
+func (c command) Has(name string) bool {
+	_, ok := c.values[name]
+	return ok
+}
+
+// parse validates raw arguments against the definition.
+// Flags may appear anywhere as --name value or --name=value and a bare -- ends flag parsing.
+func (d *commandDef) parse(raw []string) (command, error) {
+	cmd := command{
+		name:   d.name,
+		values: make(map[string]string),
+		lists:  make(map[string][]string),
+	}
+
+	positional := []string{}
+	for i := 0; i < len(raw); i++ {
+		a := raw[i]
+		if a == "--" {
+			positional = append(positional, raw[i+1:]...)
+			break
+		}
+		if a == "-h" || a == "--help" {
+			return cmd, errHelp
+		}
+		if !strings.HasPrefix(a, "--") {
+			positional = append(positional, a)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(a, "--"), "=")
+		f := d.flag(name)
+		if f == nil {
+			return cmd, usageError{d, fmt.Sprintf("unknown flag --%s", name)}
+		}
+		if !hasValue {
+			if f.kind == kindBool {
+				value = "true"
+			} else {
+				if i+1 >= len(raw) {
+					return cmd, usageError{d, fmt.Sprintf("flag --%s needs a value", name)}
+				}
+				i++
+				value = raw[i]
+			}
+		}
+		if err := checkValue(f.kind, f.choices, value); err != nil {
+			return cmd, usageError{d, fmt.Sprintf("invalid value for --%s: %v", name, err)}
+		}
+		cmd.values[f.name] = value
+	}
+
+	for _, f := range d.flags {
+		if _, ok := cmd.values[f.name]; !ok && f.def != "" {
+			cmd.values[f.name] = f.def
+		}
+	}
+
+	used := 0
+	for _, a := range d.args {
+		if a.variadic {
+			rest := positional[used:]
+			if len(rest) == 0 && !a.optional {
+				return cmd, usageError{d, fmt.Sprintf("missing argument <%s>", a.name)}
+			}
+			for _, v := range rest {
+				if err := checkValue(a.kind, a.choices, v); err != nil {
+					return cmd, usageError{d, fmt.Sprintf("invalid <%s>: %v", a.name, err)}
+				}
+			}
+			cmd.lists[a.name] = rest
+			used = len(positional)
+			break
+		}
+
+		if used >= len(positional) {
+			if !a.optional {
+				return cmd, usageError{d, fmt.Sprintf("missing argument <%s>", a.name)}
+			}
+			if a.def != "" {
+				cmd.values[a.name] = a.def
+			}
+			continue
+		}
+
+		v := positional[used]
+		if err := checkValue(a.kind, a.choices, v); err != nil {
+			return cmd, usageError{d, fmt.Sprintf("invalid <%s>: %v", a.name, err)}
+		}
+		cmd.values[a.name] = v
+		used++
+	}
+
+	if used < len(positional) {
+		return cmd, usageError{d, fmt.Sprintf("too many arguments, unexpected %q", positional[used])}
+	}
+
+	cmd.args = positional
+	return cmd, nil
+}
+
+// flag finds a flag definition by name, falling back to the global flags
+func (d *commandDef) flag(name string) *flagDef {
+	for i := range d.flags {
+		if d.flags[i].name == name {
+			return &d.flags[i]
+		}
+	}
+	return globalFlag(name)
+}
+
+func globalFlag(name string) *flagDef {
+	for i := range globalFlags {
+		if globalFlags[i].name == name {
+			return &globalFlags[i]
+		}
+	}
+	return nil
+}
+
+// extractGlobalFlags removes the global flags from the raw command line and returns their values,
+// defaults included. Arguments after a bare -- are left alone.
+func extractGlobalFlags(raw []string) ([]string, map[string]string, error) {
+	values := make(map[string]string)
+	for _, f := range globalFlags {
+		values[f.name] = f.def
+	}
+
+	rest := []string{}
+	for i := 0; i < len(raw); i++ {
+		a := raw[i]
+		if a == "--" {
+			rest = append(rest, raw[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(a, "--"), "=")
+		f := globalFlag(name)
+		if !strings.HasPrefix(a, "--") || f == nil {
+			rest = append(rest, a)
+			continue
+		}
+		if !hasValue && f.kind == kindBool {
+			value = "true"
+		} else if !hasValue {
+			if i+1 >= len(raw) {
+				return nil, nil, fmt.Errorf("gator: flag --%s needs a value", name)
+			}
+			i++
+			value = raw[i]
+		}
+		if err := checkValue(f.kind, f.choices, value); err != nil {
+			return nil, nil, fmt.Errorf("gator: invalid value for --%s: %v", name, err)
+		}
+		values[f.name] = value
+	}
+	return rest, values, nil
+}
+
+// usage returns the one line synopsis of the command, ex gator addfeed <name> <url>
+func (d *commandDef) usage() string {
+	parts := []string{"gator", d.name}
+	for _, a := range d.args {
+		name := "<" + a.name + ">"
+		if len(a.choices) > 0 {
+			name = strings.Join(a.choices, "|")
+		}
+		if a.variadic {
+			name += "..."
+		}
+		if a.optional {
+			name = "[" + name + "]"
+		}
+		parts = append(parts, name)
+	}
+	for _, f := range d.flags {
+		if f.kind == kindBool {
+			parts = append(parts, "[--"+f.name+"]")
+		} else {
+			parts = append(parts, "[--"+f.name+" <"+kindName(f.kind)+">]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// printCommandHelp prints the full usage of a single command
+func printCommandHelp(s *state, d *commandDef) {
+	s.ui.Header("Help: " + d.name)
+	s.ui.Item("%s\n", d.summary)
+	s.ui.Item("Usage:")
+	s.ui.Item("  %s", d.usage())
+
+	if len(d.args) > 0 {
+		s.ui.Item("\nArguments:")
+		for _, a := range d.args {
+			s.ui.Column("  %s\t%s\t%s\t\n", a.name, kindName(a.kind), describeDefault(a.help, a.def, a.choices))
+		}
+	}
+	if len(d.flags) > 0 {
+		s.ui.Item("\nFlags:")
+		for _, f := range d.flags {
+			s.ui.Column("  --%s\t%s\t%s\t\n", f.name, kindName(f.kind), describeDefault(f.help, f.def, f.choices))
+		}
+	}
+}
+
+// printGroupHelp prints the subcommands of a group
+func printGroupHelp(s *state, g *groupDef, defs []*commandDef) {
+	s.ui.Header("Help: " + g.name)
+	s.ui.Item("%s\n", g.summary)
+	s.ui.Item("Usage:")
+	s.ui.Item("  gator %s <command> [args]\n", g.name)
+	s.ui.Item("Commands:")
+	for _, def := range defs {
+		s.ui.Column("  %s\t%s\t\n", def.name, def.summary)
+	}
+	if g.def != "" {
+		s.ui.Item("\nRunning 'gator %s' on its own runs 'gator %s %s'", g.name, g.name, g.def)
+	}
+}
+
+// checkValue validates a raw value against a kind and an optional list of choices
+func checkValue(kind argKind, choices []string, v string) error {
+	if len(choices) > 0 && !slices.Contains(choices, v) {
+		return fmt.Errorf("%q is not one of %s", v, strings.Join(choices, ", "))
+	}
+	switch kind {
+	case kindInt:
+		if _, err := strconv.Atoi(v); err != nil {
+			return fmt.Errorf("%q is not a whole number", v)
+		}
+	case kindBool:
+		if _, err := strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("%q is not true or false", v)
+		}
+	case kindDuration:
+		if _, err := time.ParseDuration(v); err != nil {
+			return fmt.Errorf("%q is not a duration (ex 1m, 2h)", v)
+		}
+	case kindURL:
+		if !isValidURL(v) {
+			return fmt.Errorf("%q is not an http or https url", v)
+		}
+	case kindFeed:
+		if !isHandleOrID(v) && !isValidURL(v) {
+			return fmt.Errorf("%q is not a feed handle, id or url", v)
+		}
+	}
+	return nil
+}
+
+func kindName(kind argKind) string {
+	switch kind {
+	case kindInt:
+		return "int"
+	case kindBool:
+		return "bool"
+	case kindDuration:
+		return "duration"
+	case kindURL:
+		return "url"
+	case kindFeed:
+		return "feed"
+	}
+	return "string"
+}
+
+func describeDefault(help, def string, choices []string) string {
+	if len(choices) > 0 {
+		help += " (one of " + strings.Join(choices, ", ") + ")"
+	}
+	if def != "" {
+		help += " (default " + def + ")"
+	}
+	return help
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// completer returns the dynamic candidates for an argument or flag value.
+// A candidate may carry a description after a tab, ex "https://url\thackernews".
+type completer func(s *state) []string
+
+const bashCompletion = `# bash completion for gator, load with: source <(gator completion bash)
+_gator() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    [[ "$line" == *" " ]] && words+=("")
+    local cur="${words[${#words[@]}-1]}"
+
+    local IFS=$'\n'
+    COMPREPLY=($(gator __complete "${words[@]:1}" 2>/dev/null | cut -f1))
+
+    # bash splits words on ':' so urls are completed relative to the last colon
+    if [[ "$cur" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+        local colon_prefix="${cur%"${cur##*:}"}"
+        local i=${#COMPREPLY[@]}
+        while ((i-- > 0)); do
+            COMPREPLY[i]="${COMPREPLY[i]#"$colon_prefix"}"
+        done
+    fi
+}
+complete -o default -F _gator gator
+`
+
+const zshCompletion = `#compdef gator
+# zsh completion for gator, load with: source <(gator completion zsh)
+_gator() {
+    local -a lines candidates
+    local line value desc
+    lines=(${(f)"$(gator __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    for line in $lines; do
+        value=${line%%$'\t'*}
+        desc=${line#*$'\t'}
+        value=${value//:/\\:}
+        if [[ "$line" == *$'\t'* ]]; then
+            candidates+=("$value:$desc")
+        else
+            candidates+=("$value")
+        fi
+    done
+    _describe 'gator' candidates
+}
+
+if [[ "$funcstack[1]" == "_gator" ]]; then
+    _gator "$@"
+else
+    compdef _gator gator
+fi
+`
+
+const fishCompletion = `# fish completion for gator, load with: gator completion fish | source
+function __gator_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    gator __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c gator -f -a '(__gator_complete)'
+`
+
+// handlerCompletion prints the completion script for a shell, the scripts call 'gator __complete' for candidates
+func handlerCompletion(s *state, cmd command) error {
+	switch cmd.String("shell") {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	}
+	return nil
+}
+
+// handlerComplete prints the candidates for the last of the given words, one per line.
+// It backs the completion scripts and never fails, a database error just means fewer candidates.
+func (c *commands) handlerComplete(s *state, cmd command) error {
+	for _, candidate := range c.completions(s, cmd.args) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// completions returns the sorted candidates for the last word, the words before it are already complete
+func (c *commands) completions(s *state, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	prev, cur := words[:len(words)-1], words[len(words)-1]
+
+	candidates := []string{}
+	switch {
+	case len(prev) == 0:
+		for _, def := range c.subcommands("") {
+			candidates = append(candidates, def.name)
+		}
+		for name := range c.groups {
+			candidates = append(candidates, name)
+		}
+	case len(prev) == 1 && c.groups[prev[0]] != nil:
+		for _, def := range c.subcommands(prev[0]) {
+			candidates = append(candidates, strings.TrimPrefix(def.name, prev[0]+" "))
+		}
+	default:
+		def, rest, _, err := c.resolve(prev)
+		if err != nil {
+			return nil
+		}
+		candidates = def.completions(s, rest, cur)
+	}
+
+	matches := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, cur) {
+			matches = append(matches, candidate)
+		}
+	}
+	slices.Sort(matches)
+	return matches
+}
+
+// completions returns the candidates for the word being typed after the given arguments
+func (d *commandDef) completions(s *state, rest []string, cur string) []string {
+	if strings.HasPrefix(cur, "-") {
+		candidates := []string{"--help"}
+		for _, f := range slices.Concat(d.flags, globalFlags) {
+			candidates = append(candidates, "--"+f.name)
+		}
+		return candidates
+	}
+
+	// the word is the value of the flag before it
+	if len(rest) > 0 {
+		last := rest[len(rest)-1]
+		if name, ok := strings.CutPrefix(last, "--"); ok && !strings.Contains(name, "=") {
+			if f := d.flag(name); f != nil && f.kind != kindBool {
+				return candidatesFor(s, f.choices, f.complete)
+			}
+		}
+	}
+
+	// count the positional arguments already given, skipping flags and their values
+	n := 0
+	for i := 0; i < len(rest); i++ {
+		name, ok := strings.CutPrefix(rest[i], "--")
+		if !ok {
+			n++
+			continue
+		}
+		if f := d.flag(name); f != nil && f.kind != kindBool && !strings.Contains(name, "=") {
+			i++
+		}
+	}
+
+	for i, a := range d.args {
+		if i == n || (a.variadic && n >= i) {
+			if a.kind == kindBool {
+				return []string{"true", "false"}
+			}
+			return candidatesFor(s, a.choices, a.complete)
+		}
+	}
+	return nil
+}
+
+func candidatesFor(s *state, choices []string, complete completer) []string {
+	if len(choices) > 0 {
+		return choices
+	}
+	// the completers look values up in the database, there is nothing to offer when it can't be opened
+	if complete != nil && s.db != nil {
+		return complete(s)
+	}
+	return nil
+}
+
+// completeUsers completes the names of every user
+func completeUsers(s *state) []string {
+	users, err := s.db.GetUsers(context.Background())
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+// completeFeedURLs completes the url of every feed, described by the feed's name
+func completeFeedURLs(s *state) []string {
+	feeds, err := s.db.GetAllFeeds(context.Background())
+	if err != nil {
+		return nil
+	}
+	urls := []string{}
+	for _, f := range feeds {
+		urls = append(urls, f.Url+"\t"+f.Name)
+	}
+	return urls
+}
+
+// completeFollowedURLs completes the urls of the feeds the current user follows, described by the feed's name
+func completeFollowedURLs(s *state) []string {
+	user, err := s.db.GetUser(context.Background(), s.config.Current_User_Name)
+	if err != nil {
+		return nil
+	}
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	urls := []string{}
+	for _, f := range follows {
+		urls = append(urls, f.FeedUrl+"\t"+f.FeedName)
+	}
+	return urls
+}
+
+// completeCategories completes the names of the current user's categories
+func completeCategories(s *state) []string {
+	user, err := s.db.GetUser(context.Background(), s.config.Current_User_Name)
+	if err != nil {
+		return nil
+	}
+	categories, err := s.db.GetCategoriesForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for _, c := range categories {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// completeRuleNumbers completes the numbers of the current user's rules, described by the rule
+func completeRuleNumbers(s *state) []string {
+	user, err := s.db.GetUser(context.Background(), s.config.Current_User_Name)
+	if err != nil {
+		return nil
+	}
+	dbRules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	numbers := []string{}
+	for i, r := range dbRules {
+		numbers = append(numbers, fmt.Sprintf("%d\t%s", i+1, describeRule(toRule(r))))
+	}
+	return numbers
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/dedupe"
+)
+
+// posts fetched within this window are compared when looking for duplicates
+const duplicateWindow = 7 * 24 * time.Hour
+
+// findCluster looks for a post from another feed that is the same story as p and returns its cluster.
+// uuid.Nil is returned when the post is not a duplicate and should start a cluster of its own.
+func findCluster(s *state, feedID uuid.UUID, p dedupe.Post) (uuid.UUID, error) {
+	candidates, err := s.db.GetDuplicateCandidates(context.Background(), database.GetDuplicateCandidatesParams{
+		FeedID:       feedID,
+		CanonicalUrl: p.CanonicalURL,
+		CreatedAt:    time.Now().Add(-duplicateWindow),
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to get duplicate candidates %v", err)
+	}
+
+	for _, c := range candidates {
+		if dedupe.Duplicate(p, dedupe.Post{
+			CanonicalURL: c.CanonicalUrl,
+			Title:        c.Title,
+			Simhash:      uint64(c.Simhash.Int64),
+			HasSimhash:   c.Simhash.Valid,
+		}) {
+			return c.ClusterID, nil
+		}
+	}
+	return uuid.Nil, nil
+}
+
+// alsoIn describes the other feeds a post arrived through, for browse listings
+func alsoIn(clusterFeeds []string, feedName string) string {
+	others := dedupe.AlsoIn(clusterFeeds, feedName)
+	if len(others) == 0 {
+		return ""
+	}
+	return "also in: " + strings.Join(others, ", ")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/config"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/ui"
+)
+
+// what happens to a feed once its last follow is gone, set by retention.unfollowed_feeds in the config
+const (
+	unfollowedDelete      = "delete"
+	unfollowedDeletePosts = "delete_posts"
+	unfollowedKeep        = "keep"
+)
+
+// unfollowedPolicy reads retention.unfollowed_feeds, feeds are deleted when it is not set
+func unfollowedPolicy(cfg config.Retention) (string, error) {
+	switch cfg.Unfollowed_Feeds {
+	case "":
+		return unfollowedDelete, nil
+	case unfollowedDelete, unfollowedDeletePosts, unfollowedKeep:
+		return cfg.Unfollowed_Feeds, nil
+	}
+	return "", fmt.Errorf("invalid retention unfollowed_feeds in config %q, use %s, %s or %s", cfg.Unfollowed_Feeds, unfollowedDelete, unfollowedDeletePosts, unfollowedKeep)
+}
+
+// collectedFeed is a feed nobody follows and the posts collected with it
+type collectedFeed struct {
+	feed  database.Feed
+	posts int64
+}
+
+// collectFeeds deletes the feeds nobody follows, or only their posts, as policy says. Only the feeds in
+// candidates are looked at, nil looks at every feed. Feeds are shared, so this is what runs when the last
+// user following a feed unfollows it or is deleted.
+func collectFeeds(s *state, candidates map[uuid.UUID]bool, policy string, dryRun bool) ([]collectedFeed, error) {
+	if policy == unfollowedKeep {
+		return nil, nil
+	}
+	ctx := context.Background()
+	feeds, err := s.db.GetUnfollowedFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get unfollowed feeds %v", err)
+	}
+
+	var collected []collectedFeed
+	for _, f := range feeds {
+		if candidates != nil && !candidates[f.ID] {
+			continue
+		}
+		posts, err := s.db.CountPostsForFeed(ctx, f.ID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to count posts %v", err)
+		}
+		// a feed kept without posts has nothing left to collect
+		if policy == unfollowedDeletePosts && posts == 0 {
+			continue
+		}
+		collected = append(collected, collectedFeed{feed: f, posts: posts})
+		if dryRun {
+			continue
+		}
+
+		if policy == unfollowedDelete {
+			err = s.db.DeleteFeed(ctx, f.ID)
+		} else if _, err = s.db.DeletePostsForFeed(ctx, f.ID); err == nil {
+			err = s.db.MarkFeedUnfetched(ctx, f.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to collect feed %s %v", f.Name, err)
+		}
+	}
+	return collected, nil
+}
+
+// collectCounts counts the feeds out of followed that nobody else follows and their posts, which collectFeeds
+// deletes by policy once the follows are gone
+func collectCounts(s *state, followed map[uuid.UUID]bool, policy string) ([]tableCount, error) {
+	if policy == unfollowedKeep || len(followed) == 0 {
+		return nil, nil
+	}
+	ctx := context.Background()
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get feeds %v", err)
+	}
+	unfollowed, posts := 0, 0
+	for _, f := range feeds {
+		if !followed[f.ID] || f.Followers > 1 {
+			continue
+		}
+		n, err := s.db.CountPostsForFeed(ctx, f.ID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to count posts %v", err)
+		}
+		unfollowed++
+		posts += int(n)
+	}
+	var counts []tableCount
+	if policy == unfollowedDelete {
+		counts = append(counts, tableCount{"feeds nobody else follows", unfollowed})
+	}
+	return append(counts, tableCount{"posts of feeds nobody else follows", posts}), nil
+}
+
+// unfollowFeed deletes a user's follow of a feed and collects the feed in the same transaction when nobody
+// else follows it, it returns what was collected and the policy it was collected by
+func unfollowFeed(s *state, user database.User, feedID uuid.UUID) ([]collectedFeed, string, error) {
+	policy, err := unfollowedPolicy(s.config.Retention)
+	if err != nil {
+		return nil, "", err
+	}
+	var collected []collectedFeed
+	err = inTx(s, func(tx *state) error {
+		if err := tx.db.DeleteFeedFollowForUser(context.Background(), database.DeleteFeedFollowForUserParams{
+			UserID: user.ID,
+			FeedID: feedID,
+		}); err != nil {
+			return fmt.Errorf("error deleting feed follow for user %v", err)
+		}
+		collected, err = collectFeeds(tx, map[uuid.UUID]bool{feedID: true}, policy, false)
+		return err
+	})
+	return collected, policy, err
+}
+
+// reportCollected tells the user which feeds went with their last follow
+func reportCollected(s *state, collected []collectedFeed, policy string) {
+	for _, c := range collected {
+		if policy == unfollowedDelete {
+			s.ui.Info(fmt.Sprintf("Nobody follows %s anymore, it was deleted with %d posts", c.feed.Name, c.posts))
+		} else {
+			s.ui.Info(fmt.Sprintf("Nobody follows %s anymore, its %d posts were deleted", c.feed.Name, c.posts))
+		}
+	}
+}
+
+// handlerGC deletes every feed nobody follows along with its posts, --keep-feeds deletes only the posts.
+// Feeds are collected as their last follow goes, this catches the ones left over by
+// retention.unfollowed_feeds set to keep or by older versions of gator. Only admins may run it.
+func handlerGC(s *state, cmd command, user database.User) error {
+	if !user.IsAdmin {
+		return errNotAdmin("collect the feeds nobody follows")
+	}
+	dryRun := cmd.Bool("dry-run")
+	policy := unfollowedDelete
+	if cmd.Bool("keep-feeds") {
+		policy = unfollowedDeletePosts
+	}
+
+	var collected []collectedFeed
+	err := inTx(s, func(tx *state) error {
+		var err error
+		collected, err = collectFeeds(tx, nil, policy, dryRun)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	title := "Unfollowed Feeds"
+	if dryRun {
+		title += " (dry run)"
+	}
+	t := ui.Table{Title: title, Columns: []string{"name", "url", "posts"}, Roles: map[string]ui.Role{"name": ui.RoleFeedName}}
+	var posts int64
+	for _, c := range collected {
+		t.Add(c.feed.Name, c.feed.Url, c.posts)
+		posts += c.posts
+	}
+	if err := s.ui.Table(t); err != nil {
+		return err
+	}
+
+	switch {
+	case dryRun && policy == unfollowedDelete:
+		s.ui.Info(fmt.Sprintf("%d feeds and %d posts would be deleted", len(collected), posts))
+	case dryRun:
+		s.ui.Info(fmt.Sprintf("%d posts would be deleted", posts))
+	case policy == unfollowedDelete:
+		s.ui.Info(fmt.Sprintf("%d feeds and %d posts deleted", len(collected), posts))
+	default:
+		s.ui.Info(fmt.Sprintf("%d posts deleted", posts))
+	}
+	return nil
+}
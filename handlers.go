@@ -2,38 +2,73 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"os"
+	"maps"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/dedupe"
 	"github.com/joshhartwig/gator/internal/ui"
-	"github.com/lib/pq"
 )
 
-// quit the app
+// handlerQuit ends the shell session, outside of a session there is nothing to quit
 func (c *commands) handlerQuit(s *state, cmd command) error {
-	os.Exit(1)
-	return nil
+	if !c.inShell {
+		s.ui.Info("quit only ends a 'gator shell' session")
+		return nil
+	}
+	return errQuit
 }
 
-// handlerHelp prints a list of all available command names to the standard output.
-// It iterates over the command names stored in the commands struct and outputs each one.
+// handlerHelp prints every command to the standard output, top level commands first and then each group.
+// Commands and groups are sorted by name.
+// Given a group it prints the group's subcommands and given a command it prints the full usage of that command.
 // Returns an error if any occurs during execution, otherwise returns nil.
 func (c *commands) handlerHelp(s *state, cmd command) error {
+	words := cmd.List("command")
+	if len(words) == 1 {
+		if g, ok := c.groups[words[0]]; ok {
+			printGroupHelp(s, g, c.subcommands(g.name))
+			return nil
+		}
+	}
+	if len(words) > 0 {
+		def, _, _, err := c.resolve(words)
+		if err != nil {
+			return err
+		}
+		printCommandHelp(s, def)
+		return nil
+	}
+
 	s.ui.Header("Help")
-	s.ui.Item("%sUsage:%s", ui.Red, ui.Reset)
-	s.ui.Item("  gator <command> [args]\n")
-	s.ui.Item("%sCommands:%s", ui.Red, ui.Reset)
-	for n := range c.names {
-		s.ui.Item("  %s", n)
+	s.ui.Item("%s", s.ui.Paint(ui.RoleHeader, "Usage:"))
+	s.ui.Item("  gator <command> [args]")
+	s.ui.Item("  gator <group> <command> [args]")
+	s.ui.Item("  gator help <command>\n")
+
+	s.ui.Item("%s", s.ui.Paint(ui.RoleHeader, "Commands:"))
+	for _, def := range c.subcommands("") {
+		s.ui.Column("  %s\t%s\t\n", def.name, def.summary)
 	}
 
-	s.ui.Item("\n%sExamples:%s", ui.Red, ui.Reset)
-	s.ui.Item("  gator register ted")
-	s.ui.Item("  gator addfeed \"hn\" \"https://hackernews.com/rss")
+	groups := slices.Sorted(maps.Keys(c.groups))
+	for _, name := range groups {
+		s.ui.Item("\n%s %s", s.ui.Paint(ui.RoleHeader, name+":"), c.groups[name].summary)
+		for _, def := range c.subcommands(name) {
+			s.ui.Column("  %s\t%s\t\n", def.name, def.summary)
+		}
+	}
+
+	s.ui.Item("\n%s", s.ui.Paint(ui.RoleHeader, "Examples:"))
+	s.ui.Item("  gator user register ted")
+	s.ui.Item("  gator feed add \"hn\" \"https://hackernews.com/rss")
 	s.ui.Item("  gator agg 1m")
 	return nil
 }
@@ -47,12 +82,15 @@ func handlerListFollows(s *state, cmd command) error {
 		return err
 	}
 
-	s.ui.Header("Listing Feed Follows for each user")
+	t := ui.Table{
+		Title:   "Listing Feed Follows for each user",
+		Columns: []string{"feed_id", "user_id", "user_name", "feed_name"},
+		Roles:   map[string]ui.Role{"feed_name": ui.RoleFeedName},
+	}
 	for _, f := range follows {
-		s.ui.Column("%s\t%s\t%s\t%s\n", f.FeedID, f.UserID, f.UserName, f.FeedName)
+		t.Add(f.FeedID, f.UserID, f.UserName, f.FeedName)
 	}
-
-	return nil
+	return s.ui.Table(t)
 }
 
 // handlerAddFeed handles the addition of a new feed to the user's account.
@@ -63,12 +101,8 @@ func handlerListFollows(s *state, cmd command) error {
 func handlerAddFeed(s *state, cmd command, user database.User) error {
 	s.ui.Header("Add Feed")
 
-	if len(cmd.args) < 2 || len(cmd.args) > 3 {
-		return fmt.Errorf(`addfeed should have two or more commands (ex addfeed "hackernews" "http://url")`)
-	}
-
-	name := cmd.args[0]
-	url := cmd.args[1]
+	name := cmd.String("name")
+	url := cmd.String("url")
 
 	// fetch feed
 	_, err := fetchFeed(context.Background(), url)
@@ -77,156 +111,419 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		return err
 	}
 
-	// create a new feed in the db and return it
-	retFeed, err := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      name,
-		Url:       url,
-		UserID:    user.ID,
-	})
+	// the feed, the follow and its handle are created together so a failed follow leaves no orphan feed
+	var retFeed database.Feed
+	var follow database.CreateFeedFollowRow
+	var handles map[uuid.UUID]int32
+	err = inTx(s, func(tx *state) error {
+		retFeed, err = tx.db.CreateFeed(context.Background(), database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      name,
+			Url:       url,
+			AddedBy:   uuid.NullUUID{UUID: user.ID, Valid: true},
+		})
+		if isUniqueViolation(err) {
+			// feeds are shared, someone else added it already
+			return fmt.Errorf("%s is already added, follow it with 'gator follow add %s'", url, url)
+		}
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		s.ui.Error(err.Error())
-		return err
-	}
+		// create a new follow
+		follow, err = tx.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    retFeed.ID,
+		})
+		if err != nil {
+			return err
+		}
 
-	// create a new follow
-	follow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    retFeed.UserID,
-		FeedID:    retFeed.ID,
+		handles, err = feedHandles(tx, user, []uuid.UUID{retFeed.ID})
+		return err
 	})
-
 	if err != nil {
 		s.ui.Error(err.Error())
 		return err
 	}
 
 	s.ui.Item("Feed Name: %s feed url: %s feed following: %s created", retFeed.Name, retFeed.Url, follow.FeedID)
+	s.ui.Item("Feed handle: %d", handles[retFeed.ID])
 	return nil
 }
 
-// handlerGetFeeds retrieves all feeds from the database and prints their details (Name, Url, UserName) to the standard output.
+// handlerGetFeeds retrieves all feeds from the database and prints their details (Handle, Name, Url, the user who added them
+// and how many users follow them) to the standard output.
 // It returns an error if fetching feeds from the database fails.
-func handlerGetFeeds(s *state, cmd command) error {
+func handlerGetFeeds(s *state, cmd command, user database.User) error {
 	feeds, err := s.db.GetFeeds(context.Background())
 	if err != nil {
 		s.ui.Error(err.Error())
 		return err
 	}
-	s.ui.Header("Feeds")
+
+	ids := []uuid.UUID{}
+	for _, f := range feeds {
+		ids = append(ids, f.ID)
+	}
+	handles, err := feedHandles(s, user, ids)
+	if err != nil {
+		return err
+	}
+
+	t := ui.Table{
+		Title:   "Feeds",
+		Columns: []string{"handle", "name", "url", "added_by", "followers"},
+		Roles:   map[string]ui.Role{"name": ui.RoleFeedName},
+	}
 	for _, f := range feeds {
-		s.ui.Column("%s\t%s\t%s\t\n", f.Name, f.Url, f.UserName)
+		t.Add(handles[f.ID], f.Name, f.Url, f.AddedByName.String, f.Followers)
 	}
+	return s.ui.Table(t)
+}
+
+// handlerFeedStatus prints when each feed was last fetched along with its retention overrides
+func handlerFeedStatus(s *state, cmd command) error {
+	feeds, err := s.db.GetAllFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to get feeds %v", err)
+	}
+
+	t := ui.Table{
+		Title:   "Feed Status",
+		Columns: []string{"name", "url", "last_fetched_at", "retention_max_age", "retention_max_posts"},
+		Roles:   map[string]ui.Role{"name": ui.RoleFeedName, "last_fetched_at": ui.RoleTimestamp},
+	}
+	for _, f := range feeds {
+		fetched := ui.Text{Display: "never"}
+		if f.LastFetchedAt.Valid {
+			fetched = ui.Text{Value: f.LastFetchedAt.Time, Display: "last fetched " + f.LastFetchedAt.Time.Format(time.DateTime)}
+		}
+		maxAge := ui.Text{Display: "max_age " + describeNullString(f.RetentionMaxAge)}
+		if f.RetentionMaxAge.Valid {
+			maxAge.Value = f.RetentionMaxAge.String
+		}
+		maxPosts := ui.Text{Display: "max_posts " + describeNullInt(f.RetentionMaxPosts)}
+		if f.RetentionMaxPosts.Valid {
+			maxPosts.Value = f.RetentionMaxPosts.Int32
+		}
+		t.Add(f.Name, f.Url, fetched, maxAge, maxPosts)
+	}
+	return s.ui.Table(t)
+}
+
+// handlerRenameFeed changes the name of a feed for everyone who follows it, display names set with
+// 'follow set' still take its place. Only the user who added the feed and admins may rename it.
+func handlerRenameFeed(s *state, cmd command, user database.User) error {
+	feed, err := getFeedByRef(s, user, cmd.String("feed"))
+	if err != nil {
+		return err
+	}
+	if err := checkAddedBy(feed, user, "rename"); err != nil {
+		return err
+	}
+	name := cmd.String("name")
+	if name == "" {
+		return errors.New("the new name can not be empty")
+	}
+
+	renamed, err := s.db.RenameFeed(context.Background(), database.RenameFeedParams{ID: feed.ID, Name: name})
+	if err != nil {
+		return fmt.Errorf("unable to rename feed %v", err)
+	}
+	s.ui.Info(fmt.Sprintf("Renamed %s to %s", feed.Name, renamed.Name))
 	return nil
 }
 
-// handlerUnfollow will unfollow a feed assigned to a user if that user is currently following the feed
-func handlerUnfollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 1 || len(cmd.args) > 2 {
-		return fmt.Errorf("unsupported error count %d", len(cmd.args))
+// handlerSetFeedURL points a feed at a new url, ex when a site moves its feed. The new url is fetched first
+// so a typo can't break the feed, and agg fetches the feed again next. Only the user who added the feed and
+// admins may change it.
+func handlerSetFeedURL(s *state, cmd command, user database.User) error {
+	feed, err := getFeedByRef(s, user, cmd.String("feed"))
+	if err != nil {
+		return err
+	}
+	if err := checkAddedBy(feed, user, "change the url of"); err != nil {
+		return err
+	}
+	url := cmd.String("url")
+	if url == feed.Url {
+		return fmt.Errorf("%s already uses %s", feed.Name, url)
 	}
 
-	url := cmd.args[0]
+	if _, err := fetchFeed(context.Background(), url); err != nil {
+		return fmt.Errorf("unable to fetch the new url, the feed was not changed %v", err)
+	}
+	updated, err := s.db.SetFeedUrl(context.Background(), database.SetFeedUrlParams{ID: feed.ID, Url: url})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("another feed already uses %s", url)
+		}
+		return fmt.Errorf("unable to change the url of %s %v", feed.Name, err)
+	}
+	s.ui.Info(fmt.Sprintf("%s now uses %s", updated.Name, updated.Url))
+	return nil
+}
 
-	s.ui.Header("Unfollow Feed")
-	if !isValidURL(url) {
-		return fmt.Errorf("invalid url: %s", cmd.args[0])
+// handlerRemoveFeed removes a feed for everyone who follows it. The feed is deleted along with its posts,
+// --keep-posts only deletes the follows so the feed stops being fetched and its posts stay for anyone
+// who follows it again. The follows and posts that go are listed and confirmed first unless --yes is
+// passed. Only the user who added the feed and admins may remove it.
+func handlerRemoveFeed(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	feed, err := getFeedByRef(s, user, cmd.String("feed"))
+	if err != nil {
+		return err
 	}
+	if err := checkAddedBy(feed, user, "remove"); err != nil {
+		return err
+	}
+	keepPosts := cmd.Bool("keep-posts")
 
-	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	followers, err := s.db.CountFeedFollowsForFeed(ctx, feed.ID)
 	if err != nil {
-		return fmt.Errorf("unable to get feed follows %v", err)
+		return fmt.Errorf("unable to count feed follows %v", err)
+	}
+	posts := int64(0)
+	if !keepPosts {
+		if posts, err = s.db.CountPostsForFeed(ctx, feed.ID); err != nil {
+			return fmt.Errorf("unable to count posts %v", err)
+		}
 	}
 
-	// loop through the feeds and find the feed id that matches the url
-	// when we find it assign to feedIdToDelete
-	feedIdToDelete := uuid.UUID{}
-	for _, f := range follows {
-		if f.FeedUrl == url {
-			feedIdToDelete = f.FeedID
+	t := ui.Table{Title: fmt.Sprintf("Rows deleted by removing %s", feed.Name), Columns: []string{"table", "rows"}}
+	t.Add("follows", followers)
+	t.Add("posts", posts)
+	if err := s.ui.Table(t); err != nil {
+		return err
+	}
+	if !cmd.Bool("yes") {
+		ok, err := confirm(s, fmt.Sprintf("Remove feed %s?", feed.Name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.ui.Info("Remove cancelled, nothing was deleted")
+			return nil
 		}
 	}
 
-	// delete the feed now that we have the feedid & user
-	if err = s.db.DeleteFeedFollowForUser(context.Background(), database.DeleteFeedFollowForUserParams{
-		UserID: user.ID,
-		FeedID: feedIdToDelete,
-	}); err != nil {
-		return fmt.Errorf("error deleting feed follow for user %v", err)
+	err = inTx(s, func(tx *state) error {
+		if keepPosts {
+			_, err := tx.db.DeleteFeedFollowsForFeed(ctx, feed.ID)
+			return err
+		}
+		return tx.db.DeleteFeed(ctx, feed.ID)
+	})
+	if err != nil {
+		return fmt.Errorf("error removing feed %s %v", feed.Name, err)
+	}
+
+	if keepPosts {
+		s.ui.Info(fmt.Sprintf("Removed %s from every user following it, the feed and its posts were kept", feed.Name))
+		s.ui.Info(fmt.Sprintf("Nobody follows it so it is no longer fetched, 'gator follow add %s' brings it back", feed.Url))
+	} else {
+		s.ui.Info(fmt.Sprintf("Removed %s along with its posts", feed.Name))
 	}
+	return nil
+}
 
-	s.ui.Item("Successfully unfollowed feed: %s", url)
+// handlerUnfollow will unfollow a feed assigned to a user if that user is currently following the feed
+func handlerUnfollow(s *state, cmd command, user database.User) error {
+	s.ui.Header("Unfollow Feed")
+
+	// the feed may be given by handle, id or url
+	follow, err := getFeedFollowByRef(s, user, cmd.String("feed"))
+	if err != nil {
+		return err
+	}
+
+	// delete the follow now that we have the feedid & user, the feed goes too when nobody else follows it
+	collected, policy, err := unfollowFeed(s, user, follow.FeedID)
+	if err != nil {
+		return err
+	}
+
+	s.ui.Item("Successfully unfollowed feed: %s", follow.FeedUrl)
+	reportCollected(s, collected, policy)
 	return nil
 }
 
 // handlerGetFollows retrieves the list of feed follows for the current user from the database
-// and prints each followed feed's username and feed name to the standard output.
+// and prints each followed feed's handle, username, feed name, settings and categories.
+// An optional category name limits the list to the feeds in that category.
 // It returns an error if the user or their feed follows cannot be retrieved.
 func handlerGetFollows(s *state, cmd command, user database.User) error {
-	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	assigned, err := s.db.GetFeedFollowCategoriesForUser(context.Background(), user.ID)
 	if err != nil {
-		return fmt.Errorf("unable to get feed follows for user %v", err)
+		return fmt.Errorf("unable to get categories for user %v", err)
 	}
-	s.ui.Header("Show Follows")
+	categories := make(map[uuid.UUID][]string)
+	for _, a := range assigned {
+		categories[a.FeedFollowID] = append(categories[a.FeedFollowID], a.CategoryName)
+	}
+
+	title := "Show Follows"
+	follows := []database.GetFeedFollowsForUserRow{}
+	if cmd.Has("category") {
+		category, err := getCategoryByName(s, user, cmd.String("category"))
+		if err != nil {
+			return err
+		}
+		rows, err := s.db.GetFeedFollowsForUserInCategory(context.Background(), database.GetFeedFollowsForUserInCategoryParams{
+			UserID:     user.ID,
+			CategoryID: category.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to get feed follows for user %v", err)
+		}
+		title = "Show Follows in " + category.Name
+		for _, f := range rows {
+			follows = append(follows, database.GetFeedFollowsForUserRow(f))
+		}
+	} else {
+		follows, err = s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("unable to get feed follows for user %v", err)
+		}
+	}
+
+	ids := []uuid.UUID{}
 	for _, f := range follows {
-		s.ui.Column("%s\t%s\t\n", f.UserName, f.FeedName)
+		ids = append(ids, f.FeedID)
 	}
-	return nil
+	handles, err := feedHandles(s, user, ids)
+	if err != nil {
+		return err
+	}
+
+	t := followsTable(title)
+	for _, f := range follows {
+		addFollow(&t, f, handles[f.FeedID], categories)
+	}
+	return s.ui.Table(t)
 }
 
-// handleReset will delete everything in the users table
-func handlerReset(s *state, cmd command) error {
-	err := s.db.DeleteAllUsers(context.Background())
-	if err != nil {
-		return fmt.Errorf("error reseting database %v", err)
+func followsTable(title string) ui.Table {
+	return ui.Table{
+		Title:   title,
+		Columns: []string{"feed_handle", "user_name", "feed_name", "feed_url", "priority", "muted", "notify", "categories"},
+		Hidden:  []string{"feed_url"},
+		Roles:   map[string]ui.Role{"feed_name": ui.RoleFeedName},
 	}
-	s.ui.Header("Reset Database")
-	s.ui.Info("Reset database complete")
-	return nil
 }
 
-// handlerFollow takes a single url record and creates a new
-// follow record for the current user
-func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 1 || len(cmd.args) > 1 {
-		return fmt.Errorf("should only have one argument in follow command but got %d", len(cmd.args))
+func addFollow(t *ui.Table, f database.GetFeedFollowsForUserRow, handle int32, categories map[uuid.UUID][]string) {
+	t.Add(
+		handle,
+		f.UserName,
+		f.FeedName,
+		f.FeedUrl,
+		f.Priority,
+		ui.Text{Value: f.Muted, Display: followFlags(f.Muted, false)},
+		ui.Text{Value: f.Notify, Display: followFlags(false, f.Notify)},
+		categories[f.ID],
+	)
+}
+
+// handlerSetFollow changes the current user's settings for a followed feed.
+// The display name replaces the feed name in the user's listings, an empty name restores the original.
+func handlerSetFollow(s *state, cmd command, user database.User) error {
+	follow, err := getFeedFollowByRef(s, user, cmd.String("feed"))
+	if err != nil {
+		return err
+	}
+
+	value := cmd.String("value")
+
+	params := database.UpdateFeedFollowSettingsParams{
+		ID:          follow.ID,
+		DisplayName: follow.DisplayName,
+		Priority:    follow.Priority,
+		Muted:       follow.Muted,
+		Notify:      follow.Notify,
+	}
+
+	switch cmd.String("setting") {
+	case "name":
+		params.DisplayName = sql.NullString{String: value, Valid: strings.TrimSpace(value) != ""}
+	case "priority":
+		p, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("priority should be a whole number, recieved %q", value)
+		}
+		params.Priority = int32(p)
+	case "muted":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("muted should be true or false, recieved %q", value)
+		}
+		params.Muted = b
+	case "notify":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("notify should be true or false, recieved %q", value)
+		}
+		params.Notify = b
 	}
 
-	url := cmd.args[0]
-	if url == "" {
-		return fmt.Errorf("The url is blank, pass in a properly formatted url")
+	s.ui.Header("Update Follow Settings")
+	updated, err := s.db.UpdateFeedFollowSettings(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("error updating follow settings %v", err)
 	}
 
-	if !isValidURL(url) {
-		return fmt.Errorf("The url is not a valid format, pass in a properly formatted url")
+	name := follow.FeedName
+	if updated.DisplayName.Valid {
+		name = updated.DisplayName.String
 	}
+	s.ui.Item("Feed %s: priority %d %s", name, updated.Priority, followFlags(updated.Muted, updated.Notify))
+	return nil
+}
 
+// handlerFollow takes a single feed handle, id or url and creates a new
+// follow record for the current user
+func handlerFollow(s *state, cmd command, user database.User) error {
 	user, err := s.db.GetUser(context.Background(), s.config.Current_User_Name)
 	if err != nil {
 		return fmt.Errorf("unable to get user from db %v", err)
 	}
 
-	feed, err := s.db.GetFeedByUrl(context.Background(), url)
+	feed, err := getFeedByRef(s, user, cmd.String("feed"))
 	if err != nil {
-		return fmt.Errorf("unable to get feed by url %v", err)
+		return err
 	}
 	s.ui.Header("Create a Following for User")
 	s.ui.Item("Found current user:%s and feed by url:%s", user.Name, feed.Name)
 
-	follow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    user.ID,
-		FeedID:    feed.ID,
+	var follow database.CreateFeedFollowRow
+	var handles map[uuid.UUID]int32
+	err = inTx(s, func(tx *state) error {
+		follow, err = tx.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to follow feed %s %v", feed.Name, err)
+		}
+
+		handles, err = feedHandles(tx, user, []uuid.UUID{feed.ID})
+		return err
 	})
+	if err != nil {
+		return err
+	}
 
 	s.ui.Item("New feed following created: id:%s name:%s username:%s", follow.ID, follow.FeedName, follow.UserName)
+	s.ui.Item("Feed handle: %d", handles[feed.ID])
 	return nil
 }
 
@@ -237,53 +534,364 @@ func handlerListUsers(s *state, cmd command) error {
 		return fmt.Errorf("error listing users %s", err.Error())
 	}
 
-	s.ui.Header("List Users")
+	t := ui.Table{Title: "List Users", Columns: []string{"name", "admin", "current"}}
 	for _, u := range users {
+		admin := ui.Text{Value: u.IsAdmin}
+		if u.IsAdmin {
+			admin.Display = "admin"
+		}
+		current := ui.Text{Value: false}
 		if u.Name == s.config.Current_User_Name {
-			s.ui.Item("%s (current logged in user)", u.Name)
-		} else {
-			s.ui.Item("%s", u.Name)
+			current = ui.Text{Value: true, Display: "(current logged in user)"}
+		}
+		t.Add(u.Name, admin, current)
+	}
+	return s.ui.Table(t)
+}
+
+// handlerSetAdmin makes a user an admin or takes it away, only admins may and the last admin can't be removed
+func handlerSetAdmin(s *state, cmd command, user database.User) error {
+	if !user.IsAdmin {
+		return errNotAdmin("change who is an admin")
+	}
+	other, err := s.db.GetUser(context.Background(), cmd.String("username"))
+	if err != nil {
+		return fmt.Errorf("unable to find user in database %s", cmd.String("username"))
+	}
+	admin := cmd.Bool("admin")
+
+	err = inTx(s, func(tx *state) error {
+		if !admin {
+			users, err := tx.db.GetUsers(context.Background())
+			if err != nil {
+				return fmt.Errorf("unable to get users %v", err)
+			}
+			admins := 0
+			for _, u := range users {
+				if u.IsAdmin && u.ID != other.ID {
+					admins++
+				}
+			}
+			if admins == 0 {
+				return fmt.Errorf("%s is the last admin, make another user an admin first", other.Name)
+			}
+		}
+		other, err = tx.db.SetUserAdmin(context.Background(), database.SetUserAdminParams{ID: other.ID, IsAdmin: admin})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.ui.Header("User Admin")
+	if other.IsAdmin {
+		s.ui.Info(fmt.Sprintf("%s is an admin", other.Name))
+	} else {
+		s.ui.Info(fmt.Sprintf("%s is no longer an admin", other.Name))
+	}
+	return nil
+}
+
+// handlerDeleteUser deletes a user with their follows, categories, rules and post states. Feeds are shared so
+// the feeds the user added stay for their followers, --transfer-to records another user as having added them
+// and feeds only the deleted user followed are collected like on unfollow. Users may delete themselves and
+// admins anyone, the rows that go are listed and confirmed first unless --yes is passed. Deleting the current
+// user logs them out.
+func handlerDeleteUser(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	other, err := s.db.GetUser(ctx, cmd.String("username"))
+	if err != nil {
+		return fmt.Errorf("unable to find user in database %s", cmd.String("username"))
+	}
+	if other.ID != user.ID && !user.IsAdmin {
+		return errNotAdmin("delete another user")
+	}
+	policy, err := unfollowedPolicy(s.config.Retention)
+	if err != nil {
+		return err
+	}
+
+	users, err := s.db.GetUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get users %v", err)
+	}
+	admins := 0
+	for _, u := range users {
+		if u.IsAdmin && u.ID != other.ID {
+			admins++
+		}
+	}
+	if other.IsAdmin && admins == 0 && len(users) > 1 {
+		return fmt.Errorf("%s is the last admin, make another user an admin first", other.Name)
+	}
+
+	var to database.User
+	if cmd.Has("transfer-to") {
+		to, err = s.db.GetUser(ctx, cmd.String("transfer-to"))
+		if err != nil {
+			return fmt.Errorf("unable to find user in database %s", cmd.String("transfer-to"))
+		}
+		if to.ID == other.ID {
+			return fmt.Errorf("unable to transfer the feeds of %s to themselves", other.Name)
+		}
+	}
+
+	counts, followed, err := deleteUserCounts(s, other, policy)
+	if err != nil {
+		return err
+	}
+	t := ui.Table{Title: fmt.Sprintf("Rows deleted with user %s", other.Name), Columns: []string{"table", "rows"}}
+	for _, c := range counts {
+		t.Add(c.table, c.rows)
+	}
+	if err := s.ui.Table(t); err != nil {
+		return err
+	}
+
+	if !cmd.Bool("yes") {
+		ok, err := confirm(s, fmt.Sprintf("Delete user %s?", other.Name))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.ui.Info("Delete cancelled, nothing was deleted")
+			return nil
+		}
+	}
+
+	var collected []collectedFeed
+	var transferred int64
+	err = inTx(s, func(tx *state) error {
+		if cmd.Has("transfer-to") {
+			transferred, err = tx.db.TransferFeeds(ctx, database.TransferFeedsParams{
+				ToUserID:   uuid.NullUUID{UUID: to.ID, Valid: true},
+				FromUserID: uuid.NullUUID{UUID: other.ID, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("unable to transfer feeds %v", err)
+			}
+		}
+		if err := tx.db.DeleteUser(ctx, other.ID); err != nil {
+			return err
+		}
+		collected, err = collectFeeds(tx, followed, policy, false)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting user %s %v", other.Name, err)
+	}
+
+	s.ui.Info(fmt.Sprintf("Deleted user %s", other.Name))
+	if transferred > 0 {
+		s.ui.Info(fmt.Sprintf("%d feeds %s added are now recorded as added by %s", transferred, other.Name, to.Name))
+	}
+	reportCollected(s, collected, policy)
+	if s.config.Current_User_Name == other.Name {
+		if err := s.config.ClearUser(); err != nil {
+			return fmt.Errorf("unable to log out %s %v", other.Name, err)
+		}
+		s.ui.Info("You are logged out, run 'gator user login <name>'")
+	}
+	return nil
+}
+
+// deleteUserCounts counts the rows deleted with a user and returns the feeds they follow, the feeds nobody
+// else follows are counted with their posts when policy collects them
+func deleteUserCounts(s *state, user database.User, policy string) ([]tableCount, map[uuid.UUID]bool, error) {
+	ctx := context.Background()
+	follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get feed follows %v", err)
+	}
+	categories, err := s.db.GetCategoriesForUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get categories %v", err)
+	}
+	rules, err := s.db.GetRulesForUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get rules %v", err)
+	}
+	states, err := s.db.CountPostStatesForUser(ctx, user.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to count post states %v", err)
+	}
+	counts := []tableCount{
+		{"users", 1},
+		{"follows", len(follows)},
+		{"categories", len(categories)},
+		{"rules", len(rules)},
+		{"post states", int(states)},
+	}
+
+	followed := map[uuid.UUID]bool{}
+	for _, f := range follows {
+		followed[f.FeedID] = true
+	}
+	collected, err := collectCounts(s, followed, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(counts, collected...), followed, nil
+}
+
+// handlerRenameUser renames a user, users may rename themselves and admins anyone. Renaming the current
+// user keeps them logged in under the new name.
+func handlerRenameUser(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	other, err := s.db.GetUser(ctx, cmd.String("username"))
+	if err != nil {
+		return fmt.Errorf("unable to find user in database %s", cmd.String("username"))
+	}
+	if other.ID != user.ID && !user.IsAdmin {
+		return errNotAdmin("rename another user")
+	}
+	name := cmd.String("new-name")
+	if name == "" {
+		return errors.New("the new name can not be empty")
+	}
+
+	renamed, err := s.db.RenameUser(ctx, database.RenameUserParams{ID: other.ID, Name: name})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("user %s already exists", name)
 		}
+		return fmt.Errorf("unable to rename user %v", err)
 	}
 
+	s.ui.Info(fmt.Sprintf("Renamed %s to %s", other.Name, renamed.Name))
+	if s.config.Current_User_Name == other.Name {
+		if err := s.config.SetUser(renamed.Name); err != nil {
+			return fmt.Errorf("unable to set user in config %v", err)
+		}
+	}
 	return nil
 }
 
 // handlerBrowsePosts shows all RSS Items that have been gathered in the database
+// the --category flag limits the posts to the feeds in one of the user's categories,
+// --after starts the listing after a post and --page skips whole pages.
+// Each post is shown with a short handle that 'open' and --after accept in place of its id.
 func handlerBrowsePosts(s *state, cmd command, user database.User) error {
-	limit := 3
-	if len(cmd.args) > 0 {
-		if l, err := strconv.Atoi(cmd.args[0]); err == nil {
-			limit = l
+	limit := cmd.Int("limit")
+	if limit < 1 {
+		return fmt.Errorf("the browse limit should be at least 1, recieved %d", limit)
+	}
+	page := cmd.Int("page")
+	if page < 1 {
+		return fmt.Errorf("the browse page should be at least 1, recieved %d", page)
+	}
+	after := uuid.NullUUID{}
+	if cmd.Has("after") {
+		post, err := getPostByRef(s, user, cmd.String("after"))
+		if err != nil {
+			return err
 		}
+		after = uuid.NullUUID{UUID: post.ID, Valid: true}
 	}
 
-	posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	})
+	title := "Browse Posts"
+	next := fmt.Sprintf("gator post browse %d", limit)
+	fetch := func(after uuid.NullUUID) ([]database.GetPostsForUserRow, error) {
+		return s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+			UserID: user.ID,
+			Limit:  int32(limit),
+			After:  after,
+		})
+	}
+
+	if cmd.Has("category") {
+		category, err := getCategoryByName(s, user, cmd.String("category"))
+		if err != nil {
+			return err
+		}
+		title = "Browse Posts in " + category.Name
+		next += " --category " + quoteWord(category.Name)
+		fetch = func(after uuid.NullUUID) ([]database.GetPostsForUserRow, error) {
+			posts, err := s.db.GetPostsForUserInCategory(context.Background(), database.GetPostsForUserInCategoryParams{
+				UserID:     user.ID,
+				CategoryID: category.ID,
+				Limit:      int32(limit),
+				After:      after,
+			})
+			rows := []database.GetPostsForUserRow{}
+			for _, p := range posts {
+				rows = append(rows, database.GetPostsForUserRow(p))
+			}
+			return rows, err
+		}
+	}
+
+	posts, err := browsePage(fetch, after, page)
 	if err != nil {
 		return fmt.Errorf("unable to get posts for user %s", user.Name)
 	}
-	s.ui.Header("Browse Posts")
+	ids := []uuid.UUID{}
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	handles, err := postHandles(s, user, ids)
+	if err != nil {
+		return err
+	}
 
+	t := postsTable(title)
 	for _, p := range posts {
-		s.ui.Column("%s\t%s\t%s\t\n", p.Title, p.Description.String, p.PublishedAt)
+		addPost(&t, p, handles[p.ID])
+	}
+	if err := s.ui.Table(t); err != nil {
+		return err
+	}
+
+	// a full page means there may be more, the last post is the cursor for the next page
+	if len(posts) == limit {
+		s.ui.Info(fmt.Sprintf("Next page: %s --after %d", next, handles[posts[len(posts)-1].ID]))
 	}
 	return nil
 }
 
+// browsePage fetches the given page of posts, the pages before it are walked with the
+// cursor of their last post so the query never has to skip rows with an offset
+func browsePage(fetch func(after uuid.NullUUID) ([]database.GetPostsForUserRow, error), after uuid.NullUUID, page int) ([]database.GetPostsForUserRow, error) {
+	for ; page > 1; page-- {
+		posts, err := fetch(after)
+		if err != nil || len(posts) == 0 {
+			return posts, err
+		}
+		after = uuid.NullUUID{UUID: posts[len(posts)-1].ID, Valid: true}
+	}
+	return fetch(after)
+}
+
+func postsTable(title string) ui.Table {
+	return ui.Table{
+		Title:   title,
+		Columns: []string{"id", "handle", "feed_name", "title", "url", "description", "published_at", "flagged", "also_in"},
+		Hidden:  []string{"id", "url", "flagged"},
+		Roles:   map[string]ui.Role{"feed_name": ui.RoleFeedName},
+	}
+}
+
+func addPost(t *ui.Table, p database.GetPostsForUserRow, handle int32) {
+	t.Add(
+		p.ID,
+		handle,
+		p.FeedName,
+		ui.Text{Value: p.Title, Display: flaggedTitle(p.Title, p.Flagged)},
+		p.Url,
+		p.Description.String,
+		p.PublishedAt,
+		p.Flagged,
+		ui.Text{Value: dedupe.AlsoIn(p.ClusterFeeds, p.FeedName), Display: alsoIn(p.ClusterFeeds, p.FeedName)},
+	)
+}
+
 // handleAgg will aggregate all posts from the feeds and write them to the database
 // expects duration argument in time ex 1m
 func handlerAgg(s *state, cmd command) error {
 	s.ui.Header("Aggregate Feeds")
-	if len(cmd.args) < 1 || len(cmd.args) > 1 {
-		return fmt.Errorf("The agg command should only have one duration argument, recieved %d arguments.", len(cmd.args))
-	}
-	timeArg := cmd.args[0]
-	duration, err := time.ParseDuration(timeArg)
-	if err != nil {
-		return err
+	duration := cmd.Duration("interval")
+	if duration <= 0 {
+		return fmt.Errorf("the agg interval should be greater than zero, recieved %v", duration)
 	}
 
 	s.ui.Item("We will begin downloading posts from your feeds starting in %v", duration)
@@ -315,22 +923,27 @@ func handlerAgg(s *state, cmd command) error {
 // On successful creation, it sets the user in the configuration and prints the user details.
 // Returns an error if any step fails.
 func handlerRegister(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("a username is required")
-	}
-
-	username := cmd.args[0]
+	username := cmd.String("username")
 	s.ui.Header("Register")
 
-	// search for user first prior to creating new user
-	user, err := s.db.CreateUser(context.Background(), database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      username,
+	// the first user registered is the admin
+	var user database.User
+	err := inTx(s, func(tx *state) error {
+		users, err := tx.db.GetUsers(context.Background())
+		if err != nil {
+			return err
+		}
+		user, err = tx.db.CreateUser(context.Background(), database.CreateUserParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      username,
+			IsAdmin:   len(users) == 0,
+		})
+		return err
 	})
 	if err != nil {
-		if pgErr, ok := err.(*pq.Error); ok && string(pgErr.Code) == "23505" {
+		if isUniqueViolation(err) {
 			s.ui.Error(fmt.Sprintf("User %q already exists in database", username))
 			return fmt.Errorf("error user %q already exists", username)
 		}
@@ -343,16 +956,16 @@ func handlerRegister(s *state, cmd command) error {
 	}
 
 	s.ui.Item("The following user was created successfully id:%s name:%s", user.ID.String(), user.Name)
+	if user.IsAdmin {
+		s.ui.Info(fmt.Sprintf("%s is the first user and an admin, admins can reset the database and make other users admins", user.Name))
+	}
 	return nil
 
 }
 
 // will login the passed in user
 func handlerLogin(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("a username is required")
-	}
-	username := cmd.args[0]
+	username := cmd.String("username")
 
 	s.ui.Header("Login")
 	// find user in db
@@ -0,0 +1,1031 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/config"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/database/memory"
+	"github.com/joshhartwig/gator/internal/ui"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>News</title><link>https://example.com</link><description>news</description>
+<item><title>First post</title><link>https://example.com/1</link><pubDate>Fri, 02 Jan 2026 03:04:05 +0000</pubDate></item>
+</channel></rss>`
+
+// handlerTest runs a command line against a store prepared by setup, the output must contain every
+// string in want and none in notWant, the error must contain wantErr and check inspects the store afterwards
+type handlerTest struct {
+	name    string
+	setup   func(t *testing.T, s *state)
+	args    []string
+	want    []string
+	notWant []string
+	wantErr string
+	check   func(t *testing.T, s *state)
+}
+
+func TestHandlers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testRSS)
+	}))
+	defer server.Close()
+	feedURL := server.URL + "/rss"
+
+	tests := []handlerTest{
+		{
+			name: "quit outside the shell",
+			args: []string{"quit"},
+			want: []string{"quit only ends a 'gator shell' session"},
+		},
+		{
+			name: "help lists commands and groups",
+			args: []string{"help"},
+			want: []string{"Commands:", "feed:", "add", "Examples:"},
+		},
+		{
+			name: "help for a command",
+			args: []string{"help", "user", "register"},
+			want: []string{"user register <username>"},
+		},
+		{
+			name:    "help for an unknown command",
+			args:    []string{"help", "nope"},
+			wantErr: "nope",
+		},
+		{
+			name: "admin follows lists every user's follows",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+			},
+			args: []string{"admin", "follows"},
+			want: []string{"alice", "news"},
+		},
+		{
+			name:  "feed add fetches the feed and follows it",
+			setup: func(t *testing.T, s *state) { login(t, s, addUser(t, s, "alice")) },
+			args:  []string{"feed", "add", "news", feedURL},
+			want:  []string{"feed url: " + feedURL, "Feed handle: 1"},
+			check: func(t *testing.T, s *state) {
+				follows, _ := s.db.GetFeedFollows(context.Background())
+				if len(follows) != 1 || follows[0].FeedName != "news" {
+					t.Errorf("wanted alice to follow news got %+v", follows)
+				}
+			},
+		},
+		{
+			name: "feed add with a url already added",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", feedURL)
+			},
+			args:    []string{"feed", "add", "again", feedURL},
+			wantErr: "is already added, follow it with 'gator follow add " + feedURL + "'",
+		},
+		{
+			name:    "feed add logged out",
+			args:    []string{"feed", "add", "news", feedURL},
+			wantErr: "unable to find user in database",
+		},
+		{
+			name: "feed list shows every feed with a handle",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				addFollowedFeed(t, s, addUser(t, s, "bob"), "blog", "https://example.com/blog")
+			},
+			args: []string{"feed", "list"},
+			want: []string{"news", "https://example.com/news", "blog", "bob  1"},
+		},
+		{
+			name: "feed status shows when feeds were fetched",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				blog := addFollowedFeed(t, s, alice, "blog", "https://example.com/blog")
+				if _, err := s.db.MarkFeedFetched(context.Background(), blog.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			args: []string{"feed", "status"},
+			want: []string{"never", "last fetched", "max_age default"},
+		},
+		{
+			name: "feed rename renames the feed for everyone",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+			},
+			args: []string{"feed", "rename", "https://example.com/news", "headlines"},
+			want: []string{"Renamed news to headlines"},
+			check: func(t *testing.T, s *state) {
+				if feed, _ := s.db.GetFeedByUrl(context.Background(), "https://example.com/news"); feed.Name != "headlines" {
+					t.Errorf("wanted news renamed got %+v", feed)
+				}
+			},
+		},
+		{
+			name: "feed rename of a feed someone else added",
+			setup: func(t *testing.T, s *state) {
+				addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+				login(t, s, addUser(t, s, "alice"))
+			},
+			args:    []string{"feed", "rename", "https://example.com/news", "headlines"},
+			wantErr: "only the user who added news or an admin can rename it",
+		},
+		{
+			name: "feed seturl fetches the new url",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				feed := addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				if _, err := s.db.MarkFeedFetched(context.Background(), feed.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			args: []string{"feed", "seturl", "https://example.com/news", feedURL},
+			want: []string{"news now uses " + feedURL},
+			check: func(t *testing.T, s *state) {
+				feed, err := s.db.GetFeedByUrl(context.Background(), feedURL)
+				if err != nil || feed.LastFetchedAt.Valid {
+					t.Errorf("wanted news moved and fetched again got %+v %v", feed, err)
+				}
+			},
+		},
+		{
+			name: "feed seturl to a url that does not answer",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+			},
+			args:    []string{"feed", "seturl", "https://example.com/news", "http://127.0.0.1:1/rss"},
+			wantErr: "unable to fetch the new url, the feed was not changed",
+		},
+		{
+			name: "feed seturl to a url another feed uses",
+			setup: func(t *testing.T, s *state) {
+				alice := addAdmin(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+				addFollowedFeed(t, s, alice, "blog", feedURL)
+			},
+			args:    []string{"feed", "seturl", "https://example.com/news", feedURL},
+			wantErr: "another feed already uses " + feedURL,
+		},
+		{
+			name: "feed remove deletes the feed with its posts",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addAdmin(t, s, "alice"))
+				feed := addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+				addTestPost(t, s, feed, "First post", 1)
+				runCommand(t, s, "follow", "add", feed.Url)
+			},
+			args: []string{"feed", "remove", "https://example.com/news", "--yes"},
+			want: []string{"follows  2", "posts  1", "Removed news along with its posts"},
+			check: func(t *testing.T, s *state) {
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 0 {
+					t.Errorf("wanted news removed got %+v", feeds)
+				}
+				if n, _ := s.db.CountPosts(context.Background()); n != 0 {
+					t.Errorf("wanted the posts removed got %d", n)
+				}
+			},
+		},
+		{
+			name: "feed remove keeping the posts",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addTestPost(t, s, addFollowedFeed(t, s, alice, "news", "https://example.com/news"), "First post", 1)
+			},
+			args: []string{"feed", "remove", "https://example.com/news", "--keep-posts", "--yes"},
+			want: []string{"follows  1", "posts  0", "the feed and its posts were kept", "'gator follow add https://example.com/news' brings it back"},
+			check: func(t *testing.T, s *state) {
+				if follows, _ := s.db.GetFeedFollows(context.Background()); len(follows) != 0 {
+					t.Errorf("wanted no follows got %+v", follows)
+				}
+				if n, _ := s.db.CountPosts(context.Background()); n != 1 {
+					t.Errorf("wanted the post kept got %d", n)
+				}
+				// the feed is still there to follow again
+				runCommand(t, s, "follow", "add", "https://example.com/news")
+				if follows, _ := s.db.GetFeedFollows(context.Background()); len(follows) != 1 {
+					t.Errorf("wanted news followed again got %+v", follows)
+				}
+			},
+		},
+		{
+			name: "feed remove asks first",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				s.in = strings.NewReader("n\n")
+			},
+			args: []string{"feed", "remove", "https://example.com/news"},
+			want: []string{"Remove feed news? [y/N]", "Remove cancelled, nothing was deleted"},
+			check: func(t *testing.T, s *state) {
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 1 {
+					t.Errorf("wanted news kept got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "feed remove of a feed someone else added",
+			setup: func(t *testing.T, s *state) {
+				addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+				login(t, s, addUser(t, s, "alice"))
+			},
+			args:    []string{"feed", "remove", "https://example.com/news", "--yes"},
+			wantErr: "only the user who added news or an admin can remove it",
+		},
+		{
+			name: "follow remove by url",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+			},
+			args: []string{"follow", "remove", "https://example.com/news"},
+			want: []string{"Successfully unfollowed feed: https://example.com/news", "Nobody follows news anymore, it was deleted with 0 posts"},
+			check: func(t *testing.T, s *state) {
+				if follows, _ := s.db.GetFeedFollows(context.Background()); len(follows) != 0 {
+					t.Errorf("wanted no follows got %+v", follows)
+				}
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 0 {
+					t.Errorf("wanted the unfollowed feed collected got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "follow remove keeps a feed others follow",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				feed := addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+				runCommand(t, s, "follow", "add", feed.Url)
+			},
+			args: []string{"follow", "remove", "https://example.com/news"},
+			want: []string{"Successfully unfollowed feed: https://example.com/news"},
+			check: func(t *testing.T, s *state) {
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 1 {
+					t.Errorf("wanted news kept for bob got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "follow remove with unfollowed_feeds delete_posts keeps the feed",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addTestPost(t, s, addFollowedFeed(t, s, alice, "news", "https://example.com/news"), "First post", 1)
+				s.config.Retention.Unfollowed_Feeds = unfollowedDeletePosts
+			},
+			args: []string{"follow", "remove", "https://example.com/news"},
+			want: []string{"Nobody follows news anymore, its 1 posts were deleted"},
+			check: func(t *testing.T, s *state) {
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 1 {
+					t.Errorf("wanted news kept got %+v", feeds)
+				}
+				if n, _ := s.db.CountPosts(context.Background()); n != 0 {
+					t.Errorf("wanted the posts deleted got %d", n)
+				}
+			},
+		},
+		{
+			name: "admin gc collects feeds left by unfollowed_feeds keep",
+			setup: func(t *testing.T, s *state) {
+				alice := addAdmin(t, s, "alice")
+				login(t, s, alice)
+				addTestPost(t, s, addFollowedFeed(t, s, alice, "news", "https://example.com/news"), "First post", 1)
+				addFollowedFeed(t, s, alice, "blog", "https://example.com/blog")
+				s.config.Retention.Unfollowed_Feeds = unfollowedKeep
+				runCommand(t, s, "follow", "remove", "https://example.com/news")
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 2 {
+					t.Fatalf("wanted news kept got %+v", feeds)
+				}
+			},
+			args: []string{"admin", "gc"},
+			want: []string{"news", "1 feeds and 1 posts deleted"},
+			check: func(t *testing.T, s *state) {
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 1 || feeds[0].Name != "blog" {
+					t.Errorf("wanted only blog kept got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "admin prune dry run lists without deleting",
+			setup: func(t *testing.T, s *state) {
+				alice := addAdmin(t, s, "alice")
+				login(t, s, alice)
+				addTestPost(t, s, addFollowedFeed(t, s, alice, "news", "https://example.com/news"), "First post", 1)
+			},
+			args: []string{"admin", "prune", "--dry-run"},
+			want: []string{"Prune Posts (dry run)"},
+			check: func(t *testing.T, s *state) {
+				if n, _ := s.db.CountPosts(context.Background()); n != 1 {
+					t.Errorf("wanted the post kept got %d", n)
+				}
+			},
+		},
+		{
+			name:    "admin prune is only for admins",
+			setup:   func(t *testing.T, s *state) { login(t, s, addUser(t, s, "alice")) },
+			args:    []string{"admin", "prune"},
+			wantErr: "only admins can prune the posts of every feed",
+		},
+		{
+			name: "feed retention of a feed whose adder was deleted is changed by an admin",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addAdmin(t, s, "alice"))
+				bob := addUser(t, s, "bob")
+				addFollowedFeed(t, s, bob, "news", "https://example.com/news")
+				runCommand(t, s, "follow", "add", "https://example.com/news")
+				if err := s.db.DeleteUser(context.Background(), bob.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			args: []string{"feed", "retention", "https://example.com/news", "max_posts", "10"},
+			check: func(t *testing.T, s *state) {
+				feed, _ := s.db.GetFeedByUrl(context.Background(), "https://example.com/news")
+				if feed.RetentionMaxPosts.Int32 != 10 {
+					t.Errorf("wanted max_posts 10 got %+v", feed.RetentionMaxPosts)
+				}
+			},
+		},
+		{
+			name: "feed retention of a feed someone else added",
+			setup: func(t *testing.T, s *state) {
+				addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+				login(t, s, addUser(t, s, "carol"))
+			},
+			args:    []string{"feed", "retention", "https://example.com/news", "max_posts", "10"},
+			wantErr: "only the user who added news or an admin can change the retention of it",
+		},
+		{
+			name: "category list counts the feeds in each category",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				addFollowedFeed(t, s, alice, "blog", "https://example.com/blog")
+				runCommand(t, s, "category", "add", "tech")
+				runCommand(t, s, "category", "add", "empty")
+				runCommand(t, s, "category", "assign", "https://example.com/news", "tech")
+				runCommand(t, s, "category", "assign", "https://example.com/blog", "tech")
+			},
+			args: []string{"category", "list"},
+			want: []string{"empty  0 feeds", "tech  2 feeds"},
+		},
+		{
+			name:    "admin gc is only for admins",
+			setup:   func(t *testing.T, s *state) { login(t, s, addUser(t, s, "alice")) },
+			args:    []string{"admin", "gc"},
+			wantErr: "only admins can collect the feeds nobody follows",
+		},
+		{
+			name: "admin gc dry run deletes nothing",
+			setup: func(t *testing.T, s *state) {
+				alice := addAdmin(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				if _, err := s.db.DeleteFeedFollowsForUser(context.Background(), alice.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			args: []string{"admin", "gc", "--dry-run"},
+			want: []string{"Unfollowed Feeds (dry run)", "1 feeds and 0 posts would be deleted"},
+			check: func(t *testing.T, s *state) {
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 1 {
+					t.Errorf("wanted news kept got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "follow remove a feed not followed",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addUser(t, s, "alice"))
+				addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+			},
+			args:    []string{"follow", "remove", "https://example.com/news"},
+			wantErr: "not following",
+		},
+		{
+			name: "follow list shows the user's follows",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				addFollowedFeed(t, s, addUser(t, s, "bob"), "blog", "https://example.com/blog")
+			},
+			args:    []string{"follow", "list"},
+			want:    []string{"Show Follows", "news"},
+			notWant: []string{"blog"},
+		},
+		{
+			name: "follow list in a category",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				addFollowedFeed(t, s, alice, "blog", "https://example.com/blog")
+				runCommand(t, s, "category", "add", "tech")
+				runCommand(t, s, "category", "assign", "https://example.com/blog", "tech")
+			},
+			args: []string{"follow", "list", "tech"},
+			want: []string{"Show Follows in tech", "blog"},
+		},
+		{
+			name: "follow list in an unknown category",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addUser(t, s, "alice"))
+			},
+			args:    []string{"follow", "list", "tech"},
+			wantErr: "tech",
+		},
+		{
+			name: "follow set name",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+			},
+			args: []string{"follow", "set", "https://example.com/news", "name", "Daily News"},
+			want: []string{"Feed Daily News: priority 0"},
+		},
+		{
+			name: "follow set priority that is not a number",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+			},
+			args:    []string{"follow", "set", "https://example.com/news", "priority", "high"},
+			wantErr: "priority should be a whole number",
+		},
+		{
+			name: "admin reset deletes every user",
+			setup: func(t *testing.T, s *state) {
+				admin := addAdmin(t, s, "alice")
+				login(t, s, admin)
+				addFollowedFeed(t, s, admin, "news", "https://example.com/news")
+			},
+			args: []string{"admin", "reset", "--yes"},
+			want: []string{"users  1", "feeds  1", "Reset the whole database complete"},
+			check: func(t *testing.T, s *state) {
+				if users, _ := s.db.GetUsers(context.Background()); len(users) != 0 {
+					t.Errorf("wanted no users got %+v", users)
+				}
+			},
+		},
+		{
+			name:    "admin reset is only for admins",
+			setup:   func(t *testing.T, s *state) { login(t, s, addUser(t, s, "alice")) },
+			args:    []string{"admin", "reset", "--yes"},
+			wantErr: "only admins can reset the whole database",
+		},
+		{
+			name:    "admin reset asks for confirmation",
+			setup:   func(t *testing.T, s *state) { login(t, s, addAdmin(t, s, "alice")) },
+			args:    []string{"admin", "reset"},
+			wantErr: "pass --yes",
+			check: func(t *testing.T, s *state) {
+				if users, _ := s.db.GetUsers(context.Background()); len(users) != 1 {
+					t.Errorf("wanted nothing deleted got %+v", users)
+				}
+			},
+		},
+		{
+			name: "admin reset keeps everything when the answer is no",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addAdmin(t, s, "alice"))
+				s.in = strings.NewReader("n\n")
+			},
+			args: []string{"admin", "reset"},
+			want: []string{"Reset the whole database? [y/N]", "Reset cancelled"},
+			check: func(t *testing.T, s *state) {
+				if users, _ := s.db.GetUsers(context.Background()); len(users) != 1 {
+					t.Errorf("wanted nothing deleted got %+v", users)
+				}
+			},
+		},
+		{
+			name: "admin reset posts keeps the feeds",
+			setup: func(t *testing.T, s *state) {
+				admin := addAdmin(t, s, "alice")
+				login(t, s, admin)
+				addTestPost(t, s, addFollowedFeed(t, s, admin, "news", "https://example.com/news"), "First post", 1)
+				s.in = strings.NewReader("yes\n")
+			},
+			args: []string{"admin", "reset", "posts"},
+			want: []string{"posts  1", "Reset every post complete"},
+			check: func(t *testing.T, s *state) {
+				if n, _ := s.db.CountPosts(context.Background()); n != 0 {
+					t.Errorf("wanted no posts got %d", n)
+				}
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 1 {
+					t.Errorf("wanted the feed kept got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "admin reset user clears their settings and keeps the account",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				runCommand(t, s, "category", "add", "tech")
+			},
+			args: []string{"admin", "reset", "user", "alice", "--yes"},
+			want: []string{"follows  1", "categories  1", "Reset user alice complete"},
+			check: func(t *testing.T, s *state) {
+				alice, err := s.db.GetUser(context.Background(), "alice")
+				if err != nil {
+					t.Fatalf("wanted alice kept %v", err)
+				}
+				if follows, _ := s.db.GetFeedFollowsForUser(context.Background(), alice.ID); len(follows) != 0 {
+					t.Errorf("wanted no follows got %+v", follows)
+				}
+			},
+		},
+		{
+			name: "admin reset user collects the feeds nobody else follows",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addTestPost(t, s, addFollowedFeed(t, s, alice, "news", "https://example.com/news"), "First post", 1)
+			},
+			args: []string{"admin", "reset", "user", "alice", "--yes"},
+			want: []string{"feeds nobody else follows  1", "posts of feeds nobody else follows  1", "Nobody follows news anymore, it was deleted with 1 posts"},
+			check: func(t *testing.T, s *state) {
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 0 {
+					t.Errorf("wanted news collected got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "admin reset user of someone else is only for admins",
+			setup: func(t *testing.T, s *state) {
+				addUser(t, s, "alice")
+				login(t, s, addUser(t, s, "bob"))
+			},
+			args:    []string{"admin", "reset", "user", "alice", "--yes"},
+			wantErr: "only admins can reset another user",
+		},
+		{
+			name: "admin reset feed deletes its posts",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				addTestPost(t, s, addFollowedFeed(t, s, alice, "news", "https://example.com/news"), "First post", 1)
+			},
+			args: []string{"admin", "reset", "feed", "https://example.com/news", "--yes"},
+			want: []string{"posts  1", "Reset feed news complete"},
+			check: func(t *testing.T, s *state) {
+				if n, _ := s.db.CountPosts(context.Background()); n != 0 {
+					t.Errorf("wanted no posts got %d", n)
+				}
+			},
+		},
+		{
+			name:    "admin reset user needs a name",
+			setup:   func(t *testing.T, s *state) { login(t, s, addAdmin(t, s, "alice")) },
+			args:    []string{"admin", "reset", "user", "--yes"},
+			wantErr: "reset user needs the user to reset",
+		},
+		{
+			name: "user admin makes another user an admin",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addAdmin(t, s, "alice"))
+				addUser(t, s, "bob")
+			},
+			args: []string{"user", "admin", "bob", "true"},
+			want: []string{"bob is an admin"},
+		},
+		{
+			name:    "user admin keeps the last admin",
+			setup:   func(t *testing.T, s *state) { login(t, s, addAdmin(t, s, "alice")) },
+			args:    []string{"user", "admin", "alice", "false"},
+			wantErr: "alice is the last admin",
+		},
+		{
+			name: "user register makes the first user an admin",
+			args: []string{"user", "register", "alice"},
+			want: []string{"alice is the first user and an admin"},
+			check: func(t *testing.T, s *state) {
+				runCommand(t, s, "user", "register", "bob")
+				if bob, _ := s.db.GetUser(context.Background(), "bob"); bob.IsAdmin {
+					t.Errorf("wanted only the first user to be an admin")
+				}
+			},
+		},
+		{
+			name: "user rename renames the current user",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addUser(t, s, "alice"))
+			},
+			args: []string{"user", "rename", "alice", "carol"},
+			want: []string{"Renamed alice to carol"},
+			check: func(t *testing.T, s *state) {
+				if s.config.Current_User_Name != "carol" {
+					t.Errorf("wanted carol logged in got %q", s.config.Current_User_Name)
+				}
+			},
+		},
+		{
+			name: "user rename to a taken name",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addUser(t, s, "alice"))
+				addUser(t, s, "bob")
+			},
+			args:    []string{"user", "rename", "alice", "bob"},
+			wantErr: "user bob already exists",
+		},
+		{
+			name: "user rename of someone else is only for admins",
+			setup: func(t *testing.T, s *state) {
+				addUser(t, s, "alice")
+				login(t, s, addUser(t, s, "bob"))
+			},
+			args:    []string{"user", "rename", "alice", "carol"},
+			wantErr: "only admins can rename another user",
+		},
+		{
+			name: "user delete keeps the feeds other users follow",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addAdmin(t, s, "alice"))
+				feed := addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+				addTestPost(t, s, feed, "First post", 1)
+				runCommand(t, s, "follow", "add", feed.Url)
+			},
+			args: []string{"user", "delete", "bob", "--yes"},
+			want: []string{"follows  1", "feeds nobody else follows  0", "Deleted user bob"},
+			check: func(t *testing.T, s *state) {
+				feeds, _ := s.db.GetAllFeeds(context.Background())
+				if len(feeds) != 1 || feeds[0].AddedBy.Valid {
+					t.Errorf("wanted news kept without bob got %+v", feeds)
+				}
+				if n, _ := s.db.CountPosts(context.Background()); n != 1 {
+					t.Errorf("wanted the post kept got %d", n)
+				}
+				if s.config.Current_User_Name != "alice" {
+					t.Errorf("wanted alice still logged in got %q", s.config.Current_User_Name)
+				}
+			},
+		},
+		{
+			name: "user delete transfers the feeds and logs out",
+			setup: func(t *testing.T, s *state) {
+				alice := addAdmin(t, s, "alice")
+				bob := addUser(t, s, "bob")
+				addFollowedFeed(t, s, bob, "news", "https://example.com/news")
+				login(t, s, alice)
+				runCommand(t, s, "follow", "add", "https://example.com/news")
+				login(t, s, bob)
+			},
+			args: []string{"user", "delete", "bob", "--transfer-to", "alice", "--yes"},
+			want: []string{"1 feeds bob added are now recorded as added by alice", "Deleted user bob", "You are logged out"},
+			check: func(t *testing.T, s *state) {
+				if s.config.Current_User_Name != "" {
+					t.Errorf("wanted nobody logged in got %q", s.config.Current_User_Name)
+				}
+				feeds, _ := s.db.GetAllFeeds(context.Background())
+				alice, _ := s.db.GetUser(context.Background(), "alice")
+				if len(feeds) != 1 || feeds[0].AddedBy.UUID != alice.ID {
+					t.Errorf("wanted news recorded as added by alice got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "user delete collects the feeds nobody else follows",
+			setup: func(t *testing.T, s *state) {
+				bob := addUser(t, s, "bob")
+				login(t, s, bob)
+				addTestPost(t, s, addFollowedFeed(t, s, bob, "news", "https://example.com/news"), "First post", 1)
+			},
+			args: []string{"user", "delete", "bob", "--yes"},
+			want: []string{"feeds nobody else follows  1", "posts of feeds nobody else follows  1", "Nobody follows news anymore, it was deleted with 1 posts"},
+			check: func(t *testing.T, s *state) {
+				if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 0 {
+					t.Errorf("wanted news collected got %+v", feeds)
+				}
+			},
+		},
+		{
+			name: "user delete keeps the last admin",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addAdmin(t, s, "alice"))
+				addUser(t, s, "bob")
+			},
+			args:    []string{"user", "delete", "alice", "--yes"},
+			wantErr: "alice is the last admin",
+		},
+		{
+			name: "user delete of someone else is only for admins",
+			setup: func(t *testing.T, s *state) {
+				addUser(t, s, "alice")
+				login(t, s, addUser(t, s, "bob"))
+			},
+			args:    []string{"user", "delete", "alice", "--yes"},
+			wantErr: "only admins can delete another user",
+		},
+		{
+			name: "follow add another user's feed",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addUser(t, s, "alice"))
+				addFollowedFeed(t, s, addUser(t, s, "bob"), "news", "https://example.com/news")
+			},
+			args: []string{"follow", "add", "https://example.com/news"},
+			want: []string{"Found current user:alice and feed by url:news", "username:alice", "Feed handle: 1"},
+		},
+		{
+			name: "follow add an unknown feed",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addUser(t, s, "alice"))
+			},
+			args:    []string{"follow", "add", "https://example.com/news"},
+			wantErr: "https://example.com/news",
+		},
+		{
+			name: "user list marks the current user",
+			setup: func(t *testing.T, s *state) {
+				addAdmin(t, s, "alice")
+				login(t, s, addUser(t, s, "bob"))
+			},
+			args: []string{"user", "list"},
+			want: []string{"alice  admin", "bob         (current logged in user)"},
+		},
+		{
+			name: "post browse shows the oldest posts first",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				login(t, s, alice)
+				news := addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+				addTestPost(t, s, news, "First post", 1)
+				addTestPost(t, s, news, "Second post", 2)
+				addTestPost(t, s, news, "Third post", 3)
+			},
+			args:    []string{"post", "browse", "2"},
+			want:    []string{"First post", "Second post", "Next page: gator post browse 2 --after 2"},
+			notWant: []string{"Third post"},
+		},
+		{
+			name: "post browse shows a link shared by two feeds in each of them",
+			setup: func(t *testing.T, s *state) {
+				alice := addUser(t, s, "alice")
+				bob := addUser(t, s, "bob")
+				for _, feed := range []database.Feed{
+					addFollowedFeed(t, s, alice, "news", feedURL),
+					addFollowedFeed(t, s, bob, "wire", server.URL+"/wire"),
+				} {
+					if err := scrapeFeed(s, feed); err != nil {
+						t.Fatal(err)
+					}
+				}
+				login(t, s, bob)
+			},
+			args: []string{"post", "browse"},
+			want: []string{"First post", "wire"},
+			check: func(t *testing.T, s *state) {
+				if n, _ := s.db.CountPosts(context.Background()); n != 2 {
+					t.Fatalf("wanted a copy of the post in each feed got %d posts", n)
+				}
+				// the copies are candidates for each other and share the cluster of the first one
+				var clusters []uuid.UUID
+				for _, url := range []string{feedURL, server.URL + "/wire"} {
+					feed, _ := s.db.GetFeedByUrl(context.Background(), url)
+					candidates, err := s.db.GetDuplicateCandidates(context.Background(), database.GetDuplicateCandidatesParams{
+						FeedID:       feed.ID,
+						CanonicalUrl: "https://example.com/1",
+					})
+					if err != nil || len(candidates) != 1 {
+						t.Fatalf("wanted the copy in the other feed got %+v %v", candidates, err)
+					}
+					clusters = append(clusters, candidates[0].ClusterID)
+				}
+				if clusters[0] != clusters[1] {
+					t.Errorf("wanted the copies in one cluster got %v", clusters)
+				}
+			},
+		},
+		{
+			name: "post browse with a limit below one",
+			setup: func(t *testing.T, s *state) {
+				login(t, s, addUser(t, s, "alice"))
+			},
+			args:    []string{"post", "browse", "0"},
+			wantErr: "the browse limit should be at least 1",
+		},
+		{
+			name:    "agg with an interval of zero",
+			args:    []string{"agg", "0s"},
+			wantErr: "the agg interval should be greater than zero",
+		},
+		{
+			name: "user register logs the new user in",
+			args: []string{"user", "register", "alice"},
+			want: []string{"name:alice"},
+			check: func(t *testing.T, s *state) {
+				if s.config.Current_User_Name != "alice" {
+					t.Errorf("wanted alice logged in got %q", s.config.Current_User_Name)
+				}
+			},
+		},
+		{
+			name:    "user register a name that is taken",
+			setup:   func(t *testing.T, s *state) { addUser(t, s, "alice") },
+			args:    []string{"user", "register", "alice"},
+			want:    []string{`User "alice" already exists in database`},
+			wantErr: `error user "alice" already exists`,
+		},
+		{
+			name:  "user login",
+			setup: func(t *testing.T, s *state) { addUser(t, s, "alice") },
+			args:  []string{"user", "login", "alice"},
+			want:  []string{"Username has been set"},
+			check: func(t *testing.T, s *state) {
+				if s.config.Current_User_Name != "alice" {
+					t.Errorf("wanted alice logged in got %q", s.config.Current_User_Name)
+				}
+			},
+		},
+		{
+			name:    "user login an unknown user",
+			args:    []string{"user", "login", "alice"},
+			wantErr: "unable to find user in database alice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, out := newTestState(t)
+			if tt.setup != nil {
+				tt.setup(t, s)
+				out.Reset()
+			}
+
+			err := runArgs(s, tt.args...)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("wanted no error got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("wanted an error containing %q got %v", tt.wantErr, err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("wanted output containing %q got\n%s", want, out)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("wanted output without %q got\n%s", notWant, out)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, s)
+			}
+		})
+	}
+}
+
+// newTestState returns a state backed by the in memory store along with the buffer it writes to,
+// the config is written to a temporary home
+func newTestState(t *testing.T) (*state, *bytes.Buffer) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	out := &bytes.Buffer{}
+	return &state{
+		db:     memory.New(),
+		config: &config.Config{},
+		ui:     ui.New(out),
+	}, out
+}
+
+func runArgs(s *state, args ...string) error {
+	cmds := newCommands()
+	registerCommands(cmds)
+	return cmds.run(s, command{name: args[0], args: args[1:]})
+}
+
+func runCommand(t *testing.T, s *state, args ...string) {
+	t.Helper()
+	if err := runArgs(s, args...); err != nil {
+		t.Fatalf("unable to run %s %v", strings.Join(args, " "), err)
+	}
+}
+
+func addUser(t *testing.T, s *state, name string) database.User {
+	t.Helper()
+	u, err := s.db.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+	})
+	if err != nil {
+		t.Fatalf("unable to create user %s %v", name, err)
+	}
+	return u
+}
+
+func addAdmin(t *testing.T, s *state, name string) database.User {
+	t.Helper()
+	u, err := s.db.SetUserAdmin(context.Background(), database.SetUserAdminParams{ID: addUser(t, s, name).ID, IsAdmin: true})
+	if err != nil {
+		t.Fatalf("unable to make %s an admin %v", name, err)
+	}
+	return u
+}
+
+func login(t *testing.T, s *state, user database.User) {
+	t.Helper()
+	s.config.Current_User_Name = user.Name
+}
+
+// addFollowedFeed adds a feed the way feed add does, without fetching it
+func addFollowedFeed(t *testing.T, s *state, user database.User, name, url string) database.Feed {
+	t.Helper()
+	feed, err := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+		Url:       url,
+		AddedBy:   uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("unable to create feed %s %v", name, err)
+	}
+	if _, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	}); err != nil {
+		t.Fatalf("unable to follow feed %s %v", name, err)
+	}
+	return feed
+}
+
+// addTestPost adds a post to the feed published and updated n hours into 2026
+func addTestPost(t *testing.T, s *state, feed database.Feed, title string, n int) database.Post {
+	t.Helper()
+	at := time.Date(2026, 1, 1, n, 0, 0, 0, time.UTC)
+	id := uuid.New()
+	url := fmt.Sprintf("%s/%d", feed.Url, n)
+	p, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:           id,
+		CreatedAt:    at,
+		UpdatedAt:    at,
+		Title:        title,
+		Url:          url,
+		Description:  sql.NullString{String: title, Valid: true},
+		PublishedAt:  at,
+		FeedID:       feed.ID,
+		CanonicalUrl: url,
+		ClusterID:    id,
+	})
+	if err != nil {
+		t.Fatalf("unable to create post %s %v", title, err)
+	}
+	return p
+}
+
+func TestReaderUnfollowCollects(t *testing.T) {
+	s, _ := newTestState(t)
+	alice := addUser(t, s, "alice")
+	login(t, s, alice)
+	addTestPost(t, s, addFollowedFeed(t, s, alice, "news", "https://example.com/news"), "First post", 1)
+
+	r := &reader{s: s, user: alice}
+	if err := r.loadFeeds(); err != nil {
+		t.Fatal(err)
+	}
+	r.toggleFollow()
+	if !strings.Contains(r.status, "nobody follows it anymore so it was deleted") {
+		t.Errorf("wanted news collected got status %q", r.status)
+	}
+	if feeds, _ := s.db.GetAllFeeds(context.Background()); len(feeds) != 0 {
+		t.Errorf("wanted no feeds left got %+v", feeds)
+	}
+	if len(r.feeds) != 0 {
+		t.Errorf("wanted the reader to drop news got %+v", r.feeds)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+// postHandles returns the user's handle for each post, posts shown for the first time are given the next free numbers
+func postHandles(s *state, user database.User, ids []uuid.UUID) (map[uuid.UUID]int32, error) {
+	err := s.db.AssignPostHandles(context.Background(), database.AssignPostHandlesParams{
+		PostIds: ids,
+		UserID:  user.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to assign post handles %v", err)
+	}
+
+	rows, err := s.db.GetPostHandles(context.Background(), database.GetPostHandlesParams{
+		UserID:  user.ID,
+		PostIds: ids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get post handles %v", err)
+	}
+	handles := make(map[uuid.UUID]int32)
+	for _, r := range rows {
+		handles[r.PostID] = r.Handle
+	}
+	return handles, nil
+}
+
+// getPostByRef finds a post by the handle shown in 'post browse' or by its full id
+func getPostByRef(s *state, user database.User, ref string) (database.Post, error) {
+	var post database.Post
+	var err error
+	if handle, convErr := strconv.ParseInt(ref, 10, 32); convErr == nil {
+		post, err = s.db.GetPostByHandle(context.Background(), database.GetPostByHandleParams{
+			UserID: user.ID,
+			Handle: int32(handle),
+		})
+	} else if id, convErr := uuid.Parse(ref); convErr == nil {
+		post, err = s.db.GetPost(context.Background(), id)
+	} else {
+		return post, fmt.Errorf("%q is not a post handle or id, see 'post browse'", ref)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return post, fmt.Errorf("post %s not found, see 'post browse'", ref)
+	}
+	if err != nil {
+		return post, fmt.Errorf("unable to get post %v", err)
+	}
+	return post, nil
+}
+
+// feedHandles returns the user's handle for each feed, feeds shown for the first time are given the next free numbers
+func feedHandles(s *state, user database.User, ids []uuid.UUID) (map[uuid.UUID]int32, error) {
+	err := s.db.AssignFeedHandles(context.Background(), database.AssignFeedHandlesParams{
+		FeedIds: ids,
+		UserID:  user.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to assign feed handles %v", err)
+	}
+
+	rows, err := s.db.GetFeedHandles(context.Background(), database.GetFeedHandlesParams{
+		UserID:  user.ID,
+		FeedIds: ids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get feed handles %v", err)
+	}
+	handles := make(map[uuid.UUID]int32)
+	for _, r := range rows {
+		handles[r.FeedID] = r.Handle
+	}
+	return handles, nil
+}
+
+// getFeedByRef finds a feed by the handle shown in 'feed list' and 'follow list', by its id or by its url
+func getFeedByRef(s *state, user database.User, ref string) (database.Feed, error) {
+	var feed database.Feed
+	var err error
+	if handle, convErr := strconv.ParseInt(ref, 10, 32); convErr == nil {
+		feed, err = s.db.GetFeedByHandle(context.Background(), database.GetFeedByHandleParams{
+			UserID: user.ID,
+			Handle: int32(handle),
+		})
+	} else if id, convErr := uuid.Parse(ref); convErr == nil {
+		feed, err = s.db.GetFeed(context.Background(), id)
+	} else if isValidURL(ref) {
+		feed, err = s.db.GetFeedByUrl(context.Background(), ref)
+	} else {
+		return feed, fmt.Errorf("%q is not a feed handle, id or url, see 'feed list'", ref)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return feed, fmt.Errorf("feed %s not found, see 'feed list'", ref)
+	}
+	if err != nil {
+		return feed, fmt.Errorf("unable to get feed %v", err)
+	}
+	return feed, nil
+}
+
+// isHandleOrID reports whether a value looks like a handle or an id rather than a name or url
+func isHandleOrID(v string) bool {
+	if _, err := strconv.ParseInt(v, 10, 32); err == nil {
+		return true
+	}
+	_, err := uuid.Parse(v)
+	return err == nil
+}
@@ -8,19 +8,29 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/dedupe"
+	"github.com/joshhartwig/gator/internal/rules"
 )
 
-const (
-	prefix = "\n🐊 Gator - RSS Aggregator Command Line Interface"
-	Reset  = "\033[0m"
-	Red    = "\033[31m"
-	Green  = "\033[32m]"
-	Blue   = "\033[34m"
-)
+const prefix = "\n🐊 Gator - RSS Aggregator Command Line Interface"
+
+// addedBy reports whether user added feed, feeds added by a deleted user were added by nobody
+func addedBy(feed database.Feed, user database.User) bool {
+	return feed.AddedBy.Valid && feed.AddedBy.UUID == user.ID
+}
+
+// checkAddedBy allows the user who added a feed and admins to change it
+func checkAddedBy(feed database.Feed, user database.User, action string) error {
+	if addedBy(feed, user) || user.IsAdmin {
+		return nil
+	}
+	return fmt.Errorf("only the user who added %s or an admin can %s it", feed.Name, action)
+}
 
 func scrapeFeeds(s *state) error {
 	// fetch the latest feed
@@ -28,7 +38,11 @@ func scrapeFeeds(s *state) error {
 	if err != nil {
 		return errors.ErrUnsupported
 	}
+	return scrapeFeed(s, feed)
+}
 
+// scrapeFeed fetches a single feed and stores its new posts
+func scrapeFeed(s *state, feed database.Feed) error {
 	rss, err := fetchFeed(context.Background(), feed.Url)
 	if err != nil {
 		return fmt.Errorf("unable to fetch feed with the following url:%s error:%s", feed.Url, err)
@@ -40,14 +54,61 @@ func scrapeFeeds(s *state) error {
 		return fmt.Errorf("error marking fetch feed with id:%s error: %s", feed.ID.String(), err)
 	}
 
+	// remove posts that fall outside the retention policy when auto prune is on
+	if s.config.Retention.Auto_Prune {
+		n, err := pruneFeed(s, feed, false)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			s.ui.Item("Pruned %d old posts from %s", n, feed.Name)
+		}
+	}
+
 	if len(rss.Channel.Item) == 0 {
 		s.ui.Item("No new posts for: %s", rss.Channel.Title)
 		return nil
 	}
 
-	// loop through each item in the channel
+	// the posts of a fetch and their rule states are stored together, a failure leaves none of them behind.
+	// The feed stays marked as fetched so a feed that keeps failing does not hold up the others.
+	var added []RSSItem
+	err = inTx(s, func(tx *state) error {
+		added, err = storePosts(tx, feed, rss.Channel.Item)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
 	s.ui.Item("%s", rss.Channel.Title)
-	for _, r := range rss.Channel.Item {
+	for _, r := range added {
+		t, err := time.Parse(time.DateTime, r.PubDate)
+		if err != nil {
+			s.ui.Column("  + %s\t%s\t\n", r.Title, time.DateTime)
+		} else {
+			s.ui.Column("  + %s\t%s\t\n", r.Title, t)
+		}
+	}
+
+	if len(added) > 0 {
+		return notifyFollowers(s, feed.ID, len(added))
+	}
+	return nil
+}
+
+// storePosts stores the items of a feed that are not stored yet and runs each follower's rules over them.
+// It returns the items that were new.
+func storePosts(s *state, feed database.Feed, items []RSSItem) ([]RSSItem, error) {
+	// load the filtering rules of everyone following this feed
+	feedRules, feedNames, err := loadFeedRules(s, feed.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// loop through each item in the channel
+	added := []RSSItem{}
+	for _, r := range items {
 
 		// attempt to parse the time, if not set it to now
 		pubDate, err := time.Parse(time.RFC3339, r.PubDate)
@@ -55,31 +116,111 @@ func scrapeFeeds(s *state) error {
 			pubDate = time.Now()
 		}
 
-		_, err = s.db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       r.Title,
-			Url:         r.Link,
-			Description: sql.NullString{String: r.Description, Valid: true},
-			PublishedAt: pubDate,
-			FeedID:      feed.ID,
-		})
+		author := r.Author
+		if author == "" {
+			author = r.Creator
+		}
+		category := strings.Join(r.Categories, ", ")
 
+		// a post stored by an earlier fetch is skipped before it is compared with other feeds, every fetch
+		// sees the whole feed again and the comparison is the expensive part
+		stored, err := s.db.CountPostsForFeedUrl(context.Background(), database.CountPostsForFeedUrlParams{FeedID: feed.ID, Url: r.Link})
 		if err != nil {
-			return fmt.Errorf("error creating post %v", err)
+			return nil, fmt.Errorf("unable to look up post %s %v", r.Link, err)
+		}
+		if stored > 0 {
+			continue
 		}
 
-		t, err := time.Parse(time.DateTime, r.PubDate)
+		// group the post with the same story from other feeds
+		postID := uuid.New()
+		canonical := dedupe.CanonicalURL(r.Link)
+		hash, hasHash := dedupe.Simhash(r.Description)
+		clusterID, err := findCluster(s, feed.ID, dedupe.Post{
+			CanonicalURL: canonical,
+			Title:        r.Title,
+			Simhash:      hash,
+			HasSimhash:   hasHash,
+		})
 		if err != nil {
-			s.ui.Column("  + %s\t%s\t\n", r.Title, time.DateTime)
-		} else {
-			s.ui.Column("  + %s\t%s\t\n", r.Title, t)
+			return nil, err
 		}
+		if clusterID == uuid.Nil {
+			clusterID = postID
+		}
+
+		post, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
+			ID:           postID,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+			Title:        r.Title,
+			Url:          r.Link,
+			Description:  sql.NullString{String: r.Description, Valid: true},
+			PublishedAt:  pubDate,
+			FeedID:       feed.ID,
+			Author:       sql.NullString{String: author, Valid: author != ""},
+			Category:     sql.NullString{String: category, Valid: category != ""},
+			CanonicalUrl: canonical,
+			Simhash:      sql.NullInt64{Int64: int64(hash), Valid: hasHash},
+			ClusterID:    clusterID,
+		})
+
+		if err != nil {
+			// the post was stored by another fetch since it was looked up, the insert skips it rather than
+			// failing so the transaction is not aborted
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, fmt.Errorf("error creating post %v", err)
+		}
+		added = append(added, r)
+
+		// run each follower's rules over the new post
+		for userID, set := range feedRules {
+			res := rules.Evaluate(set, rules.Post{
+				Title:       r.Title,
+				Description: r.Description,
+				Author:      author,
+				Category:    category,
+				Feed:        feedNames[userID],
+				URL:         r.Link,
+			})
+			if !res.Changed() {
+				continue
+			}
+			if err := savePostState(s, userID, post.ID, res); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return added, nil
+}
+
+// notifyFollowers tells every follower who asked for notifications that a feed has new posts,
+// muted follows are never notified
+func notifyFollowers(s *state, feedID uuid.UUID, count int) error {
+	followers, err := s.db.GetNotifiedFollowersForFeed(context.Background(), feedID)
+	if err != nil {
+		return fmt.Errorf("unable to get followers to notify %v", err)
+	}
+	for _, f := range followers {
+		s.ui.Info(fmt.Sprintf("%s: %d new posts in %s", f.UserName, count, f.FeedName))
 	}
 	return nil
 }
 
+// followFlags describes a follow's muted and notify settings for listings
+func followFlags(muted, notify bool) string {
+	flags := []string{}
+	if muted {
+		flags = append(flags, "muted")
+	}
+	if notify {
+		flags = append(flags, "notify")
+	}
+	return strings.Join(flags, ",")
+}
+
 // fetchFeed retrieves and parses an RSS feed from the specified URL.
 // It sends an HTTP GET request with a custom User-Agent header, reads the response body,
 // and unmarshals the XML data into an RSSFeed struct.
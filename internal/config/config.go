@@ -9,8 +9,46 @@ import (
 )
 
 type Config struct {
-	DB_URL            string `json:"db_url"`
-	Current_User_Name string `json:"current_user_name"`
+	DB_URL            string    `json:"db_url"`
+	Current_User_Name string    `json:"current_user_name"`
+	Retention         Retention `json:"retention"`
+	Theme             Theme     `json:"theme"`
+	Database          Database  `json:"database"`
+}
+
+// Database tunes the connection pool and how long gator waits for the database.
+// Durations are Go durations (ex 30s), empty or zero values keep the defaults.
+type Database struct {
+	Max_Open_Conns     int    `json:"max_open_conns,omitempty"`
+	Max_Idle_Conns     int    `json:"max_idle_conns,omitempty"`
+	Conn_Max_Lifetime  string `json:"conn_max_lifetime,omitempty"`
+	Conn_Max_Idle_Time string `json:"conn_max_idle_time,omitempty"`
+	Connect_Timeout    string `json:"connect_timeout,omitempty"`
+	Startup_Wait       string `json:"startup_wait,omitempty"`
+}
+
+// Retention controls how long posts are kept before prune removes them.
+// Ages are Go durations (ex 720h), empty or zero values disable a limit.
+// Unfollowed_Feeds is what happens to a feed once nobody follows it: delete (the default),
+// delete_posts to keep the feed without its posts or keep.
+type Retention struct {
+	Max_Age            string `json:"max_age,omitempty"`
+	Max_Posts_Per_Feed int    `json:"max_posts_per_feed,omitempty"`
+	Keep_Newest        int    `json:"keep_newest,omitempty"`
+	Min_Age            string `json:"min_age,omitempty"`
+	Auto_Prune         bool   `json:"auto_prune,omitempty"`
+	Unfollowed_Feeds   string `json:"unfollowed_feeds,omitempty"`
+}
+
+// Theme restyles the table output by role, ex "bold red", "bright-cyan on-blue", "208" or "#ff8800".
+// Empty roles keep the default style and "none" turns a role's color off.
+type Theme struct {
+	Header    string `json:"header,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Warning   string `json:"warning,omitempty"`
+	Info      string `json:"info,omitempty"`
+	Feed_Name string `json:"feed_name,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
 }
 
 const configFileName = ".gatorconfig.json"
@@ -58,6 +96,17 @@ func (c *Config) SetUser(user string) error {
 	return nil
 }
 
+// ClearUser logs the current user out, used when that user is deleted
+func (c *Config) ClearUser() error {
+	c.Current_User_Name = ""
+	return write(*c)
+}
+
+// Path returns where the config file is read from and written to
+func Path() (string, error) {
+	return getConfigFilePath()
+}
+
 func getConfigFilePath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
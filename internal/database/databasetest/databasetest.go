@@ -0,0 +1,1000 @@
+// Package databasetest is the conformance suite every storage backend must pass. It checks the
+// behaviour the handlers rely on through database.Store rather than the sql behind it.
+package databasetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+// base is the time the fixtures are created at, posts are published in the hours after it
+var base = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// Run runs the suite, open must return an empty migrated database for each test
+func Run(t *testing.T, open func(t *testing.T) database.Store) {
+	tests := map[string]func(t *testing.T, f *fixture){
+		"users":        testUsers,
+		"feeds":        testFeeds,
+		"fetch order":  testFetchOrder,
+		"follows":      testFollows,
+		"categories":   testCategories,
+		"posts":        testPosts,
+		"browse":       testBrowse,
+		"clusters":     testClusters,
+		"post states":  testPostStates,
+		"prune":        testPrune,
+		"post handles": testPostHandles,
+		"feed handles": testFeedHandles,
+		"rules":        testRules,
+		"restore":      testRestore,
+		"reset":        testReset,
+		"admins":       testAdmins,
+		"users delete": testDeleteUser,
+	}
+	names := []string{}
+	for name := range tests {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			tests[name](t, &fixture{t: t, q: open(t), ctx: context.Background()})
+		})
+	}
+}
+
+type fixture struct {
+	t   *testing.T
+	q   database.Store
+	ctx context.Context
+}
+
+func (f *fixture) user(name string) database.User {
+	f.t.Helper()
+	u, err := f.q.CreateUser(f.ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: name})
+	if err != nil {
+		f.t.Fatalf("unable to create user %s %v", name, err)
+	}
+	return u
+}
+
+func (f *fixture) feed(user database.User, name string) database.Feed {
+	f.t.Helper()
+	feed, err := f.q.CreateFeed(f.ctx, database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: base,
+		UpdatedAt: base,
+		Name:      name,
+		Url:       "https://example.com/" + name + ".xml",
+		AddedBy:   uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if err != nil {
+		f.t.Fatalf("unable to create feed %s %v", name, err)
+	}
+	return feed
+}
+
+func (f *fixture) follow(user database.User, feed database.Feed) database.CreateFeedFollowRow {
+	f.t.Helper()
+	follow, err := f.q.CreateFeedFollow(f.ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: base,
+		UpdatedAt: base,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		f.t.Fatalf("unable to follow %s %v", feed.Name, err)
+	}
+	return follow
+}
+
+// post creates a post published hour hours after base, a zero cluster puts it in a cluster of its own
+func (f *fixture) post(feed database.Feed, title string, hour int, cluster uuid.UUID) database.Post {
+	f.t.Helper()
+	id := uuid.New()
+	if cluster == uuid.Nil {
+		cluster = id
+	}
+	at := base.Add(time.Duration(hour) * time.Hour)
+	p, err := f.q.CreatePost(f.ctx, database.CreatePostParams{
+		ID:           id,
+		CreatedAt:    at,
+		UpdatedAt:    at,
+		Title:        title,
+		Url:          "https://example.com/" + id.String(),
+		Description:  sql.NullString{String: title + " description", Valid: true},
+		PublishedAt:  at,
+		FeedID:       feed.ID,
+		CanonicalUrl: "https://example.com/" + id.String(),
+		ClusterID:    cluster,
+	})
+	if err != nil {
+		f.t.Fatalf("unable to create post %s %v", title, err)
+	}
+	return p
+}
+
+func testUsers(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	f.user("bob")
+
+	got, err := f.q.GetUser(f.ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != alice.ID || !got.CreatedAt.Equal(base) {
+		t.Errorf("wanted %+v got %+v", alice, got)
+	}
+	if _, err := f.q.GetUser(f.ctx, "carol"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("wanted sql.ErrNoRows for a missing user got %v", err)
+	}
+	if _, err := f.q.CreateUser(f.ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "alice"}); err == nil {
+		t.Error("wanted an error creating a user with a taken name")
+	}
+
+	users, err := f.q.GetUsers(f.ctx)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("wanted 2 users got %d %v", len(users), err)
+	}
+
+	f.feed(alice, "news")
+	if err := f.q.DeleteAllUsers(f.ctx); err != nil {
+		t.Fatal(err)
+	}
+	feeds, err := f.q.GetAllFeeds(f.ctx)
+	if err != nil || len(feeds) != 1 || feeds[0].AddedBy.Valid {
+		t.Errorf("wanted the feed kept without the user who added it got %+v %v", feeds, err)
+	}
+}
+
+func testFeeds(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	f.feed(alice, "blog")
+
+	byURL, err := f.q.GetFeedByUrl(f.ctx, news.Url)
+	if err != nil || byURL.ID != news.ID {
+		t.Errorf("wanted feed %s by url got %+v %v", news.Name, byURL, err)
+	}
+	byID, err := f.q.GetFeed(f.ctx, news.ID)
+	if err != nil || byID.Url != news.Url || byID.LastFetchedAt.Valid {
+		t.Errorf("wanted feed %s by id got %+v %v", news.Name, byID, err)
+	}
+	if _, err := f.q.GetFeed(f.ctx, uuid.New()); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("wanted sql.ErrNoRows for a missing feed got %v", err)
+	}
+	if _, err := f.q.CreateFeed(f.ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "copy", Url: news.Url, AddedBy: uuid.NullUUID{UUID: alice.ID, Valid: true}}); err == nil {
+		t.Error("wanted an error adding a feed url twice")
+	}
+
+	all, err := f.q.GetAllFeeds(f.ctx)
+	if err != nil || len(all) != 2 || all[0].Name != "blog" {
+		t.Errorf("wanted the feeds sorted by name got %+v %v", all, err)
+	}
+	f.follow(alice, news)
+	withUsers, err := f.q.GetFeeds(f.ctx)
+	if err != nil || len(withUsers) != 2 || withUsers[0].AddedByName.String != "alice" {
+		t.Errorf("wanted the feeds with the user who added them got %+v %v", withUsers, err)
+	}
+	if withUsers[0].Followers != 0 || withUsers[1].Followers != 1 {
+		t.Errorf("wanted news followed once got %+v", withUsers)
+	}
+	unfollowed, err := f.q.GetUnfollowedFeeds(f.ctx)
+	if err != nil || len(unfollowed) != 1 || unfollowed[0].Name != "blog" {
+		t.Errorf("wanted only blog unfollowed got %+v %v", unfollowed, err)
+	}
+
+	kept, err := f.q.SetFeedRetention(f.ctx, database.SetFeedRetentionParams{
+		ID:                news.ID,
+		RetentionMaxAge:   sql.NullString{String: "720h", Valid: true},
+		RetentionMaxPosts: sql.NullInt32{Int32: 50, Valid: true},
+	})
+	if err != nil || kept.RetentionMaxAge.String != "720h" || kept.RetentionMaxPosts.Int32 != 50 {
+		t.Errorf("wanted the retention saved got %+v %v", kept, err)
+	}
+
+	renamed, err := f.q.RenameFeed(f.ctx, database.RenameFeedParams{ID: news.ID, Name: "headlines"})
+	if err != nil || renamed.Name != "headlines" || renamed.Url != news.Url {
+		t.Errorf("wanted news renamed got %+v %v", renamed, err)
+	}
+	if _, err := f.q.MarkFeedFetched(f.ctx, news.ID); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := f.q.SetFeedUrl(f.ctx, database.SetFeedUrlParams{ID: news.ID, Url: "https://example.com/moved.xml"})
+	if err != nil || moved.Url != "https://example.com/moved.xml" || moved.LastFetchedAt.Valid {
+		t.Errorf("wanted news moved and unfetched got %+v %v", moved, err)
+	}
+	blog := all[0]
+	if _, err := f.q.SetFeedUrl(f.ctx, database.SetFeedUrlParams{ID: news.ID, Url: blog.Url}); err == nil {
+		t.Error("wanted an error moving a feed to a url another feed uses")
+	}
+
+	f.follow(f.user("bob"), news)
+	if n, err := f.q.CountFeedFollowsForFeed(f.ctx, news.ID); err != nil || n != 2 {
+		t.Errorf("wanted both follows of news counted got %d %v", n, err)
+	}
+	if n, err := f.q.DeleteFeedFollowsForFeed(f.ctx, news.ID); err != nil || n != 2 {
+		t.Errorf("wanted both follows of news deleted got %d %v", n, err)
+	}
+	if follows, _ := f.q.GetFeedFollows(f.ctx); len(follows) != 0 {
+		t.Errorf("wanted no follows left got %+v", follows)
+	}
+}
+
+func testFetchOrder(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	first := f.feed(alice, "first")
+	second := f.feed(alice, "second")
+	f.follow(alice, first)
+	f.follow(alice, second)
+	// nobody follows it so it is never fetched
+	f.feed(alice, "unfollowed")
+
+	fetched, err := f.q.MarkFeedFetched(f.ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fetched.LastFetchedAt.Valid || time.Since(fetched.LastFetchedAt.Time) > time.Minute {
+		t.Errorf("wanted last_fetched_at set to now got %+v", fetched.LastFetchedAt)
+	}
+
+	next, err := f.q.GetNextFeedToFetch(f.ctx)
+	if err != nil || next.ID != second.ID {
+		t.Fatalf("wanted the never fetched feed next got %s %v", next.Name, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := f.q.MarkFeedFetched(f.ctx, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	next, err = f.q.GetNextFeedToFetch(f.ctx)
+	if err != nil || next.ID != first.ID {
+		t.Errorf("wanted the least recently fetched feed next got %s %v", next.Name, err)
+	}
+}
+
+func testFollows(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	blog := f.feed(alice, "blog")
+
+	follow := f.follow(alice, news)
+	if follow.FeedName != "news" || follow.UserName != "alice" || follow.Muted || follow.Priority != 0 {
+		t.Errorf("unexpected follow %+v", follow)
+	}
+	if _, err := f.q.CreateFeedFollow(f.ctx, database.CreateFeedFollowParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, UserID: alice.ID, FeedID: news.ID}); err == nil {
+		t.Error("wanted an error following a feed twice")
+	}
+	f.follow(alice, blog)
+
+	updated, err := f.q.UpdateFeedFollowSettings(f.ctx, database.UpdateFeedFollowSettingsParams{
+		ID:          follow.ID,
+		DisplayName: sql.NullString{String: "Daily News", Valid: true},
+		Priority:    5,
+		Notify:      true,
+	})
+	if err != nil || updated.Priority != 5 || !updated.Notify || updated.DisplayName.String != "Daily News" {
+		t.Errorf("wanted the settings saved got %+v %v", updated, err)
+	}
+
+	follows, err := f.q.GetFeedFollowsForUser(f.ctx, alice.ID)
+	if err != nil || len(follows) != 2 {
+		t.Fatalf("wanted 2 follows got %d %v", len(follows), err)
+	}
+	if follows[0].FeedID != news.ID || follows[0].FeedUrl != news.Url {
+		t.Errorf("wanted the higher priority follow first got %+v", follows[0])
+	}
+	all, err := f.q.GetFeedFollows(f.ctx)
+	if err != nil || len(all) != 2 {
+		t.Errorf("wanted 2 follows got %d %v", len(all), err)
+	}
+
+	notified, err := f.q.GetNotifiedFollowersForFeed(f.ctx, news.ID)
+	if err != nil || len(notified) != 1 || notified[0].UserName != "alice" {
+		t.Errorf("wanted alice notified got %+v %v", notified, err)
+	}
+
+	if err := f.q.DeleteFeedFollowForUser(f.ctx, database.DeleteFeedFollowForUserParams{UserID: alice.ID, FeedID: news.ID}); err != nil {
+		t.Fatal(err)
+	}
+	follows, err = f.q.GetFeedFollowsForUser(f.ctx, alice.ID)
+	if err != nil || len(follows) != 1 || follows[0].FeedID != blog.ID {
+		t.Errorf("wanted only the blog followed got %+v %v", follows, err)
+	}
+}
+
+func testCategories(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	blog := f.feed(alice, "blog")
+	newsFollow := f.follow(alice, news)
+	f.follow(alice, blog)
+	f.post(news, "headline", 1, uuid.Nil)
+	f.post(blog, "musings", 2, uuid.Nil)
+
+	tech, err := f.q.CreateCategory(f.ctx, database.CreateCategoryParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "tech", UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.q.CreateCategory(f.ctx, database.CreateCategoryParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "tech", UserID: alice.ID}); err == nil {
+		t.Error("wanted an error creating a category twice")
+	}
+	if err := f.q.AddFeedFollowToCategory(f.ctx, database.AddFeedFollowToCategoryParams{FeedFollowID: newsFollow.ID, CategoryID: tech.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	inCategory, err := f.q.GetFeedFollowsForUserInCategory(f.ctx, database.GetFeedFollowsForUserInCategoryParams{UserID: alice.ID, CategoryID: tech.ID})
+	if err != nil || len(inCategory) != 1 || inCategory[0].FeedID != news.ID {
+		t.Errorf("wanted news in tech got %+v %v", inCategory, err)
+	}
+	posts, err := f.q.GetPostsForUserInCategory(f.ctx, database.GetPostsForUserInCategoryParams{UserID: alice.ID, CategoryID: tech.ID, Limit: 10})
+	if err != nil || len(posts) != 1 || posts[0].Title != "headline" {
+		t.Errorf("wanted the news post in tech got %+v %v", posts, err)
+	}
+	empty, err := f.q.CreateCategory(f.ctx, database.CreateCategoryParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "empty", UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := f.q.GetCategoryFeedCountsForUser(f.ctx, alice.ID)
+	if err != nil || len(counts) != 2 || counts[0].Name != "empty" || counts[0].Feeds != 0 || counts[1].Feeds != 1 {
+		t.Errorf("wanted empty with no feeds and tech with one got %+v %v", counts, err)
+	}
+	if err := f.q.DeleteCategory(f.ctx, empty.ID); err != nil {
+		t.Fatal(err)
+	}
+	assigned, err := f.q.GetFeedFollowCategoriesForUser(f.ctx, alice.ID)
+	if err != nil || len(assigned) != 1 || assigned[0].CategoryName != "tech" {
+		t.Errorf("wanted news assigned to tech got %+v %v", assigned, err)
+	}
+
+	renamed, err := f.q.RenameCategory(f.ctx, database.RenameCategoryParams{ID: tech.ID, Name: "technology"})
+	if err != nil || renamed.Name != "technology" {
+		t.Errorf("wanted the category renamed got %+v %v", renamed, err)
+	}
+	if _, err := f.q.GetCategoryByName(f.ctx, database.GetCategoryByNameParams{UserID: alice.ID, Name: "technology"}); err != nil {
+		t.Errorf("wanted the category by its new name %v", err)
+	}
+
+	if err := f.q.RemoveFeedFollowFromCategory(f.ctx, database.RemoveFeedFollowFromCategoryParams{FeedFollowID: newsFollow.ID, CategoryID: tech.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.q.DeleteCategory(f.ctx, tech.ID); err != nil {
+		t.Fatal(err)
+	}
+	categories, err := f.q.GetCategoriesForUser(f.ctx, alice.ID)
+	if err != nil || len(categories) != 0 {
+		t.Errorf("wanted no categories got %+v %v", categories, err)
+	}
+}
+
+func testPosts(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	first := f.post(news, "first", 1, uuid.Nil)
+
+	got, err := f.q.GetPost(f.ctx, first.ID)
+	if err != nil || got.Title != "first" || !got.PublishedAt.Equal(first.PublishedAt) {
+		t.Errorf("wanted %+v got %+v %v", first, got, err)
+	}
+
+	// a post already stored by an earlier fetch is skipped without failing, it may be inside a transaction
+	params := database.CreatePostParams{
+		ID:           uuid.New(),
+		CreatedAt:    base,
+		UpdatedAt:    base,
+		Title:        "again",
+		Url:          first.Url,
+		PublishedAt:  base,
+		FeedID:       news.ID,
+		CanonicalUrl: first.CanonicalUrl,
+		ClusterID:    first.ClusterID,
+	}
+	if _, err := f.q.CreatePost(f.ctx, params); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("wanted sql.ErrNoRows for a duplicate url got %v", err)
+	}
+	if got, err := f.q.GetPost(f.ctx, first.ID); err != nil || got.Title != "first" {
+		t.Errorf("wanted the first post kept got %+v %v", got, err)
+	}
+	if n, err := f.q.CountPostsForFeedUrl(f.ctx, database.CountPostsForFeedUrlParams{FeedID: news.ID, Url: first.Url}); err != nil || n != 1 {
+		t.Errorf("wanted the first post counted for its url got %d %v", n, err)
+	}
+
+	// the same link in another feed is a copy of its own
+	params.ID = uuid.New()
+	params.FeedID = f.feed(alice, "wire").ID
+	if _, err := f.q.CreatePost(f.ctx, params); err != nil {
+		t.Errorf("wanted the url stored again for another feed got %v", err)
+	}
+}
+
+func testBrowse(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	bob := f.user("bob")
+	news := f.feed(alice, "news")
+	other := f.feed(bob, "other")
+	f.follow(alice, news)
+	f.follow(bob, other)
+
+	titles := []string{"one", "two", "three", "four", "five"}
+	for i, title := range titles {
+		f.post(news, title, i, uuid.Nil)
+	}
+	f.post(other, "not followed", 9, uuid.Nil)
+
+	// pages come back oldest first and continue after the last post of the previous page
+	got := []string{}
+	after := uuid.NullUUID{}
+	for range 3 {
+		page, err := f.q.GetPostsForUser(f.ctx, database.GetPostsForUserParams{UserID: alice.ID, Limit: 2, After: after})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range page {
+			got = append(got, p.Title)
+			if !p.PublishedAt.After(base.Add(-time.Second)) || p.FeedName != "news" {
+				t.Errorf("unexpected post %+v", p)
+			}
+		}
+		if len(page) > 0 {
+			after = uuid.NullUUID{UUID: page[len(page)-1].ID, Valid: true}
+		}
+	}
+	if !slices.Equal(got, titles) {
+		t.Errorf("wanted %v got %v", titles, got)
+	}
+
+	latest, err := f.q.GetLatestPostForUser(f.ctx, alice.ID)
+	if err != nil || latest.Title != "five" {
+		t.Errorf("wanted the latest post five got %s %v", latest.Title, err)
+	}
+	forFeed, err := f.q.GetPostsForFeed(f.ctx, database.GetPostsForFeedParams{UserID: alice.ID, FeedID: news.ID, Limit: 2})
+	if err != nil || len(forFeed) != 2 || forFeed[0].Title != "five" {
+		t.Errorf("wanted the newest two posts of the feed got %+v %v", forFeed, err)
+	}
+	all, err := f.q.GetAllPostsForUser(f.ctx, alice.ID)
+	if err != nil || len(all) != len(titles) {
+		t.Errorf("wanted %d posts got %d %v", len(titles), len(all), err)
+	}
+	post, err := f.q.GetPost(f.ctx, latest.ID)
+	if err != nil || post.Url != latest.Url || post.ClusterID != latest.ID {
+		t.Errorf("wanted the post by id got %+v %v", post, err)
+	}
+
+	candidates, err := f.q.GetDuplicateCandidates(f.ctx, database.GetDuplicateCandidatesParams{
+		FeedID:       other.ID,
+		CanonicalUrl: "https://example.com/none",
+		CreatedAt:    base.Add(3*time.Hour + time.Minute),
+	})
+	if err != nil || len(candidates) != 1 || candidates[0].Title != "five" {
+		t.Errorf("wanted the posts of other feeds created after the cutoff got %+v %v", candidates, err)
+	}
+}
+
+func testClusters(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	wire := f.feed(alice, "wire")
+	f.follow(alice, news)
+	wireFollow := f.follow(alice, wire)
+	// names are quoted when the cluster's feeds are listed, make sure quotes survive
+	_, err := f.q.UpdateFeedFollowSettings(f.ctx, database.UpdateFeedFollowSettingsParams{
+		ID:          wireFollow.ID,
+		DisplayName: sql.NullString{String: `The "Wire", \ daily`, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := f.post(news, "story", 1, uuid.Nil)
+	f.post(wire, "story again", 2, first.ID)
+
+	posts, err := f.q.GetPostsForUser(f.ctx, database.GetPostsForUserParams{UserID: alice.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != first.ID {
+		t.Fatalf("wanted the cluster shown once by its first post got %+v", posts)
+	}
+	feeds := slices.Sorted(slices.Values(posts[0].ClusterFeeds))
+	if want := []string{`The "Wire", \ daily`, "news"}; !slices.Equal(feeds, want) {
+		t.Errorf("wanted cluster feeds %q got %q", want, feeds)
+	}
+}
+
+func testPostStates(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	f.follow(alice, news)
+	hidden := f.post(news, "hidden", 1, uuid.Nil)
+	flagged := f.post(news, "flagged", 2, uuid.Nil)
+
+	states := []database.UpsertPostStateParams{
+		{UserID: alice.ID, PostID: hidden.ID, UpdatedAt: base, Hidden: true},
+		{UserID: alice.ID, PostID: flagged.ID, UpdatedAt: base, Flagged: true, Title: sql.NullString{String: "renamed", Valid: true}},
+	}
+	for _, s := range states {
+		if err := f.q.UpsertPostState(f.ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	posts, err := f.q.GetPostsForUser(f.ctx, database.GetPostsForUserParams{UserID: alice.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].Title != "renamed" || !posts[0].Flagged {
+		t.Fatalf("wanted only the flagged post under its new title got %+v", posts)
+	}
+
+	if err := f.q.ResetPostStatesForUser(f.ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	posts, err = f.q.GetPostsForUser(f.ctx, database.GetPostsForUserParams{UserID: alice.ID, Limit: 10})
+	if err != nil || len(posts) != 2 || posts[1].Title != "flagged" || posts[1].Flagged {
+		t.Errorf("wanted both posts back as they were got %+v %v", posts, err)
+	}
+}
+
+func testPrune(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	for i := range 5 {
+		f.post(news, []string{"a", "b", "c", "d", "e"}[i], i, uuid.Nil)
+	}
+
+	// keep the newest 3, the two oldest go
+	params := database.GetPrunablePostsParams{
+		FeedID:       news.ID,
+		KeepNewest:   1,
+		MinAgeCutoff: base.Add(100 * time.Hour),
+		MaxAgeCutoff: base.Add(-time.Hour),
+		MaxPosts:     3,
+	}
+	prunable, err := f.q.GetPrunablePosts(f.ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, p := range prunable {
+		got = append(got, p.Title)
+	}
+	if want := []string{"b", "a"}; !slices.Equal(got, want) {
+		t.Errorf("wanted %v prunable got %v", want, got)
+	}
+
+	// posts newer than the minimum age are never pruned
+	young := params
+	young.MinAgeCutoff = base.Add(time.Hour)
+	prunable, err = f.q.GetPrunablePosts(f.ctx, young)
+	if err != nil || len(prunable) != 1 || prunable[0].Title != "a" {
+		t.Errorf("wanted only a old enough to prune got %+v %v", prunable, err)
+	}
+
+	deleted, err := f.q.DeletePrunablePosts(f.ctx, database.DeletePrunablePostsParams(params))
+	if err != nil || deleted != 2 {
+		t.Fatalf("wanted 2 posts deleted got %d %v", deleted, err)
+	}
+	prunable, err = f.q.GetPrunablePosts(f.ctx, params)
+	if err != nil || len(prunable) != 0 {
+		t.Errorf("wanted nothing left to prune got %+v %v", prunable, err)
+	}
+}
+
+func testPostHandles(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	bob := f.user("bob")
+	news := f.feed(alice, "news")
+	older := f.post(news, "older", 1, uuid.Nil)
+	newer := f.post(news, "newer", 2, uuid.Nil)
+
+	handles := func(user database.User, posts ...database.Post) []int32 {
+		t.Helper()
+		ids := []uuid.UUID{}
+		for _, p := range posts {
+			ids = append(ids, p.ID)
+		}
+		if err := f.q.AssignPostHandles(f.ctx, database.AssignPostHandlesParams{PostIds: ids, UserID: user.ID}); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := f.q.GetPostHandles(f.ctx, database.GetPostHandlesParams{UserID: user.ID, PostIds: ids})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byID := make(map[uuid.UUID]int32)
+		for _, r := range rows {
+			byID[r.PostID] = r.Handle
+		}
+		got := []int32{}
+		for _, id := range ids {
+			got = append(got, byID[id])
+		}
+		return got
+	}
+
+	if got := handles(alice, newer, older); !slices.Equal(got, []int32{1, 2}) {
+		t.Errorf("wanted handles in the order given got %v", got)
+	}
+	if got := handles(alice, older, newer); !slices.Equal(got, []int32{2, 1}) {
+		t.Errorf("wanted the handles kept got %v", got)
+	}
+	if got := handles(bob, older); !slices.Equal(got, []int32{1}) {
+		t.Errorf("wanted handles counted per user got %v", got)
+	}
+
+	post, err := f.q.GetPostByHandle(f.ctx, database.GetPostByHandleParams{UserID: alice.ID, Handle: 2})
+	if err != nil || post.ID != older.ID {
+		t.Errorf("wanted handle 2 to be the older post got %+v %v", post, err)
+	}
+
+	// the older post holds the highest handle, once it is gone its handle is not given out again
+	_, err = f.q.DeletePrunablePosts(f.ctx, database.DeletePrunablePostsParams{
+		FeedID:       news.ID,
+		KeepNewest:   1,
+		MinAgeCutoff: base.Add(100 * time.Hour),
+		MaxAgeCutoff: base.Add(100 * time.Hour),
+		MaxPosts:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.q.GetPostByHandle(f.ctx, database.GetPostByHandleParams{UserID: alice.ID, Handle: 2}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("wanted the handle gone with its post got %v", err)
+	}
+	latest := f.post(news, "latest", 3, uuid.Nil)
+	if got := handles(alice, latest); !slices.Equal(got, []int32{3}) {
+		t.Errorf("wanted the next unused handle got %v", got)
+	}
+}
+
+func testFeedHandles(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	blog := f.feed(alice, "blog")
+
+	ids := []uuid.UUID{blog.ID, news.ID}
+	if err := f.q.AssignFeedHandles(f.ctx, database.AssignFeedHandlesParams{FeedIds: ids, UserID: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.q.AssignFeedHandles(f.ctx, database.AssignFeedHandlesParams{FeedIds: ids, UserID: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.q.GetFeedHandles(f.ctx, database.GetFeedHandlesParams{UserID: alice.ID, FeedIds: ids})
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("wanted 2 feed handles got %+v %v", rows, err)
+	}
+	for _, r := range rows {
+		if want := map[uuid.UUID]int32{blog.ID: 1, news.ID: 2}[r.FeedID]; r.Handle != want {
+			t.Errorf("wanted handle %d for %s got %d", want, r.FeedID, r.Handle)
+		}
+	}
+	feed, err := f.q.GetFeedByHandle(f.ctx, database.GetFeedByHandleParams{UserID: alice.ID, Handle: 2})
+	if err != nil || feed.ID != news.ID {
+		t.Errorf("wanted handle 2 to be news got %+v %v", feed, err)
+	}
+}
+
+func testRules(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	f.follow(alice, news)
+
+	rule, err := f.q.CreateRule(f.ctx, database.CreateRuleParams{
+		ID:            uuid.New(),
+		CreatedAt:     base,
+		UpdatedAt:     base,
+		UserID:        alice.ID,
+		Field:         "title",
+		MatchType:     "contains",
+		Pattern:       "sponsored",
+		CaseSensitive: true,
+		Action:        "hide",
+	})
+	if err != nil || !rule.CaseSensitive || rule.Replacement.Valid {
+		t.Fatalf("unexpected rule %+v %v", rule, err)
+	}
+
+	forFeed, err := f.q.GetRulesForFeed(f.ctx, news.ID)
+	if err != nil || len(forFeed) != 1 || forFeed[0].ID != rule.ID || forFeed[0].FeedName != "news" {
+		t.Errorf("wanted the rule for the news feed got %+v %v", forFeed, err)
+	}
+
+	if err := f.q.DeleteRule(f.ctx, rule.ID); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := f.q.GetRulesForUser(f.ctx, alice.ID)
+	if err != nil || len(rules) != 0 {
+		t.Errorf("wanted no rules got %+v %v", rules, err)
+	}
+}
+
+func testRestore(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	fetched := base.Add(time.Hour)
+	feed, err := f.q.RestoreFeed(f.ctx, database.RestoreFeedParams{
+		ID:                uuid.New(),
+		CreatedAt:         base,
+		UpdatedAt:         base,
+		Name:              "news",
+		Url:               "https://example.com/news.xml",
+		AddedBy:           uuid.NullUUID{UUID: alice.ID, Valid: true},
+		LastFetchedAt:     sql.NullTime{Time: fetched, Valid: true},
+		RetentionMaxAge:   sql.NullString{String: "720h", Valid: true},
+		RetentionMaxPosts: sql.NullInt32{Int32: 50, Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feed.LastFetchedAt.Time.Equal(fetched) || feed.RetentionMaxPosts.Int32 != 50 {
+		t.Errorf("wanted every column restored got %+v", feed)
+	}
+
+	follow, err := f.q.RestoreFeedFollow(f.ctx, database.RestoreFeedFollowParams{
+		ID:          uuid.New(),
+		CreatedAt:   base,
+		UpdatedAt:   base,
+		UserID:      alice.ID,
+		FeedID:      feed.ID,
+		DisplayName: sql.NullString{String: "News", Valid: true},
+		Priority:    5,
+		Notify:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.q.RestoreFeedFollow(f.ctx, database.RestoreFeedFollowParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, UserID: alice.ID, FeedID: feed.ID}); err == nil {
+		t.Error("wanted an error restoring a second follow of the same feed")
+	}
+	follows, err := f.q.GetAllFeedFollows(f.ctx)
+	if err != nil || len(follows) != 1 || follows[0].Priority != 5 || follows[0].DisplayName.String != "News" || !follows[0].Notify {
+		t.Errorf("wanted the follow with its settings got %+v %v", follows, err)
+	}
+
+	tech, err := f.q.CreateCategory(f.ctx, database.CreateCategoryParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "tech", UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.q.AddFeedFollowToCategory(f.ctx, database.AddFeedFollowToCategoryParams{FeedFollowID: follow.ID, CategoryID: tech.ID}); err != nil {
+		t.Fatal(err)
+	}
+	categories, err := f.q.GetAllCategories(f.ctx)
+	if err != nil || len(categories) != 1 || categories[0].Name != "tech" {
+		t.Errorf("wanted the tech category got %+v %v", categories, err)
+	}
+	assigned, err := f.q.GetAllFeedFollowCategories(f.ctx)
+	if err != nil || len(assigned) != 1 || assigned[0].FeedFollowID != follow.ID {
+		t.Errorf("wanted the follow in tech got %+v %v", assigned, err)
+	}
+
+	second := f.post(feed, "second", 2, uuid.Nil)
+	first := f.post(feed, "first", 1, uuid.Nil)
+	posts, err := f.q.GetAllPosts(f.ctx)
+	if err != nil || len(posts) != 2 || posts[0].ID != first.ID || posts[1].ID != second.ID {
+		t.Errorf("wanted the posts oldest first got %+v %v", posts, err)
+	}
+
+	if err := f.q.UpsertPostState(f.ctx, database.UpsertPostStateParams{UserID: alice.ID, PostID: first.ID, UpdatedAt: base, Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+	restored := []database.RestorePostStateParams{
+		{UserID: alice.ID, PostID: first.ID, UpdatedAt: base, Flagged: true},
+		{UserID: alice.ID, PostID: second.ID, UpdatedAt: base, Deleted: true},
+	}
+	for _, st := range restored {
+		if err := f.q.RestorePostState(f.ctx, st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	states, err := f.q.GetAllPostStates(f.ctx)
+	if err != nil || len(states) != 2 {
+		t.Fatalf("wanted two post states got %+v %v", states, err)
+	}
+	for _, st := range states {
+		if st.PostID == first.ID && (!st.Hidden || st.Flagged) {
+			t.Errorf("wanted the existing state of the first post kept got %+v", st)
+		}
+	}
+
+	for i, pattern := range []string{"b", "a"} {
+		_, err := f.q.CreateRule(f.ctx, database.CreateRuleParams{
+			ID:        uuid.New(),
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+			UpdatedAt: base,
+			UserID:    alice.ID,
+			Field:     "title",
+			MatchType: "contains",
+			Pattern:   pattern,
+			Action:    "hide",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules, err := f.q.GetAllRules(f.ctx)
+	if err != nil || len(rules) != 2 || rules[0].Pattern != "b" {
+		t.Errorf("wanted the rules in the order they were created got %+v %v", rules, err)
+	}
+}
+
+func testReset(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	news := f.feed(alice, "news")
+	blog := f.feed(alice, "blog")
+	follow := f.follow(alice, news)
+	if _, err := f.q.MarkFeedFetched(f.ctx, news.ID); err != nil {
+		t.Fatal(err)
+	}
+	first := f.post(news, "first", 1, uuid.Nil)
+	f.post(news, "second", 2, uuid.Nil)
+	f.post(blog, "other", 3, uuid.Nil)
+	if err := f.q.UpsertPostState(f.ctx, database.UpsertPostStateParams{UserID: alice.ID, PostID: first.ID, UpdatedAt: base, Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+	tech, err := f.q.CreateCategory(f.ctx, database.CreateCategoryParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "tech", UserID: alice.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.q.AddFeedFollowToCategory(f.ctx, database.AddFeedFollowToCategoryParams{FeedFollowID: follow.ID, CategoryID: tech.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := f.q.CountPosts(f.ctx); err != nil || n != 3 {
+		t.Errorf("wanted 3 posts got %d %v", n, err)
+	}
+	if n, err := f.q.CountPostsForFeed(f.ctx, news.ID); err != nil || n != 2 {
+		t.Errorf("wanted 2 posts in news got %d %v", n, err)
+	}
+	if n, err := f.q.CountPostStatesForUser(f.ctx, alice.ID); err != nil || n != 1 {
+		t.Errorf("wanted 1 post state got %d %v", n, err)
+	}
+
+	if n, err := f.q.DeletePostsForFeed(f.ctx, news.ID); err != nil || n != 2 {
+		t.Errorf("wanted 2 posts deleted got %d %v", n, err)
+	}
+	if err := f.q.MarkFeedUnfetched(f.ctx, news.ID); err != nil {
+		t.Fatal(err)
+	}
+	if feed, err := f.q.GetFeed(f.ctx, news.ID); err != nil || feed.LastFetchedAt.Valid {
+		t.Errorf("wanted news unfetched got %+v %v", feed, err)
+	}
+	if n, _ := f.q.CountPostStatesForUser(f.ctx, alice.ID); n != 0 {
+		t.Errorf("wanted the post state deleted with its post got %d", n)
+	}
+
+	if n, err := f.q.DeleteCategoriesForUser(f.ctx, alice.ID); err != nil || n != 1 {
+		t.Errorf("wanted 1 category deleted got %d %v", n, err)
+	}
+	if assigned, _ := f.q.GetAllFeedFollowCategories(f.ctx); len(assigned) != 0 {
+		t.Errorf("wanted the category assignment deleted got %+v", assigned)
+	}
+	if n, err := f.q.DeleteFeedFollowsForUser(f.ctx, alice.ID); err != nil || n != 1 {
+		t.Errorf("wanted 1 follow deleted got %d %v", n, err)
+	}
+	if n, err := f.q.DeleteRulesForUser(f.ctx, alice.ID); err != nil || n != 0 {
+		t.Errorf("wanted no rules deleted got %d %v", n, err)
+	}
+
+	if n, err := f.q.DeleteAllPosts(f.ctx); err != nil || n != 1 {
+		t.Errorf("wanted the last post deleted got %d %v", n, err)
+	}
+	if err := f.q.MarkAllFeedsUnfetched(f.ctx); err != nil {
+		t.Fatal(err)
+	}
+	if feeds, err := f.q.GetAllFeeds(f.ctx); err != nil || len(feeds) != 2 {
+		t.Errorf("wanted both feeds kept got %+v %v", feeds, err)
+	}
+}
+
+func testAdmins(t *testing.T, f *fixture) {
+	alice, err := f.q.CreateUser(f.ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "alice", IsAdmin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := f.user("bob")
+	if got, _ := f.q.GetUser(f.ctx, "alice"); !got.IsAdmin || bob.IsAdmin {
+		t.Errorf("wanted only alice to be an admin got %+v %+v", got, bob)
+	}
+
+	bob, err = f.q.SetUserAdmin(f.ctx, database.SetUserAdminParams{ID: bob.ID, IsAdmin: true})
+	if err != nil || !bob.IsAdmin {
+		t.Errorf("wanted bob made an admin got %+v %v", bob, err)
+	}
+	if _, err := f.q.SetUserAdmin(f.ctx, database.SetUserAdminParams{ID: alice.ID, IsAdmin: false}); err != nil {
+		t.Fatal(err)
+	}
+	users, err := f.q.GetUsers(f.ctx)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("wanted two users got %+v %v", users, err)
+	}
+	for _, u := range users {
+		if u.IsAdmin != (u.Name == "bob") {
+			t.Errorf("wanted only bob to be an admin got %+v", u)
+		}
+	}
+}
+
+func testDeleteUser(t *testing.T, f *fixture) {
+	alice := f.user("alice")
+	bob := f.user("bob")
+	news := f.feed(alice, "news")
+	blog := f.feed(alice, "blog")
+	f.follow(alice, news)
+	f.follow(bob, blog)
+	post := f.post(news, "first", 1, uuid.Nil)
+	if err := f.q.UpsertPostState(f.ctx, database.UpsertPostStateParams{UserID: alice.ID, PostID: post.ID, UpdatedAt: base, Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.q.CreateCategory(f.ctx, database.CreateCategoryParams{ID: uuid.New(), CreatedAt: base, UpdatedAt: base, Name: "tech", UserID: alice.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.q.RenameUser(f.ctx, database.RenameUserParams{ID: alice.ID, Name: "bob"}); err == nil {
+		t.Errorf("wanted renaming alice to bob to fail")
+	}
+	renamed, err := f.q.RenameUser(f.ctx, database.RenameUserParams{ID: alice.ID, Name: "carol"})
+	if err != nil || renamed.Name != "carol" || renamed.ID != alice.ID {
+		t.Fatalf("wanted alice renamed to carol got %+v %v", renamed, err)
+	}
+	if _, err := f.q.GetUser(f.ctx, "alice"); err == nil {
+		t.Errorf("wanted alice gone after the rename")
+	}
+
+	n, err := f.q.TransferFeeds(f.ctx, database.TransferFeedsParams{
+		ToUserID:   uuid.NullUUID{UUID: bob.ID, Valid: true},
+		FromUserID: uuid.NullUUID{UUID: alice.ID, Valid: true},
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("wanted 2 feeds transferred got %d %v", n, err)
+	}
+	if err := f.q.DeleteUser(f.ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	feeds, err := f.q.GetAllFeeds(f.ctx)
+	if err != nil || len(feeds) != 2 {
+		t.Fatalf("wanted both feeds kept got %+v %v", feeds, err)
+	}
+	for _, feed := range feeds {
+		if feed.AddedBy.UUID != bob.ID {
+			t.Errorf("wanted %s added by bob got %v", feed.Name, feed.AddedBy)
+		}
+	}
+	if n, _ := f.q.CountPosts(f.ctx); n != 1 {
+		t.Errorf("wanted the post kept with its feed got %d", n)
+	}
+	if n, _ := f.q.CountPostStatesForUser(f.ctx, alice.ID); n != 0 {
+		t.Errorf("wanted the post states deleted with alice got %d", n)
+	}
+	if categories, _ := f.q.GetAllCategories(f.ctx); len(categories) != 0 {
+		t.Errorf("wanted the categories deleted with alice got %+v", categories)
+	}
+	if follows, _ := f.q.GetFeedFollows(f.ctx); len(follows) != 1 || follows[0].UserID != bob.ID {
+		t.Errorf("wanted only bob's follow kept got %+v", follows)
+	}
+
+	// the feeds outlive the user who added them, they are only deleted on their own
+	if err := f.q.DeleteUser(f.ctx, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	feeds, _ = f.q.GetAllFeeds(f.ctx)
+	if len(feeds) != 2 || feeds[0].AddedBy.Valid || feeds[1].AddedBy.Valid {
+		t.Errorf("wanted the feeds kept without a user got %+v", feeds)
+	}
+	if err := f.q.DeleteFeed(f.ctx, news.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := f.q.CountPosts(f.ctx); n != 0 {
+		t.Errorf("wanted the posts deleted with their feed got %d", n)
+	}
+	if err := f.q.DeleteAllFeeds(f.ctx); err != nil {
+		t.Fatal(err)
+	}
+	if feeds, _ := f.q.GetAllFeeds(f.ctx); len(feeds) != 0 {
+		t.Errorf("wanted every feed deleted got %+v", feeds)
+	}
+}
@@ -7,19 +7,33 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countFeedFollowsForFeed = `-- name: CountFeedFollowsForFeed :one
+SELECT COUNT(*)
+FROM feed_follows
+WHERE feed_id = $1
+`
+
+func (q *Queries) CountFeedFollowsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedFollowsForFeed, feedID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
   INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
   VALUES ($1,$2,$3,$4,$5)
-  RETURNING id, created_at, updated_at, user_id, feed_id
+  RETURNING id, created_at, updated_at, user_id, feed_id, display_name, priority, muted, notify
 ) 
 SELECT
-  inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+  inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.display_name, inserted_feed_follow.priority, inserted_feed_follow.muted, inserted_feed_follow.notify,
   feeds.name as feed_name,
   users.name as user_name
 FROM inserted_feed_follow
@@ -36,13 +50,17 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	DisplayName sql.NullString
+	Priority    int32
+	Muted       bool
+	Notify      bool
+	FeedName    string
+	UserName    string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -60,6 +78,10 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.DisplayName,
+		&i.Priority,
+		&i.Muted,
+		&i.Notify,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -82,6 +104,71 @@ func (q *Queries) DeleteFeedFollowForUser(ctx context.Context, arg DeleteFeedFol
 	return err
 }
 
+const deleteFeedFollowsForFeed = `-- name: DeleteFeedFollowsForFeed :execrows
+DELETE FROM feed_follows
+WHERE feed_id = $1
+`
+
+func (q *Queries) DeleteFeedFollowsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowsForFeed, feedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedFollowsForUser = `-- name: DeleteFeedFollowsForUser :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowsForUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllFeedFollows = `-- name: GetAllFeedFollows :many
+SELECT id, created_at, updated_at, user_id, feed_id, display_name, priority, muted, notify
+FROM feed_follows
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetAllFeedFollows(ctx context.Context) ([]FeedFollow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeedFollows)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedFollow
+	for rows.Next() {
+		var i FeedFollow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.DisplayName,
+			&i.Priority,
+			&i.Muted,
+			&i.Notify,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedFollows = `-- name: GetFeedFollows :many
 SELECT
   feed_follows.id,
@@ -90,7 +177,7 @@ SELECT
   feed_follows.user_id,
   feed_follows.feed_id,
   users.name as user_name,
-  feeds.name as feed_name
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name
 FROM feed_follows
 INNER JOIN users ON users.id = feed_follows.user_id
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
@@ -144,24 +231,33 @@ SELECT
   feed_follows.updated_at,
   feed_follows.user_id,
   feed_follows.feed_id,
+  feed_follows.display_name,
+  feed_follows.priority,
+  feed_follows.muted,
+  feed_follows.notify,
   users.name as user_name,
-  feeds.name as feed_name,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name,
   feeds.url as feed_url
 FROM feed_follows
 INNER JOIN users ON users.id = feed_follows.user_id
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.priority DESC, feed_name
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	UserName  string
-	FeedName  string
-	FeedUrl   string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	DisplayName sql.NullString
+	Priority    int32
+	Muted       bool
+	Notify      bool
+	UserName    string
+	FeedName    string
+	FeedUrl     string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -179,6 +275,10 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.DisplayName,
+			&i.Priority,
+			&i.Muted,
+			&i.Notify,
 			&i.UserName,
 			&i.FeedName,
 			&i.FeedUrl,
@@ -195,3 +295,210 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const getFeedFollowsForUserInCategory = `-- name: GetFeedFollowsForUserInCategory :many
+SELECT
+  feed_follows.id,
+  feed_follows.created_at,
+  feed_follows.updated_at,
+  feed_follows.user_id,
+  feed_follows.feed_id,
+  feed_follows.display_name,
+  feed_follows.priority,
+  feed_follows.muted,
+  feed_follows.notify,
+  users.name as user_name,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name,
+  feeds.url as feed_url
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+INNER JOIN feed_follow_categories ON feed_follow_categories.feed_follow_id = feed_follows.id
+WHERE feed_follows.user_id = $1
+  AND feed_follow_categories.category_id = $2
+ORDER BY feed_follows.priority DESC, feed_name
+`
+
+type GetFeedFollowsForUserInCategoryParams struct {
+	UserID     uuid.UUID
+	CategoryID uuid.UUID
+}
+
+type GetFeedFollowsForUserInCategoryRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	DisplayName sql.NullString
+	Priority    int32
+	Muted       bool
+	Notify      bool
+	UserName    string
+	FeedName    string
+	FeedUrl     string
+}
+
+func (q *Queries) GetFeedFollowsForUserInCategory(ctx context.Context, arg GetFeedFollowsForUserInCategoryParams) ([]GetFeedFollowsForUserInCategoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUserInCategory, arg.UserID, arg.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFollowsForUserInCategoryRow
+	for rows.Next() {
+		var i GetFeedFollowsForUserInCategoryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.DisplayName,
+			&i.Priority,
+			&i.Muted,
+			&i.Notify,
+			&i.UserName,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNotifiedFollowersForFeed = `-- name: GetNotifiedFollowersForFeed :many
+SELECT
+  users.name as user_name,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.feed_id = $1
+  AND feed_follows.notify
+  AND NOT feed_follows.muted
+`
+
+type GetNotifiedFollowersForFeedRow struct {
+	UserName string
+	FeedName string
+}
+
+func (q *Queries) GetNotifiedFollowersForFeed(ctx context.Context, feedID uuid.UUID) ([]GetNotifiedFollowersForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getNotifiedFollowersForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetNotifiedFollowersForFeedRow
+	for rows.Next() {
+		var i GetNotifiedFollowersForFeedRow
+		if err := rows.Scan(&i.UserName, &i.FeedName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreFeedFollow = `-- name: RestoreFeedFollow :one
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, display_name, priority, muted, notify)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, user_id, feed_id, display_name, priority, muted, notify
+`
+
+type RestoreFeedFollowParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	DisplayName sql.NullString
+	Priority    int32
+	Muted       bool
+	Notify      bool
+}
+
+func (q *Queries) RestoreFeedFollow(ctx context.Context, arg RestoreFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, restoreFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.DisplayName,
+		arg.Priority,
+		arg.Muted,
+		arg.Notify,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.DisplayName,
+		&i.Priority,
+		&i.Muted,
+		&i.Notify,
+	)
+	return i, err
+}
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET
+  display_name = $2,
+  priority = $3,
+  muted = $4,
+  notify = $5,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, user_id, feed_id, display_name, priority, muted, notify
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	ID          uuid.UUID
+	DisplayName sql.NullString
+	Priority    int32
+	Muted       bool
+	Notify      bool
+}
+
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollowSettings,
+		arg.ID,
+		arg.DisplayName,
+		arg.Priority,
+		arg.Muted,
+		arg.Notify,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.DisplayName,
+		&i.Priority,
+		&i.Muted,
+		&i.Notify,
+	)
+	return i, err
+}
@@ -7,13 +7,14 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, added_by)
 VALUES (
     $1,
     $2,
@@ -22,7 +23,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
 `
 
 type CreateFeedParams struct {
@@ -31,7 +32,7 @@ type CreateFeedParams struct {
 	UpdatedAt time.Time
 	Name      string
 	Url       string
-	UserID    uuid.UUID
+	AddedBy   uuid.NullUUID
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -41,7 +42,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.UpdatedAt,
 		arg.Name,
 		arg.Url,
-		arg.UserID,
+		arg.AddedBy,
 	)
 	var i Feed
 	err := row.Scan(
@@ -50,43 +51,114 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.UpdatedAt,
 		&i.Name,
 		&i.Url,
-		&i.UserID,
+		&i.AddedBy,
 		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
-const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT
-  feeds.id,
-  feeds.created_at,
-  feeds.updated_at,
-  feeds.name,
-  feeds.url,
-  feeds.user_id
+const deleteAllFeeds = `-- name: DeleteAllFeeds :exec
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteAllFeeds(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllFeeds)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getAllFeeds = `-- name: GetAllFeeds :many
+SELECT id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
 FROM feeds
-WHERE feeds.url = $1
+ORDER BY name
 `
 
-type GetFeedByUrlRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getAllFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.AddedBy,
+			&i.LastFetchedAt,
+			&i.RetentionMaxAge,
+			&i.RetentionMaxPosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (GetFeedByUrlRow, error) {
+const getFeed = `-- name: GetFeed :one
+SELECT id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
+FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeed(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeed, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.AddedBy,
+		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const getFeedByUrl = `-- name: GetFeedByUrl :one
+SELECT id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
+FROM feeds
+WHERE url = $1
+`
+
+func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
-	var i GetFeedByUrlRow
+	var i Feed
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
 		&i.Url,
-		&i.UserID,
+		&i.AddedBy,
+		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
@@ -98,20 +170,23 @@ SELECT
   feeds.updated_at,
   feeds.name,
   feeds.url,
-  feeds.user_id,
-  users.name AS user_name
+  feeds.added_by,
+  users.name AS added_by_name,
+  (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS followers
 FROM feeds
-JOIN users ON users.id = feeds.user_id
+LEFT JOIN users ON users.id = feeds.added_by
+ORDER BY feeds.name
 `
 
 type GetFeedsRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string
+	Url         string
+	AddedBy     uuid.NullUUID
+	AddedByName sql.NullString
+	Followers   int64
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -129,8 +204,9 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 			&i.UpdatedAt,
 			&i.Name,
 			&i.Url,
-			&i.UserID,
-			&i.UserName,
+			&i.AddedBy,
+			&i.AddedByName,
+			&i.Followers,
 		); err != nil {
 			return nil, err
 		}
@@ -146,8 +222,9 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
 FROM feeds
+WHERE EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
 `
@@ -161,19 +238,73 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.UpdatedAt,
 		&i.Name,
 		&i.Url,
-		&i.UserID,
+		&i.AddedBy,
 		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
+const getUnfollowedFeeds = `-- name: GetUnfollowedFeeds :many
+SELECT id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
+FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+ORDER BY name
+`
+
+func (q *Queries) GetUnfollowedFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getUnfollowedFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.AddedBy,
+			&i.LastFetchedAt,
+			&i.RetentionMaxAge,
+			&i.RetentionMaxPosts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllFeedsUnfetched = `-- name: MarkAllFeedsUnfetched :exec
+UPDATE feeds
+SET
+  last_fetched_at = NULL,
+  updated_at = NOW()
+`
+
+func (q *Queries) MarkAllFeedsUnfetched(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, markAllFeedsUnfetched)
+	return err
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :one
 UPDATE feeds
 SET 
   last_fetched_at = NOW(), 
   updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
 `
 
 func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -185,8 +316,185 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, erro
 		&i.UpdatedAt,
 		&i.Name,
 		&i.Url,
-		&i.UserID,
+		&i.AddedBy,
+		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const markFeedUnfetched = `-- name: MarkFeedUnfetched :exec
+UPDATE feeds
+SET
+  last_fetched_at = NULL,
+  updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkFeedUnfetched(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markFeedUnfetched, id)
+	return err
+}
+
+const renameFeed = `-- name: RenameFeed :one
+UPDATE feeds
+SET
+  name = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
+`
+
+type RenameFeedParams struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, renameFeed, arg.ID, arg.Name)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.AddedBy,
+		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const restoreFeed = `-- name: RestoreFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
+`
+
+type RestoreFeedParams struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Name              string
+	Url               string
+	AddedBy           uuid.NullUUID
+	LastFetchedAt     sql.NullTime
+	RetentionMaxAge   sql.NullString
+	RetentionMaxPosts sql.NullInt32
+}
+
+func (q *Queries) RestoreFeed(ctx context.Context, arg RestoreFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, restoreFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.AddedBy,
+		arg.LastFetchedAt,
+		arg.RetentionMaxAge,
+		arg.RetentionMaxPosts,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.AddedBy,
+		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :one
+UPDATE feeds
+SET
+  retention_max_age = $2,
+  retention_max_posts = $3,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
+`
+
+type SetFeedRetentionParams struct {
+	ID                uuid.UUID
+	RetentionMaxAge   sql.NullString
+	RetentionMaxPosts sql.NullInt32
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRetention, arg.ID, arg.RetentionMaxAge, arg.RetentionMaxPosts)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.AddedBy,
+		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
+	)
+	return i, err
+}
+
+const setFeedUrl = `-- name: SetFeedUrl :one
+UPDATE feeds
+SET
+  url = $2,
+  last_fetched_at = NULL,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
+`
+
+type SetFeedUrlParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) SetFeedUrl(ctx context.Context, arg SetFeedUrlParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedUrl, arg.ID, arg.Url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.AddedBy,
 		&i.LastFetchedAt,
+		&i.RetentionMaxAge,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds
+SET
+  added_by = $1,
+  updated_at = NOW()
+WHERE added_by = $2
+`
+
+type TransferFeedsParams struct {
+	ToUserID   uuid.NullUUID
+	FromUserID uuid.NullUUID
+}
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds, arg.ToUserID, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
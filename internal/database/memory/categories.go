@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+func (s *Store) CreateCategory(ctx context.Context, arg database.CreateCategoryParams) (database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.categories, func(c database.Category) bool {
+		return c.ID == arg.ID || (c.UserID == arg.UserID && c.Name == arg.Name)
+	}) >= 0 {
+		return database.Category{}, unique("categories_user_id_name_key")
+	}
+	if _, ok := s.user(arg.UserID); !ok {
+		return database.Category{}, errForeignKey
+	}
+	c := database.Category(arg)
+	s.categories = append(s.categories, c)
+	return c, nil
+}
+
+func (s *Store) GetCategoriesForUser(ctx context.Context, userID uuid.UUID) ([]database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.Category
+	for _, c := range s.categories {
+		if c.UserID == userID {
+			items = append(items, c)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b database.Category) int { return strings.Compare(a.Name, b.Name) })
+	return items, nil
+}
+
+func (s *Store) GetCategoryFeedCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetCategoryFeedCountsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetCategoryFeedCountsForUserRow
+	for _, c := range s.categories {
+		if c.UserID != userID {
+			continue
+		}
+		row := database.GetCategoryFeedCountsForUserRow{Name: c.Name}
+		for _, fc := range s.followCategories {
+			if fc.CategoryID == c.ID {
+				row.Feeds++
+			}
+		}
+		items = append(items, row)
+	}
+	slices.SortStableFunc(items, func(a, b database.GetCategoryFeedCountsForUserRow) int { return strings.Compare(a.Name, b.Name) })
+	return items, nil
+}
+
+func (s *Store) GetCategoryByName(ctx context.Context, arg database.GetCategoryByNameParams) (database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return get(s.categories, func(c database.Category) bool { return c.UserID == arg.UserID && c.Name == arg.Name })
+}
+
+func (s *Store) RenameCategory(ctx context.Context, arg database.RenameCategoryParams) (database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.categories, func(c database.Category) bool { return c.ID == arg.ID })
+	if i < 0 {
+		return database.Category{}, sql.ErrNoRows
+	}
+	c := &s.categories[i]
+	if find(s.categories, func(o database.Category) bool {
+		return o.ID != c.ID && o.UserID == c.UserID && o.Name == arg.Name
+	}) >= 0 {
+		return database.Category{}, unique("categories_user_id_name_key")
+	}
+	c.Name = arg.Name
+	c.UpdatedAt = now()
+	return *c, nil
+}
+
+func (s *Store) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.categories = slices.DeleteFunc(s.categories, func(c database.Category) bool { return c.ID == id })
+	s.followCategories = slices.DeleteFunc(s.followCategories, func(fc database.FeedFollowCategory) bool { return fc.CategoryID == id })
+	return nil
+}
+
+// AddFeedFollowToCategory does nothing when the follow is already in the category
+func (s *Store) AddFeedFollowToCategory(ctx context.Context, arg database.AddFeedFollowToCategoryParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fc := database.FeedFollowCategory(arg)
+	if slices.Contains(s.followCategories, fc) {
+		return nil
+	}
+	if find(s.follows, func(f database.FeedFollow) bool { return f.ID == arg.FeedFollowID }) < 0 ||
+		find(s.categories, func(c database.Category) bool { return c.ID == arg.CategoryID }) < 0 {
+		return errForeignKey
+	}
+	s.followCategories = append(s.followCategories, fc)
+	return nil
+}
+
+func (s *Store) RemoveFeedFollowFromCategory(ctx context.Context, arg database.RemoveFeedFollowFromCategoryParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.followCategories = slices.DeleteFunc(s.followCategories, func(fc database.FeedFollowCategory) bool {
+		return fc == database.FeedFollowCategory(arg)
+	})
+	return nil
+}
+
+func (s *Store) GetFeedFollowCategoriesForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowCategoriesForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetFeedFollowCategoriesForUserRow
+	for _, fc := range s.followCategories {
+		c, err := get(s.categories, func(c database.Category) bool { return c.ID == fc.CategoryID })
+		if err != nil || c.UserID != userID {
+			continue
+		}
+		items = append(items, database.GetFeedFollowCategoriesForUserRow{FeedFollowID: fc.FeedFollowID, CategoryName: c.Name})
+	}
+	slices.SortStableFunc(items, func(a, b database.GetFeedFollowCategoriesForUserRow) int {
+		return strings.Compare(a.CategoryName, b.CategoryName)
+	})
+	return items, nil
+}
+
+func (s *Store) GetAllCategories(ctx context.Context) ([]database.Category, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := slices.Clone(s.categories)
+	slices.SortFunc(items, func(a, b database.Category) int { return compareCreated(a.CreatedAt, a.ID, b.CreatedAt, b.ID) })
+	return items, nil
+}
+
+func (s *Store) GetAllFeedFollowCategories(ctx context.Context) ([]database.FeedFollowCategory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.followCategories), nil
+}
+
+func (s *Store) DeleteCategoriesForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make(map[uuid.UUID]bool)
+	s.categories = slices.DeleteFunc(s.categories, func(c database.Category) bool {
+		if c.UserID == userID {
+			ids[c.ID] = true
+		}
+		return ids[c.ID]
+	})
+	s.followCategories = slices.DeleteFunc(s.followCategories, func(fc database.FeedFollowCategory) bool { return ids[fc.CategoryID] })
+	return int64(len(ids)), nil
+}
@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+func (s *Store) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.follows, func(f database.FeedFollow) bool {
+		return f.ID == arg.ID || (f.UserID == arg.UserID && f.FeedID == arg.FeedID)
+	}) >= 0 {
+		return database.CreateFeedFollowRow{}, unique("feed_follows_user_id_feed_id_key")
+	}
+	u, userOK := s.user(arg.UserID)
+	feed, feedOK := s.feed(arg.FeedID)
+	if !userOK || !feedOK {
+		return database.CreateFeedFollowRow{}, errForeignKey
+	}
+
+	f := database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	}
+	s.follows = append(s.follows, f)
+	return database.CreateFeedFollowRow{
+		ID:        f.ID,
+		CreatedAt: f.CreatedAt,
+		UpdatedAt: f.UpdatedAt,
+		UserID:    f.UserID,
+		FeedID:    f.FeedID,
+		FeedName:  feed.Name,
+		UserName:  u.Name,
+	}, nil
+}
+
+func (s *Store) GetFeedFollows(ctx context.Context) ([]database.GetFeedFollowsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetFeedFollowsRow
+	for _, f := range s.follows {
+		u, _ := s.user(f.UserID)
+		items = append(items, database.GetFeedFollowsRow{
+			ID:        f.ID,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+			UserID:    f.UserID,
+			FeedID:    f.FeedID,
+			UserName:  u.Name,
+			FeedName:  s.followName(f),
+		})
+	}
+	return items, nil
+}
+
+func (s *Store) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.followsForUser(userID, func(database.FeedFollow) bool { return true }), nil
+}
+
+func (s *Store) GetFeedFollowsForUserInCategory(ctx context.Context, arg database.GetFeedFollowsForUserInCategoryParams) ([]database.GetFeedFollowsForUserInCategoryRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetFeedFollowsForUserInCategoryRow
+	for _, f := range s.followsForUser(arg.UserID, s.inCategory(arg.CategoryID)) {
+		items = append(items, database.GetFeedFollowsForUserInCategoryRow(f))
+	}
+	return items, nil
+}
+
+// followsForUser lists the user's follows that match keep, highest priority first and then by name
+func (s *Store) followsForUser(userID uuid.UUID, keep func(database.FeedFollow) bool) []database.GetFeedFollowsForUserRow {
+	var items []database.GetFeedFollowsForUserRow
+	for _, f := range s.follows {
+		if f.UserID != userID || !keep(f) {
+			continue
+		}
+		u, _ := s.user(f.UserID)
+		feed, _ := s.feed(f.FeedID)
+		items = append(items, database.GetFeedFollowsForUserRow{
+			ID:          f.ID,
+			CreatedAt:   f.CreatedAt,
+			UpdatedAt:   f.UpdatedAt,
+			UserID:      f.UserID,
+			FeedID:      f.FeedID,
+			DisplayName: f.DisplayName,
+			Priority:    f.Priority,
+			Muted:       f.Muted,
+			Notify:      f.Notify,
+			UserName:    u.Name,
+			FeedName:    s.followName(f),
+			FeedUrl:     feed.Url,
+		})
+	}
+	slices.SortStableFunc(items, func(a, b database.GetFeedFollowsForUserRow) int {
+		return cmp.Or(cmp.Compare(b.Priority, a.Priority), cmp.Compare(a.FeedName, b.FeedName))
+	})
+	return items
+}
+
+// inCategory matches the follows assigned to a category
+func (s *Store) inCategory(categoryID uuid.UUID) func(database.FeedFollow) bool {
+	return func(f database.FeedFollow) bool {
+		return find(s.followCategories, func(fc database.FeedFollowCategory) bool {
+			return fc.FeedFollowID == f.ID && fc.CategoryID == categoryID
+		}) >= 0
+	}
+}
+
+func (s *Store) GetNotifiedFollowersForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetNotifiedFollowersForFeedRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetNotifiedFollowersForFeedRow
+	for _, f := range s.follows {
+		if f.FeedID != feedID || !f.Notify || f.Muted {
+			continue
+		}
+		u, _ := s.user(f.UserID)
+		items = append(items, database.GetNotifiedFollowersForFeedRow{UserName: u.Name, FeedName: s.followName(f)})
+	}
+	return items, nil
+}
+
+func (s *Store) UpdateFeedFollowSettings(ctx context.Context, arg database.UpdateFeedFollowSettingsParams) (database.FeedFollow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.follows, func(f database.FeedFollow) bool { return f.ID == arg.ID })
+	if i < 0 {
+		return database.FeedFollow{}, sql.ErrNoRows
+	}
+	f := &s.follows[i]
+	f.DisplayName = arg.DisplayName
+	f.Priority = arg.Priority
+	f.Muted = arg.Muted
+	f.Notify = arg.Notify
+	f.UpdatedAt = now()
+	return *f, nil
+}
+
+func (s *Store) DeleteFeedFollowForUser(ctx context.Context, arg database.DeleteFeedFollowForUserParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteFollows(func(f database.FeedFollow) bool { return f.UserID == arg.UserID && f.FeedID == arg.FeedID })
+	return nil
+}
+
+func (s *Store) GetAllFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := slices.Clone(s.follows)
+	slices.SortFunc(items, func(a, b database.FeedFollow) int { return compareCreated(a.CreatedAt, a.ID, b.CreatedAt, b.ID) })
+	return items, nil
+}
+
+func (s *Store) RestoreFeedFollow(ctx context.Context, arg database.RestoreFeedFollowParams) (database.FeedFollow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.follows, func(f database.FeedFollow) bool {
+		return f.ID == arg.ID || (f.UserID == arg.UserID && f.FeedID == arg.FeedID)
+	}) >= 0 {
+		return database.FeedFollow{}, unique("feed_follows_user_id_feed_id_key")
+	}
+	_, userOK := s.user(arg.UserID)
+	_, feedOK := s.feed(arg.FeedID)
+	if !userOK || !feedOK {
+		return database.FeedFollow{}, errForeignKey
+	}
+	f := database.FeedFollow(arg)
+	s.follows = append(s.follows, f)
+	return f, nil
+}
+
+func (s *Store) DeleteFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.follows)
+	s.deleteFollows(func(f database.FeedFollow) bool { return f.UserID == userID })
+	return int64(before - len(s.follows)), nil
+}
+
+func (s *Store) DeleteFeedFollowsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.follows)
+	s.deleteFollows(func(f database.FeedFollow) bool { return f.FeedID == feedID })
+	return int64(before - len(s.follows)), nil
+}
+
+func (s *Store) CountFeedFollowsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(s.followers(feedID)), nil
+}
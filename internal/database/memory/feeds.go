@@ -0,0 +1,248 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+func (s *Store) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.feeds, func(f database.Feed) bool { return f.Url == arg.Url || f.ID == arg.ID }) >= 0 {
+		return database.Feed{}, unique("feeds_url_key")
+	}
+	if !s.userOrNull(arg.AddedBy) {
+		return database.Feed{}, errForeignKey
+	}
+	f := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		AddedBy:   arg.AddedBy,
+	}
+	s.feeds = append(s.feeds, f)
+	return f, nil
+}
+
+func (s *Store) GetFeed(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return get(s.feeds, func(f database.Feed) bool { return f.ID == id })
+}
+
+func (s *Store) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return get(s.feeds, func(f database.Feed) bool { return f.Url == url })
+}
+
+func (s *Store) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetFeedsRow
+	for _, f := range s.feeds {
+		row := database.GetFeedsRow{
+			ID:        f.ID,
+			CreatedAt: f.CreatedAt,
+			UpdatedAt: f.UpdatedAt,
+			Name:      f.Name,
+			Url:       f.Url,
+			AddedBy:   f.AddedBy,
+			Followers: int64(s.followers(f.ID)),
+		}
+		if u, ok := s.user(f.AddedBy.UUID); ok && f.AddedBy.Valid {
+			row.AddedByName = sql.NullString{String: u.Name, Valid: true}
+		}
+		items = append(items, row)
+	}
+	slices.SortStableFunc(items, func(a, b database.GetFeedsRow) int { return strings.Compare(a.Name, b.Name) })
+	return items, nil
+}
+
+func (s *Store) GetAllFeeds(ctx context.Context) ([]database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.Feed
+	items = append(items, s.feeds...)
+	slices.SortStableFunc(items, func(a, b database.Feed) int { return strings.Compare(a.Name, b.Name) })
+	return items, nil
+}
+
+// GetNextFeedToFetch returns the followed feed fetched longest ago, feeds never fetched come first
+func (s *Store) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var next *database.Feed
+	for i := range s.feeds {
+		f := &s.feeds[i]
+		if s.followers(f.ID) == 0 {
+			continue
+		}
+		switch {
+		case next == nil:
+			next = f
+		case !next.LastFetchedAt.Valid:
+		case !f.LastFetchedAt.Valid || f.LastFetchedAt.Time.Before(next.LastFetchedAt.Time):
+			next = f
+		}
+	}
+	if next == nil {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return *next, nil
+}
+
+func (s *Store) MarkFeedFetched(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	return s.updateFeed(id, func(f *database.Feed) {
+		f.LastFetchedAt = sql.NullTime{Time: now(), Valid: true}
+	})
+}
+
+func (s *Store) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.Feed, error) {
+	return s.updateFeed(arg.ID, func(f *database.Feed) {
+		f.RetentionMaxAge = arg.RetentionMaxAge
+		f.RetentionMaxPosts = arg.RetentionMaxPosts
+	})
+}
+
+func (s *Store) RenameFeed(ctx context.Context, arg database.RenameFeedParams) (database.Feed, error) {
+	return s.updateFeed(arg.ID, func(f *database.Feed) {
+		f.Name = arg.Name
+	})
+}
+
+func (s *Store) SetFeedUrl(ctx context.Context, arg database.SetFeedUrlParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.feeds, func(f database.Feed) bool { return f.Url == arg.Url && f.ID != arg.ID }) >= 0 {
+		return database.Feed{}, unique("feeds_url_key")
+	}
+	i := find(s.feeds, func(f database.Feed) bool { return f.ID == arg.ID })
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	s.feeds[i].Url = arg.Url
+	s.feeds[i].LastFetchedAt = sql.NullTime{}
+	s.feeds[i].UpdatedAt = now()
+	return s.feeds[i], nil
+}
+
+// updateFeed applies fn to a feed and bumps updated_at, it returns the feed like UPDATE ... RETURNING *
+func (s *Store) updateFeed(id uuid.UUID, fn func(f *database.Feed)) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.feeds, func(f database.Feed) bool { return f.ID == id })
+	if i < 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	fn(&s.feeds[i])
+	s.feeds[i].UpdatedAt = now()
+	return s.feeds[i], nil
+}
+
+func (s *Store) RestoreFeed(ctx context.Context, arg database.RestoreFeedParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.feeds, func(f database.Feed) bool { return f.Url == arg.Url || f.ID == arg.ID }) >= 0 {
+		return database.Feed{}, unique("feeds_url_key")
+	}
+	if !s.userOrNull(arg.AddedBy) {
+		return database.Feed{}, errForeignKey
+	}
+	f := database.Feed(arg)
+	s.feeds = append(s.feeds, f)
+	return f, nil
+}
+
+func (s *Store) MarkFeedUnfetched(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := find(s.feeds, func(f database.Feed) bool { return f.ID == id }); i >= 0 {
+		s.feeds[i].LastFetchedAt = sql.NullTime{}
+		s.feeds[i].UpdatedAt = now()
+	}
+	return nil
+}
+
+func (s *Store) MarkAllFeedsUnfetched(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.feeds {
+		s.feeds[i].LastFetchedAt = sql.NullTime{}
+		s.feeds[i].UpdatedAt = now()
+	}
+	return nil
+}
+
+func (s *Store) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for i := range s.feeds {
+		// NULL never equals NULL so feeds without a user are never transferred
+		if f := &s.feeds[i]; arg.FromUserID.Valid && f.AddedBy == arg.FromUserID {
+			if !s.userOrNull(arg.ToUserID) {
+				return 0, errForeignKey
+			}
+			f.AddedBy = arg.ToUserID
+			f.UpdatedAt = now()
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) DeleteAllFeeds(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteFeeds(func(database.Feed) bool { return true })
+	return nil
+}
+
+func (s *Store) GetUnfollowedFeeds(ctx context.Context) ([]database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.Feed
+	for _, f := range s.feeds {
+		if s.followers(f.ID) == 0 {
+			items = append(items, f)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b database.Feed) int { return strings.Compare(a.Name, b.Name) })
+	return items, nil
+}
+
+// DeleteFeed deletes a feed along with its posts, follows and handles like the cascading foreign keys
+func (s *Store) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteFeeds(func(f database.Feed) bool { return f.ID == id })
+	return nil
+}
+
+// followers counts the follows of a feed
+func (s *Store) followers(feedID uuid.UUID) int {
+	n := 0
+	for _, f := range s.follows {
+		if f.FeedID == feedID {
+			n++
+		}
+	}
+	return n
+}
+
+// userOrNull reports whether a nullable user reference is NULL or points at a user
+func (s *Store) userOrNull(id uuid.NullUUID) bool {
+	if !id.Valid {
+		return true
+	}
+	_, ok := s.user(id.UUID)
+	return ok
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+// nextHandles advances the user's counter for kind by n and returns the last handle handed out before,
+// handles are never reused even after the rows holding them are gone
+func (s *Store) nextHandles(userID uuid.UUID, kind string, n int) int32 {
+	i := find(s.counters, func(c database.HandleCounter) bool { return c.UserID == userID && c.Kind == kind })
+	if i < 0 {
+		s.counters = append(s.counters, database.HandleCounter{UserID: userID, Kind: kind})
+		i = len(s.counters) - 1
+	}
+	first := s.counters[i].LastHandle
+	s.counters[i].LastHandle += int32(n)
+	return first
+}
+
+func (s *Store) AssignPostHandles(ctx context.Context, arg database.AssignPostHandlesParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []uuid.UUID
+	for _, id := range arg.PostIds {
+		if slices.Contains(ids, id) || find(s.postHandles, func(h database.PostHandle) bool { return h.UserID == arg.UserID && h.PostID == id }) >= 0 {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	first := s.nextHandles(arg.UserID, "post", len(ids))
+	for i, id := range ids {
+		s.postHandles = append(s.postHandles, database.PostHandle{UserID: arg.UserID, PostID: id, Handle: first + int32(i+1)})
+	}
+	return nil
+}
+
+func (s *Store) GetPostHandles(ctx context.Context, arg database.GetPostHandlesParams) ([]database.GetPostHandlesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetPostHandlesRow
+	for _, h := range s.postHandles {
+		if h.UserID == arg.UserID && slices.Contains(arg.PostIds, h.PostID) {
+			items = append(items, database.GetPostHandlesRow{PostID: h.PostID, Handle: h.Handle})
+		}
+	}
+	return items, nil
+}
+
+func (s *Store) GetPostByHandle(ctx context.Context, arg database.GetPostByHandleParams) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, err := get(s.postHandles, func(h database.PostHandle) bool { return h.UserID == arg.UserID && h.Handle == arg.Handle })
+	if err != nil {
+		return database.Post{}, err
+	}
+	return get(s.posts, func(p database.Post) bool { return p.ID == h.PostID })
+}
+
+func (s *Store) AssignFeedHandles(ctx context.Context, arg database.AssignFeedHandlesParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []uuid.UUID
+	for _, id := range arg.FeedIds {
+		if slices.Contains(ids, id) || find(s.feedHandles, func(h database.FeedHandle) bool { return h.UserID == arg.UserID && h.FeedID == id }) >= 0 {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	first := s.nextHandles(arg.UserID, "feed", len(ids))
+	for i, id := range ids {
+		s.feedHandles = append(s.feedHandles, database.FeedHandle{UserID: arg.UserID, FeedID: id, Handle: first + int32(i+1)})
+	}
+	return nil
+}
+
+func (s *Store) GetFeedHandles(ctx context.Context, arg database.GetFeedHandlesParams) ([]database.GetFeedHandlesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetFeedHandlesRow
+	for _, h := range s.feedHandles {
+		if h.UserID == arg.UserID && slices.Contains(arg.FeedIds, h.FeedID) {
+			items = append(items, database.GetFeedHandlesRow{FeedID: h.FeedID, Handle: h.Handle})
+		}
+	}
+	return items, nil
+}
+
+func (s *Store) GetFeedByHandle(ctx context.Context, arg database.GetFeedByHandleParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, err := get(s.feedHandles, func(h database.FeedHandle) bool { return h.UserID == arg.UserID && h.Handle == arg.Handle })
+	if err != nil {
+		return database.Feed{}, err
+	}
+	return get(s.feeds, func(f database.Feed) bool { return f.ID == h.FeedID })
+}
@@ -0,0 +1,189 @@
+// Package memory is a database.Store kept in memory for tests. It keeps the rules the sql schema and queries
+// enforce, unique constraints, cascading deletes and the order rows are returned in, so handlers behave the same.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+// Store keeps the tables in memory behind a single lock
+type Store struct {
+	mu sync.Mutex
+	// tx serializes units of work, calls made outside InTx are not held back by it
+	tx sync.Mutex
+	tables
+}
+
+// tables holds every table as a slice in insertion order, the order rows come back in when a query has no ORDER BY
+type tables struct {
+	users            []database.User
+	feeds            []database.Feed
+	follows          []database.FeedFollow
+	categories       []database.Category
+	followCategories []database.FeedFollowCategory
+	posts            []database.Post
+	states           []database.UserPostState
+	rules            []database.Rule
+	counters         []database.HandleCounter
+	postHandles      []database.PostHandle
+	feedHandles      []database.FeedHandle
+}
+
+// clone copies every table, the rows hold no pointers so copying the slices is enough
+func (t tables) clone() tables {
+	return tables{
+		users:            slices.Clone(t.users),
+		feeds:            slices.Clone(t.feeds),
+		follows:          slices.Clone(t.follows),
+		categories:       slices.Clone(t.categories),
+		followCategories: slices.Clone(t.followCategories),
+		posts:            slices.Clone(t.posts),
+		states:           slices.Clone(t.states),
+		rules:            slices.Clone(t.rules),
+		counters:         slices.Clone(t.counters),
+		postHandles:      slices.Clone(t.postHandles),
+		feedHandles:      slices.Clone(t.feedHandles),
+	}
+}
+
+func New() *Store {
+	return &Store{}
+}
+
+var (
+	_ database.Store      = (*Store)(nil)
+	_ database.UnitOfWork = (*Store)(nil)
+)
+
+// InTx runs fn against the store and puts every table back the way it was when fn returns an error
+func (s *Store) InTx(ctx context.Context, fn func(q database.Store) error) error {
+	s.tx.Lock()
+	defer s.tx.Unlock()
+
+	s.mu.Lock()
+	saved := s.tables.clone()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.tables = saved
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// errForeignKey mirrors the error a database gives when a row points at one that does not exist
+var errForeignKey = errors.New("insert violates foreign key constraint")
+
+func unique(constraint string) error {
+	return fmt.Errorf("%w %q", database.ErrUniqueViolation, constraint)
+}
+
+// now stands in for NOW() in the queries
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// find returns the index of the first row matching fn or -1
+func find[T any](rows []T, fn func(T) bool) int {
+	return slices.IndexFunc(rows, fn)
+}
+
+// get returns the first row matching fn or sql.ErrNoRows like a :one query
+func get[T any](rows []T, fn func(T) bool) (T, error) {
+	var zero T
+	i := find(rows, fn)
+	if i < 0 {
+		return zero, sql.ErrNoRows
+	}
+	return rows[i], nil
+}
+
+// compareIDs orders uuids the way postgres and sqlite do
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// compareCreated orders rows by created_at then id like ORDER BY created_at, id
+func compareCreated(aCreated time.Time, aID uuid.UUID, bCreated time.Time, bID uuid.UUID) int {
+	if c := aCreated.Compare(bCreated); c != 0 {
+		return c
+	}
+	return compareIDs(aID, bID)
+}
+
+func (s *Store) user(id uuid.UUID) (database.User, bool) {
+	u, err := get(s.users, func(u database.User) bool { return u.ID == id })
+	return u, err == nil
+}
+
+func (s *Store) feed(id uuid.UUID) (database.Feed, bool) {
+	f, err := get(s.feeds, func(f database.Feed) bool { return f.ID == id })
+	return f, err == nil
+}
+
+// followName is COALESCE(feed_follows.display_name, feeds.name)
+func (s *Store) followName(f database.FeedFollow) string {
+	if f.DisplayName.Valid {
+		return f.DisplayName.String
+	}
+	feed, _ := s.feed(f.FeedID)
+	return feed.Name
+}
+
+// state is the user's state for a post, the zero state when there is none like the LEFT JOIN and COALESCE
+func (s *Store) state(userID, postID uuid.UUID) database.UserPostState {
+	st, _ := get(s.states, func(st database.UserPostState) bool { return st.UserID == userID && st.PostID == postID })
+	return st
+}
+
+// deletePosts removes posts along with the rows that reference them
+func (s *Store) deletePosts(gone func(database.Post) bool) int64 {
+	ids := make(map[uuid.UUID]bool)
+	s.posts = slices.DeleteFunc(s.posts, func(p database.Post) bool {
+		if gone(p) {
+			ids[p.ID] = true
+		}
+		return ids[p.ID]
+	})
+	s.states = slices.DeleteFunc(s.states, func(st database.UserPostState) bool { return ids[st.PostID] })
+	s.postHandles = slices.DeleteFunc(s.postHandles, func(h database.PostHandle) bool { return ids[h.PostID] })
+	return int64(len(ids))
+}
+
+// deleteFeeds removes feeds along with their posts, follows and handles
+func (s *Store) deleteFeeds(gone func(database.Feed) bool) {
+	ids := make(map[uuid.UUID]bool)
+	s.feeds = slices.DeleteFunc(s.feeds, func(f database.Feed) bool {
+		if gone(f) {
+			ids[f.ID] = true
+		}
+		return ids[f.ID]
+	})
+	s.deletePosts(func(p database.Post) bool { return ids[p.FeedID] })
+	s.deleteFollows(func(f database.FeedFollow) bool { return ids[f.FeedID] })
+	s.feedHandles = slices.DeleteFunc(s.feedHandles, func(h database.FeedHandle) bool { return ids[h.FeedID] })
+}
+
+// deleteFollows removes follows along with their categories
+func (s *Store) deleteFollows(gone func(database.FeedFollow) bool) {
+	ids := make(map[uuid.UUID]bool)
+	s.follows = slices.DeleteFunc(s.follows, func(f database.FeedFollow) bool {
+		if gone(f) {
+			ids[f.ID] = true
+		}
+		return ids[f.ID]
+	})
+	s.followCategories = slices.DeleteFunc(s.followCategories, func(fc database.FeedFollowCategory) bool { return ids[fc.FeedFollowID] })
+}
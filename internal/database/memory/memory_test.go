@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/database/databasetest"
+	"github.com/joshhartwig/gator/internal/database/memory"
+)
+
+func TestConformance(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T) database.Store {
+		return memory.New()
+	})
+}
@@ -0,0 +1,404 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+// CreatePost skips a post whose url is already stored for its feed and returns sql.ErrNoRows like ON CONFLICT DO NOTHING
+func (s *Store) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.posts, func(p database.Post) bool { return p.FeedID == arg.FeedID && p.Url == arg.Url }) >= 0 {
+		return database.Post{}, sql.ErrNoRows
+	}
+	if find(s.posts, func(p database.Post) bool { return p.ID == arg.ID }) >= 0 {
+		return database.Post{}, unique("posts_pkey")
+	}
+	if _, ok := s.feed(arg.FeedID); !ok {
+		return database.Post{}, errForeignKey
+	}
+	p := database.Post(arg)
+	s.posts = append(s.posts, p)
+	return p, nil
+}
+
+func (s *Store) GetPost(ctx context.Context, id uuid.UUID) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return get(s.posts, func(p database.Post) bool { return p.ID == id })
+}
+
+// visiblePost is a post as one user sees it, through their follow of its feed
+type visiblePost struct {
+	post     database.Post
+	feedName string
+	title    string
+	flagged  bool
+}
+
+// visible returns the posts of the feeds the user follows that keep accepts, leaving out
+// muted feeds and posts the user's rules hid or deleted
+func (s *Store) visible(userID uuid.UUID, keep func(database.FeedFollow) bool) []visiblePost {
+	follows := make(map[uuid.UUID]database.FeedFollow)
+	for _, f := range s.follows {
+		if f.UserID == userID && !f.Muted && keep(f) {
+			follows[f.FeedID] = f
+		}
+	}
+	var posts []visiblePost
+	for _, p := range s.posts {
+		f, ok := follows[p.FeedID]
+		if !ok {
+			continue
+		}
+		st := s.state(userID, p.ID)
+		if st.Hidden || st.Deleted {
+			continue
+		}
+		title := p.Title
+		if st.Title.Valid {
+			title = st.Title.String
+		}
+		posts = append(posts, visiblePost{post: p, feedName: s.followName(f), title: title, flagged: st.Flagged})
+	}
+	return posts
+}
+
+// browse shows each cluster once by its first post along with the names of every feed in it,
+// oldest updated first starting after the cursor post
+func (s *Store) browse(userID uuid.UUID, keep func(database.FeedFollow) bool, limit int32, after uuid.NullUUID) []database.GetPostsForUserRow {
+	posts := s.visible(userID, keep)
+	clusters := make(map[uuid.UUID][]visiblePost)
+	for _, p := range posts {
+		clusters[p.post.ClusterID] = append(clusters[p.post.ClusterID], p)
+	}
+
+	var cursor *database.Post
+	if after.Valid {
+		p, err := get(s.posts, func(p database.Post) bool { return p.ID == after.UUID })
+		if err != nil {
+			// the cursor subquery is NULL and no row compares greater than it
+			return nil
+		}
+		cursor = &p
+	}
+
+	var items []database.GetPostsForUserRow
+	for _, cluster := range clusters {
+		first := slices.MinFunc(cluster, func(a, b visiblePost) int {
+			return cmp.Or(a.post.CreatedAt.Compare(b.post.CreatedAt), compareIDs(a.post.ID, b.post.ID))
+		})
+		if cursor != nil && cmp.Or(first.post.UpdatedAt.Compare(cursor.UpdatedAt), compareIDs(first.post.ID, cursor.ID)) <= 0 {
+			continue
+		}
+		feeds := []string{}
+		for _, p := range cluster {
+			feeds = append(feeds, p.feedName)
+		}
+		items = append(items, database.GetPostsForUserRow{
+			ID:           first.post.ID,
+			CreatedAt:    first.post.CreatedAt,
+			UpdatedAt:    first.post.UpdatedAt,
+			Title:        first.title,
+			Url:          first.post.Url,
+			Description:  first.post.Description,
+			PublishedAt:  first.post.PublishedAt,
+			FeedName:     first.feedName,
+			Flagged:      first.flagged,
+			ClusterFeeds: feeds,
+		})
+	}
+	slices.SortFunc(items, func(a, b database.GetPostsForUserRow) int {
+		return cmp.Or(a.UpdatedAt.Compare(b.UpdatedAt), compareIDs(a.ID, b.ID))
+	})
+	if len(items) > int(limit) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func (s *Store) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.browse(arg.UserID, func(database.FeedFollow) bool { return true }, arg.Limit, arg.After), nil
+}
+
+func (s *Store) GetPostsForUserInCategory(ctx context.Context, arg database.GetPostsForUserInCategoryParams) ([]database.GetPostsForUserInCategoryRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetPostsForUserInCategoryRow
+	for _, p := range s.browse(arg.UserID, s.inCategory(arg.CategoryID), arg.Limit, arg.After) {
+		items = append(items, database.GetPostsForUserInCategoryRow(p))
+	}
+	return items, nil
+}
+
+func (s *Store) GetLatestPostForUser(ctx context.Context, userID uuid.UUID) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	posts := s.visible(userID, func(database.FeedFollow) bool { return true })
+	if len(posts) == 0 {
+		return database.Post{}, sql.ErrNoRows
+	}
+	latest := slices.MaxFunc(posts, func(a, b visiblePost) int { return a.post.PublishedAt.Compare(b.post.PublishedAt) })
+	return latest.post, nil
+}
+
+// GetAllPostsForUser lists every post of the feeds the user follows, muted and hidden ones included, newest first
+func (s *Store) GetAllPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetAllPostsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetAllPostsForUserRow
+	var published []database.Post
+	for _, p := range s.posts {
+		i := find(s.follows, func(f database.FeedFollow) bool { return f.UserID == userID && f.FeedID == p.FeedID })
+		if i < 0 {
+			continue
+		}
+		published = append(published, p)
+		items = append(items, database.GetAllPostsForUserRow{
+			ID:          p.ID,
+			Title:       p.Title,
+			Url:         p.Url,
+			Description: p.Description,
+			Author:      p.Author,
+			Category:    p.Category,
+			FeedName:    s.followName(s.follows[i]),
+		})
+	}
+	sortNewest(items, published)
+	return items, nil
+}
+
+func (s *Store) GetPostsForFeed(ctx context.Context, arg database.GetPostsForFeedParams) ([]database.GetPostsForFeedRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetPostsForFeedRow
+	var published []database.Post
+	for _, p := range s.posts {
+		st := s.state(arg.UserID, p.ID)
+		if p.FeedID != arg.FeedID || st.Hidden || st.Deleted {
+			continue
+		}
+		title := p.Title
+		if st.Title.Valid {
+			title = st.Title.String
+		}
+		published = append(published, p)
+		items = append(items, database.GetPostsForFeedRow{
+			ID:          p.ID,
+			Title:       title,
+			Url:         p.Url,
+			Description: p.Description,
+			Author:      p.Author,
+			PublishedAt: p.PublishedAt,
+			Flagged:     st.Flagged,
+		})
+	}
+	sortNewest(items, published)
+	if len(items) > int(arg.Limit) {
+		items = items[:arg.Limit]
+	}
+	return items, nil
+}
+
+// sortNewest sorts rows by the publish date of the matching post, newest first
+func sortNewest[T any](rows []T, posts []database.Post) {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return posts[b].PublishedAt.Compare(posts[a].PublishedAt) })
+	sorted := make([]T, len(rows))
+	for i, j := range order {
+		sorted[i] = rows[j]
+	}
+	copy(rows, sorted)
+}
+
+func (s *Store) GetDuplicateCandidates(ctx context.Context, arg database.GetDuplicateCandidatesParams) ([]database.GetDuplicateCandidatesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var posts []database.Post
+	for _, p := range s.posts {
+		if p.FeedID != arg.FeedID && (p.CanonicalUrl == arg.CanonicalUrl || p.CreatedAt.After(arg.CreatedAt)) {
+			posts = append(posts, p)
+		}
+	}
+	slices.SortStableFunc(posts, func(a, b database.Post) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	var items []database.GetDuplicateCandidatesRow
+	for _, p := range posts[:min(len(posts), 500)] {
+		items = append(items, database.GetDuplicateCandidatesRow{
+			ID:           p.ID,
+			Title:        p.Title,
+			CanonicalUrl: p.CanonicalUrl,
+			Simhash:      p.Simhash,
+			ClusterID:    p.ClusterID,
+		})
+	}
+	return items, nil
+}
+
+// prunable returns the posts of a feed the retention limits allow deleting, newest first
+func (s *Store) prunable(arg database.GetPrunablePostsParams) []database.Post {
+	var ranked []database.Post
+	for _, p := range s.posts {
+		if p.FeedID == arg.FeedID {
+			ranked = append(ranked, p)
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b database.Post) int {
+		return cmp.Or(b.PublishedAt.Compare(a.PublishedAt), b.CreatedAt.Compare(a.CreatedAt))
+	})
+	var posts []database.Post
+	for i, p := range ranked {
+		position := int64(i + 1)
+		if position > arg.KeepNewest && p.CreatedAt.Before(arg.MinAgeCutoff) &&
+			(p.PublishedAt.Before(arg.MaxAgeCutoff) || position > arg.MaxPosts) {
+			posts = append(posts, p)
+		}
+	}
+	return posts
+}
+
+func (s *Store) GetPrunablePosts(ctx context.Context, arg database.GetPrunablePostsParams) ([]database.GetPrunablePostsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetPrunablePostsRow
+	for _, p := range s.prunable(arg) {
+		items = append(items, database.GetPrunablePostsRow{ID: p.ID, Title: p.Title, PublishedAt: p.PublishedAt})
+	}
+	return items, nil
+}
+
+func (s *Store) DeletePrunablePosts(ctx context.Context, arg database.DeletePrunablePostsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gone := make(map[uuid.UUID]bool)
+	for _, p := range s.prunable(database.GetPrunablePostsParams(arg)) {
+		gone[p.ID] = true
+	}
+	return s.deletePosts(func(p database.Post) bool { return gone[p.ID] }), nil
+}
+
+// UpsertPostState saves the user's state for a post, once deleted a post stays deleted
+func (s *Store) UpsertPostState(ctx context.Context, arg database.UpsertPostStateParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := database.UserPostState(arg)
+	i := find(s.states, func(st database.UserPostState) bool { return st.UserID == arg.UserID && st.PostID == arg.PostID })
+	if i < 0 {
+		s.states = append(s.states, st)
+		return nil
+	}
+	st.Deleted = s.states[i].Deleted || st.Deleted
+	s.states[i] = st
+	return nil
+}
+
+func (s *Store) ResetPostStatesForUser(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.states {
+		if st := &s.states[i]; st.UserID == userID {
+			st.UpdatedAt = now()
+			st.Hidden = false
+			st.Flagged = false
+			st.Title = sql.NullString{}
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetAllPosts(ctx context.Context) ([]database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := slices.Clone(s.posts)
+	slices.SortFunc(items, func(a, b database.Post) int { return compareCreated(a.CreatedAt, a.ID, b.CreatedAt, b.ID) })
+	return items, nil
+}
+
+func (s *Store) GetAllPostStates(ctx context.Context) ([]database.UserPostState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.states), nil
+}
+
+// RestorePostState keeps a state the user already has for the post like ON CONFLICT DO NOTHING
+func (s *Store) RestorePostState(ctx context.Context, arg database.RestorePostStateParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.states, func(st database.UserPostState) bool { return st.UserID == arg.UserID && st.PostID == arg.PostID }) >= 0 {
+		return nil
+	}
+	s.states = append(s.states, database.UserPostState(arg))
+	return nil
+}
+
+func (s *Store) CountPosts(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.posts)), nil
+}
+
+func (s *Store) CountPostsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, p := range s.posts {
+		if p.FeedID == feedID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) CountPostsForFeedUrl(ctx context.Context, arg database.CountPostsForFeedUrlParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, p := range s.posts {
+		if p.FeedID == arg.FeedID && p.Url == arg.Url {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) DeleteAllPosts(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deletePosts(func(database.Post) bool { return true }), nil
+}
+
+func (s *Store) DeletePostsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deletePosts(func(p database.Post) bool { return p.FeedID == feedID }), nil
+}
+
+func (s *Store) CountPostStatesForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, st := range s.states {
+		if st.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) DeletePostStatesForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.states)
+	s.states = slices.DeleteFunc(s.states, func(st database.UserPostState) bool { return st.UserID == userID })
+	return int64(before - len(s.states)), nil
+}
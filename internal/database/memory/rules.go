@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+func (s *Store) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.rules, func(r database.Rule) bool { return r.ID == arg.ID }) >= 0 {
+		return database.Rule{}, unique("rules_pkey")
+	}
+	if _, ok := s.user(arg.UserID); !ok {
+		return database.Rule{}, errForeignKey
+	}
+	r := database.Rule(arg)
+	s.rules = append(s.rules, r)
+	return r, nil
+}
+
+func (s *Store) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.Rule
+	for _, r := range s.rules {
+		if r.UserID == userID {
+			items = append(items, r)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b database.Rule) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return items, nil
+}
+
+func (s *Store) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = slices.DeleteFunc(s.rules, func(r database.Rule) bool { return r.ID == id })
+	return nil
+}
+
+// GetRulesForFeed returns the rules of every user following the feed, named as each user follows it
+func (s *Store) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetRulesForFeedRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.GetRulesForFeedRow
+	for _, f := range s.follows {
+		if f.FeedID != feedID {
+			continue
+		}
+		for _, r := range s.rules {
+			if r.UserID != f.UserID {
+				continue
+			}
+			items = append(items, database.GetRulesForFeedRow{
+				ID:            r.ID,
+				CreatedAt:     r.CreatedAt,
+				UpdatedAt:     r.UpdatedAt,
+				UserID:        r.UserID,
+				Field:         r.Field,
+				MatchType:     r.MatchType,
+				Pattern:       r.Pattern,
+				CaseSensitive: r.CaseSensitive,
+				Action:        r.Action,
+				Replacement:   r.Replacement,
+				FeedName:      s.followName(f),
+			})
+		}
+	}
+	slices.SortStableFunc(items, func(a, b database.GetRulesForFeedRow) int {
+		return cmp.Or(compareIDs(a.UserID, b.UserID), a.CreatedAt.Compare(b.CreatedAt))
+	})
+	return items, nil
+}
+
+func (s *Store) GetAllRules(ctx context.Context) ([]database.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := slices.Clone(s.rules)
+	slices.SortFunc(items, func(a, b database.Rule) int { return compareCreated(a.CreatedAt, a.ID, b.CreatedAt, b.ID) })
+	return items, nil
+}
+
+func (s *Store) DeleteRulesForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.rules)
+	s.rules = slices.DeleteFunc(s.rules, func(r database.Rule) bool { return r.UserID == userID })
+	return int64(before - len(s.rules)), nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.users, func(u database.User) bool { return u.Name == arg.Name || u.ID == arg.ID }) >= 0 {
+		return database.User{}, unique("users_name_key")
+	}
+	u := database.User(arg)
+	s.users = append(s.users, u)
+	return u, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, name string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return get(s.users, func(u database.User) bool { return u.Name == name })
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []database.User
+	return append(items, s.users...), nil
+}
+
+// DeleteAllUsers deletes every user and cascades like DeleteUser, feeds and their posts are kept without a user
+func (s *Store) DeleteAllUsers(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range slices.Clone(s.users) {
+		s.deleteUser(u.ID)
+	}
+	return nil
+}
+
+func (s *Store) SetUserAdmin(ctx context.Context, arg database.SetUserAdminParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := find(s.users, func(u database.User) bool { return u.ID == arg.ID })
+	if i < 0 {
+		return database.User{}, sql.ErrNoRows
+	}
+	s.users[i].IsAdmin = arg.IsAdmin
+	s.users[i].UpdatedAt = now()
+	return s.users[i], nil
+}
+
+func (s *Store) RenameUser(ctx context.Context, arg database.RenameUserParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if find(s.users, func(u database.User) bool { return u.Name == arg.Name && u.ID != arg.ID }) >= 0 {
+		return database.User{}, unique("users_name_key")
+	}
+	i := find(s.users, func(u database.User) bool { return u.ID == arg.ID })
+	if i < 0 {
+		return database.User{}, sql.ErrNoRows
+	}
+	s.users[i].Name = arg.Name
+	s.users[i].UpdatedAt = now()
+	return s.users[i], nil
+}
+
+// DeleteUser deletes a user and cascades like the schema, the feeds they added are kept for their followers
+func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteUser(id)
+	return nil
+}
+
+func (s *Store) deleteUser(id uuid.UUID) {
+	for i := range s.feeds {
+		if f := &s.feeds[i]; f.AddedBy.Valid && f.AddedBy.UUID == id {
+			f.AddedBy = uuid.NullUUID{}
+		}
+	}
+	s.deleteFollows(func(f database.FeedFollow) bool { return f.UserID == id })
+	s.feedHandles = slices.DeleteFunc(s.feedHandles, func(h database.FeedHandle) bool { return h.UserID == id })
+
+	categories := make(map[uuid.UUID]bool)
+	s.categories = slices.DeleteFunc(s.categories, func(c database.Category) bool {
+		if c.UserID == id {
+			categories[c.ID] = true
+		}
+		return categories[c.ID]
+	})
+	s.followCategories = slices.DeleteFunc(s.followCategories, func(fc database.FeedFollowCategory) bool { return categories[fc.CategoryID] })
+	s.rules = slices.DeleteFunc(s.rules, func(r database.Rule) bool { return r.UserID == id })
+	s.states = slices.DeleteFunc(s.states, func(st database.UserPostState) bool { return st.UserID == id })
+	s.counters = slices.DeleteFunc(s.counters, func(c database.HandleCounter) bool { return c.UserID == id })
+	s.postHandles = slices.DeleteFunc(s.postHandles, func(h database.PostHandle) bool { return h.UserID == id })
+	s.users = slices.DeleteFunc(s.users, func(u database.User) bool { return u.ID == id })
+}
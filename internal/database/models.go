@@ -11,33 +11,88 @@ import (
 	"github.com/google/uuid"
 )
 
-type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
-}
-
-type FeedFollow struct {
+type Category struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Name      string
 	UserID    uuid.UUID
-	FeedID    uuid.UUID
 }
 
-type Post struct {
+type Feed struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Name              string
+	Url               string
+	AddedBy           uuid.NullUUID
+	LastFetchedAt     sql.NullTime
+	RetentionMaxAge   sql.NullString
+	RetentionMaxPosts sql.NullInt32
+}
+
+type FeedFollow struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
+	UserID      uuid.UUID
 	FeedID      uuid.UUID
+	DisplayName sql.NullString
+	Priority    int32
+	Muted       bool
+	Notify      bool
+}
+
+type FeedFollowCategory struct {
+	FeedFollowID uuid.UUID
+	CategoryID   uuid.UUID
+}
+
+type FeedHandle struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+	Handle int32
+}
+
+type HandleCounter struct {
+	UserID     uuid.UUID
+	Kind       string
+	LastHandle int32
+}
+
+type Post struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	Author       sql.NullString
+	Category     sql.NullString
+	CanonicalUrl string
+	Simhash      sql.NullInt64
+	ClusterID    uuid.UUID
+}
+
+type PostHandle struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	Handle int32
+}
+
+type Rule struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Field         string
+	MatchType     string
+	Pattern       string
+	CaseSensitive bool
+	Action        string
+	Replacement   sql.NullString
 }
 
 type User struct {
@@ -45,4 +100,15 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	IsAdmin   bool
+}
+
+type UserPostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	UpdatedAt time.Time
+	Hidden    bool
+	Deleted   bool
+	Flagged   bool
+	Title     sql.NullString
 }
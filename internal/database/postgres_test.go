@@ -0,0 +1,48 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/database/databasetest"
+	"github.com/joshhartwig/gator/internal/migrate"
+	"github.com/joshhartwig/gator/sql/schema"
+
+	_ "github.com/lib/pq"
+)
+
+// TestConformance runs against the postgres database in GATOR_TEST_POSTGRES_URL, its tables are emptied between tests
+func TestConformance(t *testing.T) {
+	url := os.Getenv("GATOR_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("set GATOR_TEST_POSTGRES_URL to run the conformance suite against postgres")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrations, err := migrate.Load(schema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.New(db, migrate.Postgres, migrations).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	databasetest.Run(t, func(t *testing.T) database.Store {
+		q := database.New(db)
+		// every other table hangs off users or feeds and is emptied by the cascade, feeds outlive the users who added them
+		if err := q.DeleteAllUsers(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := q.DeleteAllFeeds(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return q
+	})
+}
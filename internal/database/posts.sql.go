@@ -11,10 +11,54 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countPosts = `-- name: CountPosts :one
+SELECT COUNT(*)
+FROM posts
+`
+
+func (q *Queries) CountPosts(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPosts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPostsForFeed = `-- name: CountPostsForFeed :one
+SELECT COUNT(*)
+FROM posts
+WHERE feed_id = $1
+`
+
+func (q *Queries) CountPostsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForFeed, feedID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPostsForFeedUrl = `-- name: CountPostsForFeedUrl :one
+SELECT COUNT(*)
+FROM posts
+WHERE feed_id = $1 AND url = $2
+`
+
+type CountPostsForFeedUrlParams struct {
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) CountPostsForFeedUrl(ctx context.Context, arg CountPostsForFeedUrlParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForFeedUrl, arg.FeedID, arg.Url)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, category, canonical_url, simhash, cluster_id)
 VALUES (
     $1,
     $2,
@@ -23,20 +67,31 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+ON CONFLICT (feed_id, url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, category, canonical_url, simhash, cluster_id
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	Author       sql.NullString
+	Category     sql.NullString
+	CanonicalUrl string
+	Simhash      sql.NullInt64
+	ClusterID    uuid.UUID
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -49,6 +104,11 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.Category,
+		arg.CanonicalUrl,
+		arg.Simhash,
+		arg.ClusterID,
 	)
 	var i Post
 	err := row.Scan(
@@ -60,49 +120,445 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.Category,
+		&i.CanonicalUrl,
+		&i.Simhash,
+		&i.ClusterID,
 	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
+const deleteAllPosts = `-- name: DeleteAllPosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) DeleteAllPosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllPosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePostsForFeed = `-- name: DeletePostsForFeed :execrows
+DELETE FROM posts
+WHERE feed_id = $1
+`
+
+func (q *Queries) DeletePostsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostsForFeed, feedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePrunablePosts = `-- name: DeletePrunablePosts :execrows
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.published_at,
+    ROW_NUMBER() OVER (ORDER BY posts.published_at DESC, posts.created_at DESC) AS position
+  FROM posts
+  WHERE posts.feed_id = $1
+)
+DELETE FROM posts
+USING ranked
+WHERE posts.id = ranked.id
+  AND ranked.position > $2::bigint
+  AND ranked.created_at < $3::timestamp
+  AND (
+    ranked.published_at < $4::timestamp
+    OR ranked.position > $5::bigint
+  )
+`
+
+type DeletePrunablePostsParams struct {
+	FeedID       uuid.UUID
+	KeepNewest   int64
+	MinAgeCutoff time.Time
+	MaxAgeCutoff time.Time
+	MaxPosts     int64
+}
+
+func (q *Queries) DeletePrunablePosts(ctx context.Context, arg DeletePrunablePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePrunablePosts,
+		arg.FeedID,
+		arg.KeepNewest,
+		arg.MinAgeCutoff,
+		arg.MaxAgeCutoff,
+		arg.MaxPosts,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllPosts = `-- name: GetAllPosts :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, category, canonical_url, simhash, cluster_id
+FROM posts
+ORDER BY created_at, id
+`
+
+func (q *Queries) GetAllPosts(ctx context.Context) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPosts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			&i.Category,
+			&i.CanonicalUrl,
+			&i.Simhash,
+			&i.ClusterID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllPostsForUser = `-- name: GetAllPostsForUser :many
 SELECT
   posts.id,
-  posts.created_at,
-  posts.updated_at,
   posts.title,
   posts.url,
   posts.description,
-  posts.published_at,
-  feeds.user_id as feed_user_id
+  posts.author,
+  posts.category,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name
 FROM
   posts
   INNER JOIN feeds ON feeds.id = posts.feed_id
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
 WHERE
-  feeds.user_id = $1
+  feed_follows.user_id = $1
 ORDER BY
-  posts.updated_at ASC
+  posts.published_at DESC
+`
+
+type GetAllPostsForUserRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description sql.NullString
+	Author      sql.NullString
+	Category    sql.NullString
+	FeedName    string
+}
+
+func (q *Queries) GetAllPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetAllPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllPostsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetAllPostsForUserRow
+	for rows.Next() {
+		var i GetAllPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Author,
+			&i.Category,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDuplicateCandidates = `-- name: GetDuplicateCandidates :many
+SELECT id, title, canonical_url, simhash, cluster_id
+FROM posts
+WHERE feed_id <> $1
+  AND (canonical_url = $2 OR created_at > $3)
+ORDER BY created_at DESC
+LIMIT 500
+`
+
+type GetDuplicateCandidatesParams struct {
+	FeedID       uuid.UUID
+	CanonicalUrl string
+	CreatedAt    time.Time
+}
+
+type GetDuplicateCandidatesRow struct {
+	ID           uuid.UUID
+	Title        string
+	CanonicalUrl string
+	Simhash      sql.NullInt64
+	ClusterID    uuid.UUID
+}
+
+func (q *Queries) GetDuplicateCandidates(ctx context.Context, arg GetDuplicateCandidatesParams) ([]GetDuplicateCandidatesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDuplicateCandidates, arg.FeedID, arg.CanonicalUrl, arg.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDuplicateCandidatesRow
+	for rows.Next() {
+		var i GetDuplicateCandidatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.CanonicalUrl,
+			&i.Simhash,
+			&i.ClusterID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLatestPostForUser = `-- name: GetLatestPostForUser :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.category, posts.canonical_url, posts.simhash, posts.cluster_id
+FROM
+  posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+    AND user_post_states.user_id = feed_follows.user_id
+WHERE
+  feed_follows.user_id = $1
+  AND NOT feed_follows.muted
+  AND NOT COALESCE(user_post_states.hidden, FALSE)
+  AND NOT COALESCE(user_post_states.deleted, FALSE)
+ORDER BY
+  posts.published_at DESC
 LIMIT
-  $2
+  1
 `
 
-type GetPostsForUserParams struct {
+func (q *Queries) GetLatestPostForUser(ctx context.Context, userID uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getLatestPostForUser, userID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Category,
+		&i.CanonicalUrl,
+		&i.Simhash,
+		&i.ClusterID,
+	)
+	return i, err
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, category, canonical_url, simhash, cluster_id
+FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Category,
+		&i.CanonicalUrl,
+		&i.Simhash,
+		&i.ClusterID,
+	)
+	return i, err
+}
+
+const getPostsForFeed = `-- name: GetPostsForFeed :many
+SELECT
+  posts.id,
+  COALESCE(user_post_states.title, posts.title) as title,
+  posts.url,
+  posts.description,
+  posts.author,
+  posts.published_at,
+  COALESCE(user_post_states.flagged, FALSE) as flagged
+FROM
+  posts
+  LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+    AND user_post_states.user_id = $1
+WHERE
+  posts.feed_id = $2
+  AND NOT COALESCE(user_post_states.hidden, FALSE)
+  AND NOT COALESCE(user_post_states.deleted, FALSE)
+ORDER BY
+  posts.published_at DESC
+LIMIT
+  $3
+`
+
+type GetPostsForFeedParams struct {
 	UserID uuid.UUID
+	FeedID uuid.UUID
 	Limit  int32
 }
 
-type GetPostsForUserRow struct {
+type GetPostsForFeedRow struct {
 	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
 	Title       string
 	Url         string
 	Description sql.NullString
+	Author      sql.NullString
 	PublishedAt time.Time
-	FeedUserID  uuid.UUID
+	Flagged     bool
+}
+
+func (q *Queries) GetPostsForFeed(ctx context.Context, arg GetPostsForFeedParams) ([]GetPostsForFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForFeed, arg.UserID, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForFeedRow
+	for rows.Next() {
+		var i GetPostsForFeedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.Author,
+			&i.PublishedAt,
+			&i.Flagged,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+WITH visible AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    COALESCE(user_post_states.title, posts.title) as title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    COALESCE(feed_follows.display_name, feeds.name) as feed_name,
+    COALESCE(user_post_states.flagged, FALSE) as flagged,
+    ROW_NUMBER() OVER (PARTITION BY posts.cluster_id ORDER BY posts.created_at, posts.id) as cluster_position,
+    ARRAY_AGG(COALESCE(feed_follows.display_name, feeds.name)) OVER (PARTITION BY posts.cluster_id) as cluster_feeds
+  FROM
+    posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+    INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+      AND user_post_states.user_id = feed_follows.user_id
+  WHERE
+    feed_follows.user_id = $1
+    AND NOT feed_follows.muted
+    AND NOT COALESCE(user_post_states.hidden, FALSE)
+    AND NOT COALESCE(user_post_states.deleted, FALSE)
+)
+SELECT
+  id,
+  created_at,
+  updated_at,
+  title,
+  url,
+  description,
+  published_at,
+  feed_name,
+  flagged,
+  cluster_feeds
+FROM visible
+WHERE cluster_position = 1
+  AND (
+    $3::uuid IS NULL
+    OR (updated_at, id) > (SELECT posts.updated_at, posts.id FROM posts WHERE posts.id = $3::uuid)
+  )
+ORDER BY
+  updated_at ASC,
+  id ASC
+LIMIT
+  $2
+`
+
+type GetPostsForUserParams struct {
+	UserID uuid.UUID
+	Limit  int32
+	After  uuid.NullUUID
+}
+
+type GetPostsForUserRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedName     string
+	Flagged      bool
+	ClusterFeeds []string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit, arg.After)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +574,120 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Url,
 			&i.Description,
 			&i.PublishedAt,
-			&i.FeedUserID,
+			&i.FeedName,
+			&i.Flagged,
+			pq.Array(&i.ClusterFeeds),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUserInCategory = `-- name: GetPostsForUserInCategory :many
+WITH visible AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    COALESCE(user_post_states.title, posts.title) as title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    COALESCE(feed_follows.display_name, feeds.name) as feed_name,
+    COALESCE(user_post_states.flagged, FALSE) as flagged,
+    ROW_NUMBER() OVER (PARTITION BY posts.cluster_id ORDER BY posts.created_at, posts.id) as cluster_position,
+    ARRAY_AGG(COALESCE(feed_follows.display_name, feeds.name)) OVER (PARTITION BY posts.cluster_id) as cluster_feeds
+  FROM
+    posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+    INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    INNER JOIN feed_follow_categories ON feed_follow_categories.feed_follow_id = feed_follows.id
+    LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+      AND user_post_states.user_id = feed_follows.user_id
+  WHERE
+    feed_follows.user_id = $1
+    AND feed_follow_categories.category_id = $2
+    AND NOT feed_follows.muted
+    AND NOT COALESCE(user_post_states.hidden, FALSE)
+    AND NOT COALESCE(user_post_states.deleted, FALSE)
+)
+SELECT
+  id,
+  created_at,
+  updated_at,
+  title,
+  url,
+  description,
+  published_at,
+  feed_name,
+  flagged,
+  cluster_feeds
+FROM visible
+WHERE cluster_position = 1
+  AND (
+    $4::uuid IS NULL
+    OR (updated_at, id) > (SELECT posts.updated_at, posts.id FROM posts WHERE posts.id = $4::uuid)
+  )
+ORDER BY
+  updated_at ASC,
+  id ASC
+LIMIT
+  $3
+`
+
+type GetPostsForUserInCategoryParams struct {
+	UserID     uuid.UUID
+	CategoryID uuid.UUID
+	Limit      int32
+	After      uuid.NullUUID
+}
+
+type GetPostsForUserInCategoryRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedName     string
+	Flagged      bool
+	ClusterFeeds []string
+}
+
+func (q *Queries) GetPostsForUserInCategory(ctx context.Context, arg GetPostsForUserInCategoryParams) ([]GetPostsForUserInCategoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUserInCategory,
+		arg.UserID,
+		arg.CategoryID,
+		arg.Limit,
+		arg.After,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserInCategoryRow
+	for rows.Next() {
+		var i GetPostsForUserInCategoryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Flagged,
+			pq.Array(&i.ClusterFeeds),
 		); err != nil {
 			return nil, err
 		}
@@ -132,3 +701,68 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.title,
+    posts.created_at,
+    posts.published_at,
+    ROW_NUMBER() OVER (ORDER BY posts.published_at DESC, posts.created_at DESC) AS position
+  FROM posts
+  WHERE posts.feed_id = $1
+)
+SELECT id, title, published_at
+FROM ranked
+WHERE position > $2::bigint
+  AND created_at < $3::timestamp
+  AND (
+    published_at < $4::timestamp
+    OR position > $5::bigint
+  )
+ORDER BY published_at DESC
+`
+
+type GetPrunablePostsParams struct {
+	FeedID       uuid.UUID
+	KeepNewest   int64
+	MinAgeCutoff time.Time
+	MaxAgeCutoff time.Time
+	MaxPosts     int64
+}
+
+type GetPrunablePostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	PublishedAt time.Time
+}
+
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts,
+		arg.FeedID,
+		arg.KeepNewest,
+		arg.MinAgeCutoff,
+		arg.MaxAgeCutoff,
+		arg.MaxPosts,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrunablePostsRow
+	for rows.Next() {
+		var i GetPrunablePostsRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.PublishedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
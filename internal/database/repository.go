@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrUniqueViolation is returned by stores that are not backed by sql when a row breaks a unique constraint
+var ErrUniqueViolation = errors.New("duplicate key value violates unique constraint")
+
+// UserRepository stores the users of gator
+type UserRepository interface {
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	GetUser(ctx context.Context, name string) (User, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	DeleteAllUsers(ctx context.Context) error
+	SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (User, error)
+	RenameUser(ctx context.Context, arg RenameUserParams) (User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+}
+
+// FeedRepository stores the feeds, when they were fetched and each user's handles for them
+type FeedRepository interface {
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	GetFeed(ctx context.Context, id uuid.UUID) (Feed, error)
+	GetFeedByUrl(ctx context.Context, url string) (Feed, error)
+	GetFeeds(ctx context.Context) ([]GetFeedsRow, error)
+	GetAllFeeds(ctx context.Context) ([]Feed, error)
+	GetNextFeedToFetch(ctx context.Context) (Feed, error)
+	MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, error)
+	SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error)
+	AssignFeedHandles(ctx context.Context, arg AssignFeedHandlesParams) error
+	GetFeedHandles(ctx context.Context, arg GetFeedHandlesParams) ([]GetFeedHandlesRow, error)
+	GetFeedByHandle(ctx context.Context, arg GetFeedByHandleParams) (Feed, error)
+	RestoreFeed(ctx context.Context, arg RestoreFeedParams) (Feed, error)
+	MarkFeedUnfetched(ctx context.Context, id uuid.UUID) error
+	MarkAllFeedsUnfetched(ctx context.Context) error
+	TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error)
+	GetUnfollowedFeeds(ctx context.Context) ([]Feed, error)
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	DeleteAllFeeds(ctx context.Context) error
+	RenameFeed(ctx context.Context, arg RenameFeedParams) (Feed, error)
+	SetFeedUrl(ctx context.Context, arg SetFeedUrlParams) (Feed, error)
+}
+
+// FollowRepository stores which users follow which feeds and their settings for each
+type FollowRepository interface {
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	GetFeedFollows(ctx context.Context) ([]GetFeedFollowsRow, error)
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+	GetFeedFollowsForUserInCategory(ctx context.Context, arg GetFeedFollowsForUserInCategoryParams) ([]GetFeedFollowsForUserInCategoryRow, error)
+	GetNotifiedFollowersForFeed(ctx context.Context, feedID uuid.UUID) ([]GetNotifiedFollowersForFeedRow, error)
+	UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (FeedFollow, error)
+	DeleteFeedFollowForUser(ctx context.Context, arg DeleteFeedFollowForUserParams) error
+	GetAllFeedFollows(ctx context.Context) ([]FeedFollow, error)
+	RestoreFeedFollow(ctx context.Context, arg RestoreFeedFollowParams) (FeedFollow, error)
+	DeleteFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteFeedFollowsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error)
+	CountFeedFollowsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error)
+}
+
+// PostRepository stores the posts fetched from the feeds, each user's state for them and their handles
+type PostRepository interface {
+	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	GetPost(ctx context.Context, id uuid.UUID) (Post, error)
+	GetLatestPostForUser(ctx context.Context, userID uuid.UUID) (Post, error)
+	GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error)
+	GetPostsForUserInCategory(ctx context.Context, arg GetPostsForUserInCategoryParams) ([]GetPostsForUserInCategoryRow, error)
+	GetAllPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetAllPostsForUserRow, error)
+	GetPostsForFeed(ctx context.Context, arg GetPostsForFeedParams) ([]GetPostsForFeedRow, error)
+	GetDuplicateCandidates(ctx context.Context, arg GetDuplicateCandidatesParams) ([]GetDuplicateCandidatesRow, error)
+	GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error)
+	DeletePrunablePosts(ctx context.Context, arg DeletePrunablePostsParams) (int64, error)
+	UpsertPostState(ctx context.Context, arg UpsertPostStateParams) error
+	ResetPostStatesForUser(ctx context.Context, userID uuid.UUID) error
+	AssignPostHandles(ctx context.Context, arg AssignPostHandlesParams) error
+	GetPostHandles(ctx context.Context, arg GetPostHandlesParams) ([]GetPostHandlesRow, error)
+	GetPostByHandle(ctx context.Context, arg GetPostByHandleParams) (Post, error)
+	GetAllPosts(ctx context.Context) ([]Post, error)
+	GetAllPostStates(ctx context.Context) ([]UserPostState, error)
+	RestorePostState(ctx context.Context, arg RestorePostStateParams) error
+	CountPosts(ctx context.Context) (int64, error)
+	CountPostsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error)
+	CountPostsForFeedUrl(ctx context.Context, arg CountPostsForFeedUrlParams) (int64, error)
+	DeleteAllPosts(ctx context.Context) (int64, error)
+	DeletePostsForFeed(ctx context.Context, feedID uuid.UUID) (int64, error)
+	CountPostStatesForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeletePostStatesForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// CategoryRepository stores the categories users sort their follows into
+type CategoryRepository interface {
+	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
+	GetCategoriesForUser(ctx context.Context, userID uuid.UUID) ([]Category, error)
+	GetCategoryFeedCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetCategoryFeedCountsForUserRow, error)
+	GetCategoryByName(ctx context.Context, arg GetCategoryByNameParams) (Category, error)
+	RenameCategory(ctx context.Context, arg RenameCategoryParams) (Category, error)
+	DeleteCategory(ctx context.Context, id uuid.UUID) error
+	AddFeedFollowToCategory(ctx context.Context, arg AddFeedFollowToCategoryParams) error
+	RemoveFeedFollowFromCategory(ctx context.Context, arg RemoveFeedFollowFromCategoryParams) error
+	GetFeedFollowCategoriesForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowCategoriesForUserRow, error)
+	GetAllCategories(ctx context.Context) ([]Category, error)
+	GetAllFeedFollowCategories(ctx context.Context) ([]FeedFollowCategory, error)
+	DeleteCategoriesForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// RuleRepository stores the rules agg applies to new posts
+type RuleRepository interface {
+	CreateRule(ctx context.Context, arg CreateRuleParams) (Rule, error)
+	GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]Rule, error)
+	GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]GetRulesForFeedRow, error)
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+	GetAllRules(ctx context.Context) ([]Rule, error)
+	DeleteRulesForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// Store is everything the handlers need from storage. The sqlc Queries implement it for postgres and sqlite,
+// the memory package implements it for tests.
+type Store interface {
+	UserRepository
+	FeedRepository
+	FollowRepository
+	PostRepository
+	CategoryRepository
+	RuleRepository
+}
+
+// UnitOfWork is implemented by stores that are not backed by sql. InTx runs fn as a single unit,
+// everything fn writes through q is kept when it returns nil and undone when it returns an error.
+type UnitOfWork interface {
+	InTx(ctx context.Context, fn func(q Store) error) error
+}
+
+var _ Store = (*Queries)(nil)
+
+// every generated query belongs to one of the repositories, this fails to build when a new one is left out
+var _ Querier = Store(nil)
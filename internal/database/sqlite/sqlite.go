@@ -0,0 +1,137 @@
+// Package sqlite runs the sqlc generated queries against sqlite. Most of the queries are portable, the ones
+// using postgres only syntax have a variant in sql/sqlite/queries with the same name that is run instead.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/sql/sqlite/queries"
+	"github.com/lib/pq"
+	modernc "modernc.org/sqlite"
+)
+
+// timeFormat is how times are written, every time is stored in UTC so they compare correctly as text
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// queryName matches the '-- name: GetUser :one' line sqlc starts each query with
+var queryName = regexp.MustCompile(`^-- name: (\w+) `)
+
+// variants are the sqlite versions of the queries keyed by name
+var variants = mustLoad(queries.FS)
+
+func init() {
+	// NOW() is used by the generated queries, sqlite has no such function
+	modernc.MustRegisterScalarFunction("now", 0, func(*modernc.FunctionContext, []driver.Value) (driver.Value, error) {
+		return time.Now().UTC().Format(timeFormat), nil
+	})
+}
+
+// Open opens the sqlite database at path, creating it when missing. Foreign keys are switched on
+// and writers wait on each other rather than failing when the database is locked. Transactions take
+// the write lock when they begin, a transaction that reads first could not wait for it later.
+func Open(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?" + url.Values{
+		"_time_format": {"sqlite"},
+		"_timezone":    {"UTC"},
+		"_txlock":      {"immediate"},
+		"_pragma":      {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"},
+	}.Encode()
+	return sql.Open("sqlite", dsn)
+}
+
+// DB runs queries on a *sql.DB or *sql.Tx, pass it to database.New
+type DB struct {
+	db database.DBTX
+}
+
+func New(db database.DBTX) *DB {
+	return &DB{db: db}
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.db.ExecContext(ctx, rewrite(query), convert(args)...)
+}
+
+func (d *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.db.PrepareContext(ctx, rewrite(query))
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, rewrite(query), convert(args)...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.db.QueryRowContext(ctx, rewrite(query), convert(args)...)
+}
+
+// rewrite swaps a generated query for its sqlite variant when it has one
+func rewrite(query string) string {
+	m := queryName.FindStringSubmatch(query)
+	if m == nil {
+		return query
+	}
+	if v, ok := variants[m[1]]; ok {
+		return v
+	}
+	return query
+}
+
+// convert passes arrays as json for json_each, sqlite has no array type
+func convert(args []any) []any {
+	out := make([]any, len(args))
+	for i, a := range args {
+		var v any
+		switch a := a.(type) {
+		case pq.GenericArray:
+			v = a.A
+		case *pq.StringArray:
+			v = []string(*a)
+		case *pq.Int64Array:
+			v = []int64(*a)
+		default:
+			out[i] = a
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			// leave it to the driver to report the unsupported value
+			out[i] = a
+			continue
+		}
+		out[i] = string(data)
+	}
+	return out
+}
+
+// mustLoad splits the embedded files on their '-- name:' lines, text before the first query is a comment
+func mustLoad(fsys fs.FS) map[string]string {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]string)
+	for _, f := range files {
+		data, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			panic(err)
+		}
+		for _, q := range strings.Split(string(data), "-- name: ")[1:] {
+			name := strings.Fields(q)[0]
+			if _, ok := loaded[name]; ok {
+				panic(fmt.Sprintf("sqlite query %s is defined twice", name))
+			}
+			loaded[name] = "-- name: " + strings.TrimSuffix(strings.TrimSpace(q), ";")
+		}
+	}
+	return loaded
+}
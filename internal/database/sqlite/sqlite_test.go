@@ -0,0 +1,32 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/database/databasetest"
+	"github.com/joshhartwig/gator/internal/database/sqlite"
+	"github.com/joshhartwig/gator/internal/migrate"
+	"github.com/joshhartwig/gator/sql/sqlite/schema"
+)
+
+func TestConformance(t *testing.T) {
+	databasetest.Run(t, func(t *testing.T) database.Store {
+		db, err := sqlite.Open(filepath.Join(t.TempDir(), "gator.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		migrations, err := migrate.Load(schema.FS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrate.New(db, migrate.SQLite, migrations).Up(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return database.New(sqlite.New(db))
+	})
+}
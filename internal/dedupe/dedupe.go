@@ -0,0 +1,180 @@
+package dedupe
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// titles at least this similar are treated as the same story
+	titleThreshold = 0.85
+	// titles shorter than this are too generic to compare
+	minTitleTokens = 4
+	// descriptions need this many words before their simhash is meaningful
+	minSimhashTokens = 20
+	// simhashes within this many differing bits are treated as the same story
+	maxSimhashDistance = 3
+)
+
+// tracking parameters removed from urls, keys ending in * match any suffix
+var trackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid",
+	"ref", "ref_src", "ref_url", "igshid", "_hsenc", "_hsmi", "yclid",
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// Post holds what is needed to compare two posts
+type Post struct {
+	CanonicalURL string
+	Title        string
+	Simhash      uint64
+	HasSimhash   bool
+}
+
+// CanonicalURL normalizes a url so the same article linked with different tracking
+// parameters, fragments, casing or trailing slashes compares equal.
+// Urls that cannot be parsed are returned trimmed but otherwise unchanged.
+func CanonicalURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+	// Encode sorts the keys so parameter order does not matter
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Simhash fingerprints text so near identical texts produce hashes that differ in only a few bits.
+// The second result is false when the text is too short for the hash to be meaningful.
+func Simhash(text string) (uint64, bool) {
+	tokens := tokenize(tagPattern.ReplaceAllString(text, " "))
+	if len(tokens) < minSimhashTokens {
+		return 0, false
+	}
+
+	var weights [64]int
+	for i := 0; i+3 <= len(tokens); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(tokens[i:i+3], " ")))
+		sum := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if sum&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var hash uint64
+	for b := 0; b < 64; b++ {
+		if weights[b] > 0 {
+			hash |= 1 << b
+		}
+	}
+	return hash, true
+}
+
+// TitleSimilarity returns the jaccard similarity of the words in two titles, from 0 to 1
+func TitleSimilarity(a, b string) float64 {
+	setA := tokenSet(a)
+	setB := tokenSet(b)
+	if len(setA) == 0 || len(setB) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range setA {
+		if setB[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+// Duplicate reports whether two posts are most likely the same story
+func Duplicate(a, b Post) bool {
+	if a.CanonicalURL != "" && a.CanonicalURL == b.CanonicalURL {
+		return true
+	}
+	if len(tokenSet(a.Title)) >= minTitleTokens && TitleSimilarity(a.Title, b.Title) >= titleThreshold {
+		return true
+	}
+	if a.HasSimhash && b.HasSimhash && bits.OnesCount64(a.Simhash^b.Simhash) <= maxSimhashDistance {
+		return true
+	}
+	return false
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range trackingParams {
+		if strings.HasSuffix(p, "*") && strings.HasPrefix(key, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+		if key == p {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func tokenSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range tokenize(text) {
+		set[t] = true
+	}
+	return set
+}
+
+// AlsoIn returns the sorted distinct feed names of a cluster other than the post's own feed
+func AlsoIn(feeds []string, own string) []string {
+	seen := map[string]bool{own: true}
+	out := []string{}
+	for _, f := range feeds {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
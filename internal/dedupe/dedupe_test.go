@@ -0,0 +1,83 @@
+package dedupe
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://example.com/post/1", "https://example.com/post/1"},
+		{"http://www.Example.com/post/1/", "https://example.com/post/1"},
+		{"https://example.com/post/1?utm_source=hn&utm_medium=rss", "https://example.com/post/1"},
+		{"https://example.com/post/1?b=2&fbclid=x&a=1#comments", "https://example.com/post/1?a=1&b=2"},
+		{"https://example.com:443/post/1", "https://example.com/post/1"},
+		{"https://example.com:8080/post/1", "https://example.com:8080/post/1"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := CanonicalURL(tt.in); got != tt.want {
+			t.Errorf("CanonicalURL(%q) wanted %q got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	if got := TitleSimilarity("Go 1.25 is released", "Go 1.25 is Released!"); got != 1 {
+		t.Errorf("wanted identical titles to score 1 got %v", got)
+	}
+	if got := TitleSimilarity("Go 1.25 is released", "Rust 2024 edition announced"); got != 0 {
+		t.Errorf("wanted unrelated titles to score 0 got %v", got)
+	}
+	if got := TitleSimilarity("", "anything"); got != 0 {
+		t.Errorf("wanted empty title to score 0 got %v", got)
+	}
+}
+
+func TestSimhash(t *testing.T) {
+	base := strings.Repeat("the quick brown fox jumps over the lazy dog while the cat sleeps ", 4)
+
+	if _, ok := Simhash("too short"); ok {
+		t.Errorf("wanted short text to have no simhash")
+	}
+
+	a, ok := Simhash(base)
+	if !ok {
+		t.Fatalf("wanted a simhash for long text")
+	}
+	b, _ := Simhash("<p>" + base + "</p>")
+	if a != b {
+		t.Errorf("wanted html tags to be ignored")
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Post
+		want bool
+	}{
+		{"same canonical url", Post{CanonicalURL: "https://a.com/1", Title: "One"}, Post{CanonicalURL: "https://a.com/1", Title: "Two"}, true},
+		{"similar titles", Post{CanonicalURL: "https://a.com/1", Title: "Go 1.25 is released today"}, Post{CanonicalURL: "https://b.com/2", Title: "Go 1.25 Is Released Today"}, true},
+		{"short titles", Post{CanonicalURL: "https://a.com/1", Title: "Weekly news"}, Post{CanonicalURL: "https://b.com/2", Title: "Weekly news"}, false},
+		{"close simhash", Post{CanonicalURL: "https://a.com/1", Simhash: 0b1011, HasSimhash: true}, Post{CanonicalURL: "https://b.com/2", Simhash: 0b0010, HasSimhash: true}, true},
+		{"missing simhash", Post{CanonicalURL: "https://a.com/1", Simhash: 1, HasSimhash: true}, Post{CanonicalURL: "https://b.com/2", Simhash: 1}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Duplicate(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: wanted %v got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestAlsoIn(t *testing.T) {
+	got := AlsoIn([]string{"hn", "lobsters", "hn", "reddit"}, "hn")
+	if strings.Join(got, ",") != "lobsters,reddit" {
+		t.Errorf("wanted lobsters,reddit got %v", got)
+	}
+}
@@ -0,0 +1,398 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockKey is the postgres advisory lock held while migrations run so two gator processes never migrate at once
+const lockKey = 4_782_431_906
+
+// files are named <version>_<name>.sql, ex 001_users.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+// the goose annotations are matched case insensitively, older files use "-- +goose up"
+var annotation = regexp.MustCompile(`(?i)^--\s*\+goose\s+(up|down|statementbegin|statementend)\b`)
+
+const recordApplied = `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`
+
+// gooseHistory reads the versions applied by goose, goose adds a row each time a version is applied or reverted
+const gooseHistory = `SELECT version_id, tstamp
+FROM (
+  SELECT DISTINCT ON (version_id) version_id, is_applied, tstamp
+  FROM goose_db_version
+  WHERE version_id > 0
+  ORDER BY version_id, id DESC
+) latest
+WHERE is_applied`
+
+// Migration is one schema change with the sql to apply and revert it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and whether it has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load reads the migrations in fsys sorted by version, files not named like migrations are skipped
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []Migration{}
+	seen := make(map[int64]string)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, e.Name(), version)
+		}
+		seen[version] = e.Name()
+
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", e.Name(), err)
+		}
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    strings.TrimSuffix(path.Base(e.Name()), ".sql"),
+			Up:      up,
+			Down:    down,
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parse splits a goose style file into its up and down sql
+func parse(text string) (string, string, error) {
+	var up, down strings.Builder
+	var section *strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		m := annotation.FindStringSubmatch(strings.TrimSpace(line))
+		switch {
+		case m == nil:
+			if section != nil {
+				section.WriteString(line + "\n")
+			}
+		case strings.EqualFold(m[1], "up"):
+			section = &up
+		case strings.EqualFold(m[1], "down"):
+			section = &down
+		}
+	}
+	if strings.TrimSpace(up.String()) == "" {
+		return "", "", errors.New("missing a '-- +goose Up' section")
+	}
+	return strings.TrimSpace(up.String()), strings.TrimSpace(down.String()), nil
+}
+
+// Dialect is the kind of database being migrated
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	SQLite
+)
+
+// Migrator applies and reverts migrations, the applied versions are kept in schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+func New(db *sql.DB, dialect Dialect, migrations []Migration) *Migrator {
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}
+}
+
+// Status lists every migration with whether it has been applied, it does not change the database
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []Status{}
+	for _, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		statuses = append(statuses, Status{Migration: mig, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet, it does not change the database
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction, and returns the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done := []Migration{}
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, conn, mig.Up, recordApplied, mig.Version, mig.Name, time.Now()); err != nil {
+				return fmt.Errorf("migration %s failed %v", mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the most recently applied migration and returns it
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	var reverted Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		var err error
+		reverted, err = m.down(ctx, conn)
+		return err
+	})
+	return reverted, err
+}
+
+// Redo reverts the most recently applied migration and applies it again
+func (m *Migrator) Redo(ctx context.Context) (Migration, error) {
+	var redone Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		var err error
+		redone, err = m.down(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.run(ctx, conn, redone.Up, recordApplied, redone.Version, redone.Name, time.Now()); err != nil {
+			return fmt.Errorf("migration %s failed %v", redone.Name, err)
+		}
+		return nil
+	})
+	return redone, err
+}
+
+func (m *Migrator) down(ctx context.Context, conn *sql.Conn) (Migration, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return Migration{}, err
+	}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return mig, fmt.Errorf("migration %s can not be reverted, it has no down section", mig.Name)
+		}
+		if err := m.run(ctx, conn, mig.Down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+			return mig, fmt.Errorf("reverting migration %s failed %v", mig.Name, err)
+		}
+		return mig, nil
+	}
+	return Migration{}, errors.New("there are no applied migrations to revert")
+}
+
+// run executes a migration's sql and records it in one transaction. sqlite can only change a foreign key by
+// rebuilding the table, and dropping the old table would cascade into the tables that reference it, so sqlite
+// migrations run with foreign keys off and are checked for broken references before they commit.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration, record string, args ...any) error {
+	if m.dialect == SQLite {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), `PRAGMA foreign_keys = ON`)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	if m.dialect == SQLite {
+		if err := foreignKeyCheck(ctx, tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// foreignKeyCheck fails when a row references one that does not exist
+func foreignKeyCheck(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fkid int64
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		return fmt.Errorf("a row in %s references a missing row in %s", table, parent)
+	}
+	return rows.Err()
+}
+
+// locked runs fn on a single connection holding the advisory lock, schema_migrations is created first if needed.
+// sqlite has no advisory locks, its transactions already allow only one writer at a time.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.dialect == Postgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+			return fmt.Errorf("unable to lock the database for migrations %v", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+	}
+
+	if err := m.createTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// createTable creates schema_migrations. Databases set up with goose have their history copied over
+// so the migrations goose already ran are not run again.
+func (m *Migrator) createTable(ctx context.Context, conn *sql.Conn) error {
+	exists, err := m.tableExists(ctx, conn, "schema_migrations")
+	if err != nil || exists {
+		return err
+	}
+	history, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("unable to create schema_migrations %v", err)
+	}
+	for version, at := range history {
+		name := fmt.Sprintf("%03d_goose", version)
+		for _, mig := range m.migrations {
+			if mig.Version == version {
+				name = mig.Name
+			}
+		}
+		if _, err := tx.ExecContext(ctx, recordApplied, version, name, at); err != nil {
+			return fmt.Errorf("unable to copy the goose history %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// applied returns when each applied version was applied. Before schema_migrations exists the goose history is read,
+// goose was only ever used with postgres.
+func (m *Migrator) applied(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	query := `SELECT version, applied_at FROM schema_migrations`
+	exists, err := m.tableExists(ctx, q, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if m.dialect != Postgres {
+			return map[int64]time.Time{}, nil
+		}
+		goose, err := m.tableExists(ctx, q, "goose_db_version")
+		if err != nil || !goose {
+			return map[int64]time.Time{}, err
+		}
+		query = gooseHistory
+	}
+
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the applied migrations %v", err)
+	}
+	defer rows.Close()
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) tableExists(ctx context.Context, q querier, name string) (bool, error) {
+	query := `SELECT to_regclass($1) IS NOT NULL`
+	if m.dialect == SQLite {
+		query = `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)`
+	}
+	var exists bool
+	err := q.QueryRowContext(ctx, query, name).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("unable to check for table %s %v", name, err)
+	}
+	return exists, nil
+}
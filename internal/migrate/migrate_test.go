@@ -0,0 +1,122 @@
+package migrate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/joshhartwig/gator/internal/database/sqlite"
+	"github.com/joshhartwig/gator/sql/schema"
+	sqliteschema "github.com/joshhartwig/gator/sql/sqlite/schema"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_feeds.sql": {Data: []byte("-- +goose up\nCREATE TABLE feeds (id UUID);\n\n-- +goose Down\nDROP TABLE feeds;\n")},
+		"001_users.sql": {Data: []byte("-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE users (id UUID);\n-- +goose StatementEnd\n")},
+		"README.md":     {Data: []byte("not a migration")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("wanted 2 migrations got %d", len(migrations))
+	}
+
+	users, feeds := migrations[0], migrations[1]
+	if users.Version != 1 || users.Name != "001_users" || users.Up != "CREATE TABLE users (id UUID);" || users.Down != "" {
+		t.Errorf("unexpected users migration %+v", users)
+	}
+	if feeds.Version != 2 || feeds.Up != "CREATE TABLE feeds (id UUID);" || feeds.Down != "DROP TABLE feeds;" {
+		t.Errorf("unexpected feeds migration %+v", feeds)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing up": {"001_users.sql": {Data: []byte("-- +goose Down\nDROP TABLE users;\n")}},
+		"same version": {
+			"001_users.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			"1_feeds.sql":   {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+		},
+	}
+	for name, fsys := range tests {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: wanted an error", name)
+		}
+	}
+}
+
+func TestEmbeddedSchema(t *testing.T) {
+	migrations, err := Load(schema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("wanted the embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("wanted version %d got %s", i+1, m.Name)
+		}
+		if m.Down == "" {
+			t.Errorf("%s has no down section", m.Name)
+		}
+	}
+}
+
+func TestSQLiteSchemaMatches(t *testing.T) {
+	postgres, err := Load(schema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lite, err := Load(sqliteschema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lite) != len(postgres) {
+		t.Fatalf("wanted %d sqlite migrations got %d", len(postgres), len(lite))
+	}
+	for i := range postgres {
+		if lite[i].Name != postgres[i].Name {
+			t.Errorf("wanted sqlite migration %s got %s", postgres[i].Name, lite[i].Name)
+		}
+	}
+}
+
+func TestSQLiteUpDown(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrations, err := Load(sqliteschema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(db, SQLite, migrations)
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("wanted %d migrations applied got %d %v", len(migrations), len(applied), err)
+	}
+	if _, err := m.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for range migrations {
+		if _, err := m.Down(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil || len(pending) != len(migrations) {
+		t.Fatalf("wanted every migration pending got %d %v", len(pending), err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("wanted the migrations to apply again %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Fields a rule condition can look at
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldAuthor      = "author"
+	FieldCategory    = "category"
+	FieldFeed        = "feed"
+	FieldURL         = "url"
+)
+
+// Ways a condition compares its pattern against a field
+const (
+	MatchContains = "contains"
+	MatchEquals   = "equals"
+	MatchRegex    = "regex"
+)
+
+// Actions taken on a post when a rule matches
+const (
+	ActionHide    = "hide"
+	ActionDelete  = "delete"
+	ActionFlag    = "flag"
+	ActionRewrite = "rewrite"
+)
+
+var (
+	Fields  = []string{FieldTitle, FieldDescription, FieldAuthor, FieldCategory, FieldFeed, FieldURL}
+	Matches = []string{MatchContains, MatchEquals, MatchRegex}
+	Actions = []string{ActionHide, ActionDelete, ActionFlag, ActionRewrite}
+)
+
+// Rule is a single condition and the action to take when it matches.
+// Matching folds case unless CaseSensitive is set.
+type Rule struct {
+	Field         string
+	Match         string
+	Pattern       string
+	CaseSensitive bool
+	Action        string
+	Replacement   string
+
+	re *regexp.Regexp
+}
+
+// Post holds the fields of a post a rule can match against
+type Post struct {
+	Title       string
+	Description string
+	Author      string
+	Category    string
+	Feed        string
+	URL         string
+}
+
+// Result is the outcome of running a set of rules over a post
+type Result struct {
+	Hidden  bool
+	Deleted bool
+	Flagged bool
+	// Title is the rewritten title, it is only set when a rewrite rule matched
+	Title   string
+	Matched []int
+}
+
+// Changed reports whether any rule matched
+func (r Result) Changed() bool {
+	return len(r.Matched) > 0
+}
+
+// Compile validates the rule and prepares its pattern, it must be called before Matches
+func (r *Rule) Compile() error {
+	if !slices.Contains(Fields, r.Field) {
+		return fmt.Errorf("unknown field %q, expected one of %s", r.Field, strings.Join(Fields, ", "))
+	}
+	if !slices.Contains(Actions, r.Action) {
+		return fmt.Errorf("unknown action %q, expected one of %s", r.Action, strings.Join(Actions, ", "))
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("the pattern is blank")
+	}
+
+	var expr string
+	switch r.Match {
+	case MatchContains:
+		expr = regexp.QuoteMeta(r.Pattern)
+	case MatchEquals:
+		expr = "^" + regexp.QuoteMeta(r.Pattern) + "$"
+	case MatchRegex:
+		expr = r.Pattern
+	default:
+		return fmt.Errorf("unknown match %q, expected one of %s", r.Match, strings.Join(Matches, ", "))
+	}
+	if !r.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %v", r.Pattern, err)
+	}
+	r.re = re
+	return nil
+}
+
+// Matches reports whether the rule's condition holds for the post
+func (r *Rule) Matches(p Post) bool {
+	if r.re == nil {
+		return false
+	}
+	return r.re.MatchString(r.value(p))
+}
+
+// Evaluate runs every rule over the post in order and combines their actions.
+// A rewrite of the title field only replaces the matched text, any other rewrite replaces the whole title.
+func Evaluate(rules []Rule, p Post) Result {
+	res := Result{}
+	title := p.Title
+	for i := range rules {
+		r := &rules[i]
+		p.Title = title
+		if !r.Matches(p) {
+			continue
+		}
+
+		res.Matched = append(res.Matched, i)
+		switch r.Action {
+		case ActionHide:
+			res.Hidden = true
+		case ActionDelete:
+			res.Deleted = true
+		case ActionFlag:
+			res.Flagged = true
+		case ActionRewrite:
+			if r.Field == FieldTitle {
+				title = r.re.ReplaceAllString(title, r.Replacement)
+			} else {
+				title = r.Replacement
+			}
+			res.Title = title
+		}
+	}
+	return res
+}
+
+// value returns the post field the rule looks at
+func (r *Rule) value(p Post) string {
+	switch r.Field {
+	case FieldTitle:
+		return p.Title
+	case FieldDescription:
+		return p.Description
+	case FieldAuthor:
+		return p.Author
+	case FieldCategory:
+		return p.Category
+	case FieldFeed:
+		return p.Feed
+	case FieldURL:
+		return p.URL
+	}
+	return ""
+}
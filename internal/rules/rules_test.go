@@ -0,0 +1,90 @@
+package rules
+
+import (
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{"valid contains", Rule{Field: FieldTitle, Match: MatchContains, Pattern: "Sponsored", Action: ActionHide}, false},
+		{"unknown field", Rule{Field: "body", Match: MatchContains, Pattern: "x", Action: ActionHide}, true},
+		{"unknown match", Rule{Field: FieldTitle, Match: "like", Pattern: "x", Action: ActionHide}, true},
+		{"unknown action", Rule{Field: FieldTitle, Match: MatchContains, Pattern: "x", Action: "drop"}, true},
+		{"blank pattern", Rule{Field: FieldTitle, Match: MatchContains, Action: ActionHide}, true},
+		{"bad regex", Rule{Field: FieldTitle, Match: MatchRegex, Pattern: "(", Action: ActionHide}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.rule.Compile()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: wanted error %v got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	post := Post{
+		Title:  "Sponsored: Buy Things",
+		Author: "Jane Doe",
+		URL:    "https://example.com/ads/1",
+	}
+
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{"contains folds case", Rule{Field: FieldTitle, Match: MatchContains, Pattern: "sponsored", Action: ActionHide}, true},
+		{"contains case sensitive", Rule{Field: FieldTitle, Match: MatchContains, Pattern: "sponsored", CaseSensitive: true, Action: ActionHide}, false},
+		{"equals whole field", Rule{Field: FieldAuthor, Match: MatchEquals, Pattern: "jane doe", Action: ActionHide}, true},
+		{"equals partial", Rule{Field: FieldAuthor, Match: MatchEquals, Pattern: "jane", Action: ActionHide}, false},
+		{"regex url", Rule{Field: FieldURL, Match: MatchRegex, Pattern: `/ads/\d+$`, Action: ActionHide}, true},
+		{"empty field", Rule{Field: FieldCategory, Match: MatchContains, Pattern: "go", Action: ActionHide}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.rule.Compile(); err != nil {
+			t.Fatalf("%s: unexpected error %s", tt.name, err.Error())
+		}
+		if got := tt.rule.Matches(post); got != tt.want {
+			t.Errorf("%s: wanted %v got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rules := []Rule{
+		{Field: FieldTitle, Match: MatchRegex, Pattern: `^\[ad\]\s*`, Action: ActionRewrite},
+		{Field: FieldTitle, Match: MatchContains, Pattern: "[ad]", Action: ActionHide},
+		{Field: FieldAuthor, Match: MatchEquals, Pattern: "bot", Action: ActionFlag},
+		{Field: FieldFeed, Match: MatchContains, Pattern: "spam", Action: ActionDelete},
+	}
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			t.Fatalf("unexpected error %s", err.Error())
+		}
+	}
+
+	got := Evaluate(rules, Post{Title: "[AD] Great Deal", Author: "Bot", Feed: "news"})
+	if got.Title != "Great Deal" {
+		t.Errorf("wanted title %q got %q", "Great Deal", got.Title)
+	}
+	if got.Hidden {
+		t.Errorf("hide rule should not match the rewritten title")
+	}
+	if !got.Flagged || got.Deleted {
+		t.Errorf("wanted flagged and not deleted got %+v", got)
+	}
+	if len(got.Matched) != 2 {
+		t.Errorf("wanted 2 matched rules got %d", len(got.Matched))
+	}
+
+	got = Evaluate(rules, Post{Title: "Hello", Feed: "Spam Daily"})
+	if !got.Deleted || got.Title != "" {
+		t.Errorf("wanted deleted with no rewrite got %+v", got)
+	}
+}
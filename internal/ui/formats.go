@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// object encodes a row as a json object with the keys in column order
+func object(columns []string, row []any) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, c := range columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(value(row[i]))
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode %s %v", c, err)
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(val)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func writeJSON(w io.Writer, t Table) error {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i, row := range t.Rows {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		obj, err := object(t.Columns, row)
+		if err != nil {
+			return err
+		}
+		b.Write(obj)
+	}
+	b.WriteByte(']')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, b.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := out.WriteTo(w)
+	return err
+}
+
+func writeJSONL(w io.Writer, t Table) error {
+	for _, row := range t.Rows {
+		obj, err := object(t.Columns, row)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, t Table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = csvText(value(v))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []string:
+		return strings.Join(v, ";")
+	}
+	return fmt.Sprint(v)
+}
+
+// writeYAML writes the rows as a sequence of mappings, values are written as json which is valid yaml
+func writeYAML(w io.Writer, t Table) error {
+	if len(t.Rows) == 0 {
+		_, err := fmt.Fprintln(w, "[]")
+		return err
+	}
+	for _, row := range t.Rows {
+		for i, c := range t.Columns {
+			val, err := json.Marshal(value(row[i]))
+			if err != nil {
+				return fmt.Errorf("unable to encode %s %v", c, err)
+			}
+			lead := "  "
+			if i == 0 {
+				lead = "- "
+			}
+			if _, err := fmt.Fprintf(w, "%s%s: %s\n", lead, c, val); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTable() Table {
+	published := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	t := Table{
+		Title:   "Posts",
+		Columns: []string{"id", "title", "flagged", "also_in"},
+		Hidden:  []string{"id"},
+	}
+	t.Add(1, Text{Value: "Go 1.22", Display: "[!] Go 1.22"}, true, []string{"hn", "lobsters"})
+	t.Add(2, "Say \"hi\", then leave", published, nil)
+	return t
+}
+
+func TestTableFormats(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatJSONL, `{"id":1,"title":"Go 1.22","flagged":true,"also_in":["hn","lobsters"]}
+{"id":2,"title":"Say \"hi\", then leave","flagged":"2024-03-01T12:30:00Z","also_in":null}
+`},
+		{FormatCSV, `id,title,flagged,also_in
+1,Go 1.22,true,hn;lobsters
+2,"Say ""hi"", then leave",2024-03-01T12:30:00Z,
+`},
+		{FormatYAML, `- id: 1
+  title: "Go 1.22"
+  flagged: true
+  also_in: ["hn","lobsters"]
+- id: 2
+  title: "Say \"hi\", then leave"
+  flagged: "2024-03-01T12:30:00Z"
+  also_in: null
+`},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		r := New(&out)
+		r.SetFormat(tt.format)
+		if err := r.Table(testTable()); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s: wanted\n%s\ngot\n%s", tt.format, tt.want, out.String())
+		}
+	}
+}
+
+func TestTableJSON(t *testing.T) {
+	var out bytes.Buffer
+	r := New(&out)
+	r.SetFormat(FormatJSON)
+
+	empty := Table{Columns: []string{"name"}}
+	if err := r.Table(empty); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "[]\n" {
+		t.Errorf("wanted an empty array got %q", out.String())
+	}
+}
+
+func TestTableHidesColumns(t *testing.T) {
+	var out bytes.Buffer
+	r := New(&out)
+	if err := r.Table(testTable()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{"[!] Go 1.22", "hn, lobsters", "2024-03-01 12:30:00"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("wanted %q in the table got %q", want, got)
+		}
+	}
+	for _, line := range strings.Split(got, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "1") {
+			t.Errorf("wanted the hidden id column left out got %q", line)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("wanted an error for an unknown format")
+	}
+	if f, err := ParseFormat("jsonl"); err != nil || f != FormatJSONL {
+		t.Errorf("wanted jsonl got %v %v", f, err)
+	}
+}
@@ -1,38 +1,346 @@
 package ui
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
 	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
 )
 
+const Reset = "\033[0m"
+
+// Format selects how tables are written, every format other than table is meant for other programs
+type Format string
+
 const (
-	Reset = "\033[0m"
-	Red   = "\033[31m"
-	Green = "\033[32m"
-	Blue  = "\033[34m"
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+	FormatYAML  Format = "yaml"
 )
 
+var Formats = []string{string(FormatTable), string(FormatJSON), string(FormatJSONL), string(FormatCSV), string(FormatYAML)}
+
+// ParseFormat validates the value of --output
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if s == f {
+			return Format(s), nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of %s", s, strings.Join(Formats, ", "))
+}
+
 type Renderer struct {
-	out io.Writer
+	out    io.Writer
+	errOut io.Writer
+	format Format
+	theme  Theme
+	// tty is set when out is a terminal, colors and fitting tables to the width only apply to terminals
+	tty   bool
+	fd    int
+	color bool
+	width int
+	pager string
+}
+
+// New returns a renderer writing tables to out. Colors are on when out is a terminal,
+// NO_COLOR is unset and TERM is not dumb.
+func New(out io.Writer) *Renderer {
+	r := &Renderer{out: out, errOut: os.Stderr, format: FormatTable, theme: DefaultTheme}
+	if f, ok := out.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		r.tty = true
+		r.fd = int(f.Fd())
+	}
+	r.color = r.tty && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	r.pager = defaultPager
+	if pager, ok := os.LookupEnv("PAGER"); ok {
+		r.pager = pager
+	}
+	return r
 }
 
-func New(out io.Writer) *Renderer { return &Renderer{out: out} }
+const defaultPager = "less -R"
+
+// SetFormat changes the output format. With any format other than table the
+// decorative output is dropped and messages go to stderr so stdout only holds data.
+func (r *Renderer) SetFormat(f Format) { r.format = f }
+
+// Structured reports whether the output is meant for other programs
+func (r *Renderer) Structured() bool { return r.format != FormatTable }
+
+// SetTheme changes the colors used for each role
+func (r *Renderer) SetTheme(t Theme) { r.theme = t }
+
+// SetColor turns colors on or off, ex for --no-color
+func (r *Renderer) SetColor(on bool) { r.color = on }
+
+// Color reports whether colors are on
+func (r *Renderer) Color() bool { return r.color }
+
+// SetPager changes the command tables longer than the terminal are piped through, an empty command turns paging off
+func (r *Renderer) SetPager(command string) { r.pager = command }
 
-func (r *Renderer) Header(title string) {
-	fmt.Fprintf(r.out, "\n=== %s ===\n\n", title)
+// Pager returns the pager command, empty when paging is off
+func (r *Renderer) Pager() string { return r.pager }
+
+// SetWidth fixes the width tables are fitted to, 0 uses the terminal width
+func (r *Renderer) SetWidth(width int) { r.width = width }
+
+// Paint wraps text in the color of its role, the text is returned as is when colors are off
+func (r *Renderer) Paint(role Role, text string) string {
+	params := r.theme[role]
+	if !r.color || r.Structured() || params == "" || text == "" {
+		return text
+	}
+	return "\033[" + params + "m" + text + Reset
+}
+
+// maxWidth returns the width tables must fit in, 0 means no limit
+func (r *Renderer) maxWidth() int {
+	if r.width > 0 {
+		return r.width
+	}
+	if !r.tty {
+		return 0
+	}
+	width, _, err := term.GetSize(r.fd)
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+func (r *Renderer) Header(title string) { r.header(r.out, title) }
+
+func (r *Renderer) header(w io.Writer, title string) {
+	if r.Structured() {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n\n", r.Paint(RoleHeader, "=== "+title+" ==="))
 }
 
 func (r *Renderer) Item(format string, args ...any) {
+	if r.Structured() {
+		return
+	}
 	fmt.Fprintf(r.out, format+"\n", args...)
 }
 
 func (r *Renderer) Column(format string, args ...any) {
+	if r.Structured() {
+		return
+	}
 	tw := tabwriter.NewWriter(r.out, 2, 2, 2, ' ', tabwriter.AlignRight)
 	fmt.Fprintf(tw, format, args...)
 	tw.Flush()
 }
 
-func (r *Renderer) Info(msg string)  { fmt.Fprintf(r.out, "ℹ %s\n", msg) }
-func (r *Renderer) Warn(msg string)  { fmt.Fprintf(r.out, "⚠ %s\n", msg) }
-func (r *Renderer) Error(msg string) { fmt.Fprintf(r.out, "✗ %s\n", msg) }
+func (r *Renderer) Info(msg string)  { fmt.Fprintln(r.messages(), r.Paint(RoleInfo, "ℹ "+msg)) }
+func (r *Renderer) Warn(msg string)  { fmt.Fprintln(r.messages(), r.Paint(RoleWarning, "⚠ "+msg)) }
+func (r *Renderer) Error(msg string) { fmt.Fprintln(r.messages(), r.Paint(RoleError, "✗ "+msg)) }
+
+// Prompt asks a question, the answer is typed on the same line
+func (r *Renderer) Prompt(msg string) { fmt.Fprint(r.messages(), r.Paint(RoleWarning, "? "+msg+" ")) }
+
+func (r *Renderer) messages() io.Writer {
+	if r.Structured() {
+		return r.errOut
+	}
+	return r.out
+}
+
+// Table is a listing, each row holds one value per column.
+// The column names are the field names in the structured formats and must not change once released.
+type Table struct {
+	Title   string
+	Columns []string
+	// Hidden names the columns left out of the table format, ex ids that are only useful to scripts
+	Hidden []string
+	// Roles colors whole columns in the table format, timestamps are colored without being listed
+	Roles map[string]Role
+	Rows  [][]any
+}
+
+// Add appends a row, values are given in column order
+func (t *Table) Add(values ...any) {
+	t.Rows = append(t.Rows, values)
+}
+
+// Text pairs a value with the text shown for it in table output, the structured formats use the value
+type Text struct {
+	Value   any
+	Display string
+}
+
+// Table writes a table in the current format, on a terminal output taller than the screen goes through the pager
+func (r *Renderer) Table(t Table) error {
+	var buf bytes.Buffer
+	if err := r.writeTable(&buf, t); err != nil {
+		return err
+	}
+	return r.page(buf.Bytes())
+}
+
+func (r *Renderer) writeTable(w io.Writer, t Table) error {
+	switch r.format {
+	case FormatJSON:
+		return writeJSON(w, t)
+	case FormatJSONL:
+		return writeJSONL(w, t)
+	case FormatCSV:
+		return writeCSV(w, t)
+	case FormatYAML:
+		return writeYAML(w, t)
+	}
+
+	r.header(w, t.Title)
+
+	// the cells are laid out by hand rather than with a tabwriter so colors do not count towards the widths
+	columns := []int{}
+	for i, name := range t.Columns {
+		if !slices.Contains(t.Hidden, name) {
+			columns = append(columns, i)
+		}
+	}
+	rows := make([][]string, len(t.Rows))
+	widths := make([]int, len(columns))
+	for i, row := range t.Rows {
+		for j, c := range columns {
+			cell := tableText(row[c])
+			rows[i] = append(rows[i], cell)
+			widths[j] = max(widths[j], utf8.RuneCountInString(cell))
+		}
+	}
+	limit := fitWidths(widths, r.maxWidth()-tablePadding*len(widths))
+
+	for i, row := range rows {
+		var line strings.Builder
+		for j, cell := range row {
+			cell = truncate(cell, limit)
+			line.WriteString(strings.Repeat(" ", tablePadding+widths[j]-utf8.RuneCountInString(cell)))
+			line.WriteString(r.Paint(cellRole(t, columns[j], t.Rows[i][columns[j]]), cell))
+		}
+		if _, err := fmt.Fprintln(w, line.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// page writes the output directly unless it is going to a terminal and does not fit on the screen,
+// then it is piped through the pager. A pager that can not be started falls back to writing directly.
+func (r *Renderer) page(output []byte) error {
+	args := strings.Fields(r.pager)
+	if len(args) == 0 || !r.tty {
+		_, err := r.out.Write(output)
+		return err
+	}
+	_, height, err := term.GetSize(r.fd)
+	if err != nil || bytes.Count(output, []byte("\n")) < height {
+		_, err := r.out.Write(output)
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(output)
+	cmd.Stdout = r.out
+	cmd.Stderr = r.errOut
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("pager %s failed %v", r.pager, err)
+		}
+		_, err := r.out.Write(output)
+		return err
+	}
+	return nil
+}
+
+// tablePadding is the space between the right aligned columns of a table
+const tablePadding = 2
+
+// fitWidths shrinks the widest columns until they fit in the available width and returns
+// the longest a cell may be, 0 when nothing needs to be cut
+func fitWidths(widths []int, available int) int {
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	if available <= 0 || total <= available {
+		return 0
+	}
+
+	// find the largest limit that fits, every column keeps at least a few characters
+	limit := slices.Max(widths)
+	for limit > minCellWidth && total > available {
+		limit--
+		total = 0
+		for _, w := range widths {
+			total += min(w, limit)
+		}
+	}
+	for i := range widths {
+		widths[i] = min(widths[i], limit)
+	}
+	return limit
+}
+
+const minCellWidth = 8
+
+// truncate cuts text to limit runes ending with an ellipsis, a limit of 0 keeps the text whole
+func truncate(text string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit-1]) + "…"
+}
+
+func cellRole(t Table, column int, v any) Role {
+	if role, ok := t.Roles[t.Columns[column]]; ok {
+		return role
+	}
+	if _, ok := v.(time.Time); ok {
+		return RoleTimestamp
+	}
+	return ""
+}
+
+// tableText formats a value for the human readable table
+func tableText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case Text:
+		return v.Display
+	case time.Time:
+		return v.Format(time.DateTime)
+	case []string:
+		return strings.Join(v, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+// value returns the underlying value for the structured formats
+func value(v any) any {
+	switch v := v.(type) {
+	case Text:
+		return value(v.Value)
+	case []string:
+		// an empty list is written as [] rather than null
+		if v == nil {
+			return []string{}
+		}
+	}
+	return v
+}
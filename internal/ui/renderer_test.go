@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseStyle(t *testing.T) {
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"bold red", "1;31"},
+		{"bright-cyan on-blue", "96;44"},
+		{"208", "38;5;208"},
+		{"#ff8800", "38;2;255;136;0"},
+		{"none", ""},
+	}
+	for _, tt := range tests {
+		got, err := ParseStyle(tt.style)
+		if err != nil {
+			t.Errorf("%s: %v", tt.style, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: wanted %q got %q", tt.style, tt.want, got)
+		}
+	}
+
+	if _, err := ParseStyle("blurple"); err == nil {
+		t.Errorf("wanted an error for an unknown color")
+	}
+	if _, err := NewTheme(map[Role]string{"footer": "red"}); err == nil {
+		t.Errorf("wanted an error for an unknown role")
+	}
+}
+
+func TestPaint(t *testing.T) {
+	r := New(&bytes.Buffer{})
+	if got := r.Paint(RoleError, "oops"); got != "oops" {
+		t.Errorf("wanted no color when not writing to a terminal got %q", got)
+	}
+
+	r.SetColor(true)
+	if got := r.Paint(RoleError, "oops"); got != "\033[31moops"+Reset {
+		t.Errorf("wanted red text got %q", got)
+	}
+
+	r.SetFormat(FormatJSON)
+	if got := r.Paint(RoleError, "oops"); got != "oops" {
+		t.Errorf("wanted no color in structured output got %q", got)
+	}
+}
+
+func TestTableFitsWidth(t *testing.T) {
+	var out bytes.Buffer
+	r := New(&out)
+	r.SetWidth(40)
+
+	tbl := Table{Columns: []string{"name", "url"}}
+	tbl.Add("hn", "https://news.ycombinator.com/rss?with=a&very=long&query=string")
+	tbl.Add("lobsters", "https://lobste.rs/rss")
+	if err := r.Table(tbl); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(strings.Trim(out.String(), "\n"), "\n")[1:] {
+		if n := len([]rune(line)); n > 40 {
+			t.Errorf("wanted lines of at most 40 characters got %d in %q", n, line)
+		}
+	}
+	if !strings.Contains(out.String(), "…") || !strings.Contains(out.String(), "https://lobste.rs/rss") {
+		t.Errorf("wanted only the long url truncated got %q", out.String())
+	}
+}
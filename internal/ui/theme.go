@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Role names what a piece of output is, the theme decides how each role is colored
+type Role string
+
+const (
+	RoleHeader    Role = "header"
+	RoleError     Role = "error"
+	RoleWarning   Role = "warning"
+	RoleInfo      Role = "info"
+	RoleFeedName  Role = "feed_name"
+	RoleTimestamp Role = "timestamp"
+)
+
+var Roles = []Role{RoleHeader, RoleError, RoleWarning, RoleInfo, RoleFeedName, RoleTimestamp}
+
+// Theme maps a role to the SGR parameters of its style, ex "1;31" for bold red.
+// A role missing from the theme is written without color.
+type Theme map[Role]string
+
+// DefaultTheme is used for every role the config does not set
+var DefaultTheme = Theme{
+	RoleHeader:    "1;31",
+	RoleError:     "31",
+	RoleWarning:   "33",
+	RoleInfo:      "34",
+	RoleFeedName:  "32",
+	RoleTimestamp: "2",
+}
+
+var colors = map[string]int{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+}
+
+var attributes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+}
+
+// NewTheme returns the default theme with the given roles restyled. Styles are space separated words,
+// ex "bold red", "bright-cyan on-blue", "208" or "#ff8800", and "none" removes the color of a role.
+// Empty styles keep the default.
+func NewTheme(styles map[Role]string) (Theme, error) {
+	t := Theme{}
+	for role, params := range DefaultTheme {
+		t[role] = params
+	}
+	for role, style := range styles {
+		if _, ok := DefaultTheme[role]; !ok {
+			return nil, fmt.Errorf("unknown theme role %q", role)
+		}
+		if style == "" {
+			continue
+		}
+		params, err := ParseStyle(style)
+		if err != nil {
+			return nil, fmt.Errorf("invalid style for %s: %v", role, err)
+		}
+		t[role] = params
+	}
+	return t, nil
+}
+
+// ParseStyle turns a style such as "bold red" into SGR parameters
+func ParseStyle(style string) (string, error) {
+	params := []string{}
+	for _, word := range strings.Fields(strings.ToLower(style)) {
+		if word == "none" {
+			return "", nil
+		}
+		if a, ok := attributes[word]; ok {
+			params = append(params, a)
+			continue
+		}
+
+		// foreground colors start at 30, backgrounds at 40
+		base := 30
+		if w, ok := strings.CutPrefix(word, "on-"); ok {
+			base, word = 40, w
+		}
+		p, err := colorParams(word, base)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, p)
+	}
+	return strings.Join(params, ";"), nil
+}
+
+func colorParams(word string, base int) (string, error) {
+	if name, ok := strings.CutPrefix(word, "bright-"); ok {
+		if c, ok := colors[name]; ok {
+			return strconv.Itoa(base + 60 + c), nil
+		}
+	}
+	if c, ok := colors[word]; ok {
+		return strconv.Itoa(base + c), nil
+	}
+	if n, err := strconv.Atoi(word); err == nil && n >= 0 && n <= 255 {
+		return fmt.Sprintf("%d;5;%d", base+8, n), nil
+	}
+	if hex, ok := strings.CutPrefix(word, "#"); ok && len(hex) == 6 {
+		if rgb, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return fmt.Sprintf("%d;2;%d;%d;%d", base+8, rgb>>16, rgb>>8&0xff, rgb&0xff), nil
+		}
+	}
+	return "", fmt.Errorf("unknown color or attribute %q", word)
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"io"
 	"os"
 
 	"github.com/joshhartwig/gator/internal/config"
 	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/migrate"
+	"github.com/joshhartwig/gator/internal/rules"
 	ui "github.com/joshhartwig/gator/internal/ui"
 
 	_ "github.com/lib/pq"
@@ -23,100 +25,102 @@ type RSSFeed struct {
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"creator"`
+	Categories  []string `xml:"category"`
 }
 
 type state struct {
-	db     *database.Queries
-	config *config.Config
-	ui     *ui.Renderer
+	db      database.Store
+	pool    *sql.DB
+	dialect migrate.Dialect
+	config  *config.Config
+	ui      *ui.Renderer
+	// in is where confirmations are read from, nil reads them from the terminal
+	in io.Reader
 }
 
-// command struct contains name and slice of string args
-// ex 'login' - args in slice
-type command struct {
-	name string
-	args []string
-}
-
-type commands struct {
-	names map[string]func(*state, command) error
-}
+func main() {
+	// create a new commands struct and register every command definition
+	cmds := newCommands()
+	registerCommands(cmds)
 
-// run a given command with the provided state
-func (c *commands) run(s *state, cmd command) error {
-	f, ok := c.names[cmd.name]
-	if !ok {
-		return fmt.Errorf("gator: error unable to find the requested command %s\n", cmd.name)
+	// the completion scripts pass the words typed so far, they may include global flags
+	args := os.Args[1:]
+	globals := map[string]string{"output": string(ui.FormatTable)}
+	if len(args) == 0 || args[0] != "__complete" {
+		var err error
+		args, globals, err = extractGlobalFlags(args)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	}
+	format, _ := ui.ParseFormat(globals["output"])
 
-	err := f(s, cmd)
-	if err != nil {
-		return err
+	// print out gator start message unless the output is meant for another program
+	def, _, _, resolveErr := cmds.resolve(args)
+	if format == ui.FormatTable && (resolveErr != nil || !def.plain) {
+		fmt.Println(prefix)
 	}
-	return nil
-}
 
-// register a handler for a command name
-func (c *commands) register(name string, f func(*state, command) error) {
-	_, ok := c.names[name]
-	if !ok {
-		c.names[name] = f
+	// unknown commands are reported by run without needing the config or a database
+	need := databaseNone
+	if resolveErr == nil {
+		need = def.database
 	}
-}
-
-func main() {
 
-	fmt.Println(prefix) // print out gator start message
 	cfg, err := config.Read()
-	if err != nil {
-		log.Panic("error reading config file")
-	}
-
-	db, err := sql.Open("postgres", cfg.DB_URL)
-	if err != nil {
-		log.Panic("error opening database")
+	if err != nil && need != databaseNone && need != databaseOptional {
+		fmt.Fprintln(os.Stderr, configError(err))
+		os.Exit(1)
 	}
 
-	queries := database.New(db)
-
 	st := state{
-		db:     queries,
 		config: &cfg,
 		ui:     ui.New(os.Stdout),
 	}
+	st.ui.SetFormat(format)
+	if globals["no-color"] == "true" {
+		st.ui.SetColor(false)
+	}
+	if globals["no-pager"] == "true" {
+		st.ui.SetPager("")
+	}
+	theme, err := configTheme(cfg.Theme)
+	if err != nil {
+		st.ui.Warn(fmt.Sprintf("ignoring the theme in the config file %v", err))
+	} else {
+		st.ui.SetTheme(theme)
+	}
 
-	// create a new commands struct and register login with handler
-	cmds := commands{names: make(map[string]func(*state, command) error)}
-
-	cmds.register("help", cmds.handlerHelp)
-	cmds.register("listfollows", handlerListFollows)
-	cmds.register("feeds", handlerGetFeeds)
-	cmds.register("users", handlerListUsers)
-	cmds.register("reset", handlerReset)
-
-	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
-	cmds.register("agg", handlerAgg)
-	cmds.register("browse", middlewareLoggedIn(handlerBrowsePosts))
-	cmds.register("follow", middlewareLoggedIn(handlerFollow))
-	cmds.register("following", middlewareLoggedIn(handlerGetFollows))
-	cmds.register("login", handlerLogin)
-	cmds.register("register", handlerRegister)
-	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-	cmds.register("quit", cmds.handlerQuit)
-
-	// get os orgs
-	args := os.Args
-	if len(args) < 2 {
+	if len(args) < 1 {
 		st.ui.Error("Gator requires two or more arguments initially, see 'help' for more assistance")
 		os.Exit(1)
 	}
 
-	action := args[1]
-	actionsArgs := args[2:]
+	if need != databaseNone {
+		// optional commands carry on without a database, completion just offers fewer candidates
+		if err := connect(&st, need); err != nil && need != databaseOptional {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	// commands that need the database check the schema first
+	if need != databaseNone && need != databaseOptional && !def.noSchemaCheck {
+		if err := checkSchema(&st); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	action := args[0]
+	actionsArgs := args[1:]
 
 	cmd := command{
 		name: action,
@@ -125,8 +129,417 @@ func main() {
 
 	err = cmds.run(&st, cmd)
 	if err != nil {
-		fmt.Println(err)
+		// keep stdout clean for the structured output formats
+		if st.ui.Structured() {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Println(err)
+		}
 		os.Exit(1)
 	}
 
 }
+
+// configTheme builds the table theme from the theme section of the config
+func configTheme(t config.Theme) (ui.Theme, error) {
+	return ui.NewTheme(map[ui.Role]string{
+		ui.RoleHeader:    t.Header,
+		ui.RoleError:     t.Error,
+		ui.RoleWarning:   t.Warning,
+		ui.RoleInfo:      t.Info,
+		ui.RoleFeedName:  t.Feed_Name,
+		ui.RoleTimestamp: t.Timestamp,
+	})
+}
+
+// registerCommands registers the command tree along with the deprecated flat names
+func registerCommands(cmds *commands) {
+	cmds.register(commandDef{
+		name:          "help",
+		summary:       "shows all commands or the usage of a group or command",
+		args:          []argDef{{name: "command", optional: true, variadic: true, help: "group or command to describe"}},
+		handler:       cmds.handlerHelp,
+		noSchemaCheck: true,
+		database:      databaseNone,
+	})
+	cmds.register(commandDef{
+		name:     "agg",
+		summary:  "fetches feeds continuously, one feed every interval",
+		args:     []argDef{{name: "interval", kind: kindDuration, help: "time between fetches (ex 1m)"}},
+		handler:  handlerAgg,
+		database: databaseWait,
+	})
+	cmds.register(commandDef{
+		name:          "completion",
+		summary:       "prints the shell completion script, ex source <(gator completion bash)",
+		args:          []argDef{{name: "shell", choices: []string{"bash", "zsh", "fish"}}},
+		handler:       handlerCompletion,
+		plain:         true,
+		noSchemaCheck: true,
+		database:      databaseNone,
+	})
+	cmds.register(commandDef{
+		name:          "__complete",
+		summary:       "prints completion candidates for the completion scripts",
+		handler:       cmds.handlerComplete,
+		hidden:        true,
+		passthrough:   true,
+		plain:         true,
+		noSchemaCheck: true,
+		database:      databaseOptional,
+	})
+	cmds.register(commandDef{
+		name:    "reader",
+		summary: "opens a full screen reader for the feeds and posts",
+		handler: middlewareLoggedIn(handlerReader),
+	})
+	cmds.register(commandDef{
+		name:    "open",
+		summary: "opens a post in the browser, ex gator open 12",
+		args:    []argDef{{name: "post", optional: true, help: "handle shown by post browse, or the post id"}},
+		flags: []flagDef{
+			{name: "latest", kind: kindBool, help: "opens the newest post from the feeds you follow"},
+			{name: "copy", kind: kindBool, help: "copies the link to the clipboard instead of opening it"},
+		},
+		handler: middlewareLoggedIn(handlerOpen),
+	})
+	cmds.register(commandDef{
+		name:    "shell",
+		summary: "starts an interactive session with history and tab completion",
+		handler: cmds.handlerShell,
+	})
+	cmds.register(commandDef{
+		name:          "quit",
+		summary:       "ends the shell session",
+		handler:       cmds.handlerQuit,
+		noSchemaCheck: true,
+		database:      databaseNone,
+	})
+	cmds.register(commandDef{
+		name:          "doctor",
+		summary:       "checks the config file, the database connection, the schema and permissions",
+		handler:       handlerDoctor,
+		noSchemaCheck: true,
+		database:      databaseNone,
+	})
+	cmds.register(commandDef{
+		name:    "backup",
+		summary: "saves every user, feed, follow and post to a compressed archive",
+		args:    []argDef{{name: "file", help: "archive to write, ex gator-backup.json.gz"}},
+		handler: middlewareLoggedIn(handlerBackup),
+	})
+	cmds.register(commandDef{
+		name:    "restore",
+		summary: "imports an archive made by backup, rows already in the database are kept",
+		args:    []argDef{{name: "file", help: "archive written by backup"}},
+		flags: []flagDef{
+			{name: "on-conflict", def: conflictSkip, choices: []string{conflictSkip, conflictFail}, help: "skip rows that already exist, or fail and restore nothing"},
+		},
+		handler: middlewareLoggedIn(handlerRestore),
+	})
+
+	cmds.group(groupDef{name: "feed", summary: "add, inspect and edit feeds"})
+	cmds.register(commandDef{
+		name:    "feed add",
+		summary: "adds a new feed and follows it",
+		args: []argDef{
+			{name: "name", help: "name shown for the feed"},
+			{name: "url", kind: kindURL, help: "url of the rss feed"},
+		},
+		handler: middlewareLoggedIn(handlerAddFeed),
+	})
+	cmds.register(commandDef{
+		name:    "feed list",
+		summary: "lists every feed in the database",
+		handler: middlewareLoggedIn(handlerGetFeeds),
+	})
+	cmds.register(commandDef{
+		name:    "feed status",
+		summary: "shows when each feed was last fetched and its retention",
+		handler: handlerFeedStatus,
+	})
+	cmds.register(commandDef{
+		name:    "feed rename",
+		summary: "renames a feed you added for everyone following it, admins can rename any feed",
+		args: []argDef{
+			{name: "feed", kind: kindFeed, help: "handle, id or url of the feed", complete: completeFeedURLs},
+			{name: "name", help: "new name shown for the feed"},
+		},
+		handler: middlewareLoggedIn(handlerRenameFeed),
+	})
+	cmds.register(commandDef{
+		name:    "feed seturl",
+		summary: "points a feed you added at a new url after fetching it, admins can change any feed",
+		args: []argDef{
+			{name: "feed", kind: kindFeed, help: "handle, id or url of the feed", complete: completeFeedURLs},
+			{name: "url", kind: kindURL, help: "new url of the rss feed"},
+		},
+		handler: middlewareLoggedIn(handlerSetFeedURL),
+	})
+	cmds.register(commandDef{
+		name:    "feed remove",
+		summary: "removes a feed you added for everyone following it, admins can remove any feed",
+		args:    []argDef{{name: "feed", kind: kindFeed, help: "handle, id or url of the feed", complete: completeFeedURLs}},
+		flags: []flagDef{
+			{name: "keep-posts", kind: kindBool, help: "only remove the follows, the feed stays with its posts and 'follow add' brings it back"},
+			{name: "yes", kind: kindBool, help: "remove without asking for confirmation"},
+		},
+		handler: middlewareLoggedIn(handlerRemoveFeed),
+	})
+	cmds.register(commandDef{
+		name:    "feed retention",
+		summary: "overrides the global retention for a feed you added, admins can change any feed",
+		args: []argDef{
+			{name: "feed", kind: kindFeed, help: "handle, id or url of the feed", complete: completeFeedURLs},
+			{name: "setting", choices: []string{"max_age", "max_posts"}},
+			{name: "value", help: "duration for max_age, number for max_posts, or default"},
+		},
+		handler: middlewareLoggedIn(handlerSetRetention),
+	})
+
+	cmds.group(groupDef{name: "follow", summary: "follow feeds and change how they are shown"})
+	cmds.register(commandDef{
+		name:    "follow add",
+		summary: "follows an existing feed",
+		args:    []argDef{{name: "feed", kind: kindFeed, help: "handle, id or url of the feed", complete: completeFeedURLs}},
+		handler: middlewareLoggedIn(handlerFollow),
+	})
+	cmds.register(commandDef{
+		name:    "follow remove",
+		summary: "stops following a feed",
+		args:    []argDef{{name: "feed", kind: kindFeed, help: "handle, id or url of the feed", complete: completeFollowedURLs}},
+		handler: middlewareLoggedIn(handlerUnfollow),
+	})
+	cmds.register(commandDef{
+		name:    "follow list",
+		summary: "lists the feeds you follow",
+		args:    []argDef{{name: "category", optional: true, help: "only list feeds in this category", complete: completeCategories}},
+		handler: middlewareLoggedIn(handlerGetFollows),
+	})
+	cmds.register(commandDef{
+		name:    "follow set",
+		summary: "changes your display name, priority, mute or notify setting for a followed feed",
+		args: []argDef{
+			{name: "feed", kind: kindFeed, help: "handle, id or url of the followed feed", complete: completeFollowedURLs},
+			{name: "setting", choices: []string{"name", "priority", "muted", "notify"}},
+			{name: "value", optional: true, help: "new value, leave out to clear the display name"},
+		},
+		handler: middlewareLoggedIn(handlerSetFollow),
+	})
+
+	cmds.group(groupDef{name: "post", summary: "read the posts of the feeds you follow"})
+	cmds.register(commandDef{
+		name:    "post browse",
+		summary: "shows the most recent posts from the feeds you follow",
+		args:    []argDef{{name: "limit", kind: kindInt, optional: true, def: "3", help: "number of posts to show"}},
+		flags: []flagDef{
+			{name: "category", help: "only show posts from feeds in this category", complete: completeCategories},
+			{name: "after", help: "only show posts that come after this post handle or id"},
+			{name: "page", kind: kindInt, def: "1", help: "page of posts to show, each page holds limit posts"},
+		},
+		handler: middlewareLoggedIn(handlerBrowsePosts),
+	})
+
+	cmds.group(groupDef{name: "category", summary: "group the feeds you follow"})
+	cmds.register(commandDef{
+		name:    "category add",
+		summary: "creates a new category",
+		args:    []argDef{{name: "name"}},
+		handler: middlewareLoggedIn(handlerAddCategory),
+	})
+	cmds.register(commandDef{
+		name:    "category list",
+		summary: "lists your categories",
+		handler: middlewareLoggedIn(handlerListCategories),
+	})
+	cmds.register(commandDef{
+		name:    "category rename",
+		summary: "renames one of your categories",
+		args:    []argDef{{name: "old", complete: completeCategories}, {name: "new"}},
+		handler: middlewareLoggedIn(handlerRenameCategory),
+	})
+	cmds.register(commandDef{
+		name:    "category delete",
+		summary: "deletes one of your categories, its feeds stay followed",
+		args:    []argDef{{name: "name", complete: completeCategories}},
+		handler: middlewareLoggedIn(handlerDeleteCategory),
+	})
+	cmds.register(commandDef{
+		name:    "category assign",
+		summary: "adds a followed feed to a category",
+		args:    []argDef{{name: "feed", kind: kindFeed, complete: completeFollowedURLs}, {name: "category", complete: completeCategories}},
+		handler: middlewareLoggedIn(handlerCategorize),
+	})
+	cmds.register(commandDef{
+		name:    "category unassign",
+		summary: "removes a followed feed from a category",
+		args:    []argDef{{name: "feed", kind: kindFeed, complete: completeFollowedURLs}, {name: "category", complete: completeCategories}},
+		handler: middlewareLoggedIn(handlerUncategorize),
+	})
+
+	cmds.group(groupDef{name: "rule", summary: "manage the rules that hide, delete, flag or rewrite posts", def: "list"})
+	cmds.register(commandDef{
+		name:    "rule list",
+		summary: "lists your rules in the order they are evaluated",
+		handler: middlewareLoggedIn(handlerListRules),
+	})
+	cmds.register(commandDef{
+		name:    "rule add",
+		summary: "adds a rule",
+		args: []argDef{
+			{name: "field", choices: rules.Fields},
+			{name: "match", choices: rules.Matches},
+			{name: "pattern", help: "text or regular expression to match"},
+			{name: "action", choices: rules.Actions},
+			{name: "replacement", optional: true, help: "new title, only for rewrite"},
+		},
+		flags:   []flagDef{{name: "case-sensitive", kind: kindBool, help: "match the pattern case sensitively"}},
+		handler: middlewareLoggedIn(handlerAddRule),
+	})
+	cmds.register(commandDef{
+		name:    "rule remove",
+		summary: "removes a rule",
+		args:    []argDef{{name: "number", kind: kindInt, help: "number shown by 'rule list'", complete: completeRuleNumbers}},
+		handler: middlewareLoggedIn(handlerRemoveRule),
+	})
+	cmds.register(commandDef{
+		name:    "rule test",
+		summary: "previews which posts your rules, one rule or a new rule would match",
+		args:    []argDef{{name: "rule", optional: true, variadic: true, help: "a rule number or <field> <match> <pattern> <action> [replacement]"}},
+		flags:   []flagDef{{name: "case-sensitive", kind: kindBool, help: "match the pattern case sensitively"}},
+		handler: middlewareLoggedIn(handlerTestRules),
+	})
+	cmds.register(commandDef{
+		name:    "rule apply",
+		summary: "re-evaluates your rules against posts already fetched",
+		handler: middlewareLoggedIn(handlerApplyRules),
+	})
+
+	cmds.group(groupDef{name: "user", summary: "manage users"})
+	cmds.register(commandDef{
+		name:    "user register",
+		summary: "creates a new user and sets them as the current user",
+		args:    []argDef{{name: "username"}},
+		handler: handlerRegister,
+	})
+	cmds.register(commandDef{
+		name:    "user login",
+		summary: "sets the current user",
+		args:    []argDef{{name: "username", complete: completeUsers}},
+		handler: handlerLogin,
+	})
+	cmds.register(commandDef{
+		name:    "user admin",
+		summary: "makes a user an admin or takes it away, only admins can",
+		args: []argDef{
+			{name: "username", complete: completeUsers},
+			{name: "admin", kind: kindBool, help: "true or false"},
+		},
+		handler: middlewareLoggedIn(handlerSetAdmin),
+	})
+	cmds.register(commandDef{
+		name:    "user rename",
+		summary: "renames a user, users can rename themselves and admins anyone",
+		args: []argDef{
+			{name: "username", complete: completeUsers},
+			{name: "new-name"},
+		},
+		handler: middlewareLoggedIn(handlerRenameUser),
+	})
+	cmds.register(commandDef{
+		name:    "user delete",
+		summary: "deletes a user, the feeds they added stay for the users who follow them",
+		args:    []argDef{{name: "username", complete: completeUsers}},
+		flags: []flagDef{
+			{name: "transfer-to", help: "user recorded as having added the feeds the deleted user added", complete: completeUsers},
+			{name: "yes", kind: kindBool, help: "delete without asking for confirmation"},
+		},
+		handler: middlewareLoggedIn(handlerDeleteUser),
+	})
+	cmds.register(commandDef{
+		name:    "user list",
+		summary: "lists every user in the database",
+		handler: handlerListUsers,
+	})
+
+	cmds.group(groupDef{name: "migrate", summary: "upgrade the database schema with the migrations built into gator", def: "status"})
+	cmds.register(commandDef{
+		name:          "migrate up",
+		summary:       "applies every pending migration",
+		handler:       handlerMigrateUp,
+		noSchemaCheck: true,
+	})
+	cmds.register(commandDef{
+		name:          "migrate down",
+		summary:       "reverts the most recently applied migration",
+		handler:       handlerMigrateDown,
+		noSchemaCheck: true,
+	})
+	cmds.register(commandDef{
+		name:          "migrate redo",
+		summary:       "reverts the most recently applied migration and applies it again",
+		handler:       handlerMigrateRedo,
+		noSchemaCheck: true,
+	})
+	cmds.register(commandDef{
+		name:          "migrate status",
+		summary:       "lists every migration and when it was applied",
+		handler:       handlerMigrateStatus,
+		noSchemaCheck: true,
+	})
+
+	cmds.group(groupDef{name: "admin", summary: "maintenance commands that affect every user"})
+	cmds.register(commandDef{
+		name:    "admin reset",
+		summary: "deletes every user, feed, follow and post, or only the posts, a user's settings or a feed's posts",
+		args: []argDef{
+			{name: "scope", optional: true, def: resetAll, choices: []string{resetAll, resetPosts, resetUser, resetFeed}},
+			{name: "target", optional: true, help: "user name for user, feed handle, id or url for feed"},
+		},
+		flags:   []flagDef{{name: "yes", kind: kindBool, help: "reset without asking for confirmation"}},
+		handler: middlewareLoggedIn(handlerReset),
+	})
+	cmds.register(commandDef{
+		name:    "admin gc",
+		summary: "deletes the feeds nobody follows along with their posts",
+		flags: []flagDef{
+			{name: "keep-feeds", kind: kindBool, help: "delete only the posts of the feeds nobody follows"},
+			{name: "dry-run", kind: kindBool, help: "list the feeds that would be collected without deleting anything"},
+		},
+		handler: middlewareLoggedIn(handlerGC),
+	})
+	cmds.register(commandDef{
+		name:    "admin prune",
+		summary: "deletes posts outside the retention settings",
+		flags:   []flagDef{{name: "dry-run", kind: kindBool, help: "list the posts that would be deleted without deleting them"}},
+		handler: middlewareLoggedIn(handlerPrune),
+	})
+	cmds.register(commandDef{
+		name:    "admin follows",
+		summary: "lists the feed follows of every user",
+		handler: handlerListFollows,
+	})
+
+	// the flat names from before the command tree, they print a deprecation warning
+	cmds.alias("addfeed", "feed add")
+	cmds.alias("feeds", "feed list")
+	cmds.alias("setretention", "feed retention")
+	cmds.alias("follow", "follow add")
+	cmds.alias("unfollow", "follow remove")
+	cmds.alias("following", "follow list")
+	cmds.alias("setfollow", "follow set")
+	cmds.alias("browse", "post browse")
+	cmds.alias("addcategory", "category add")
+	cmds.alias("categories", "category list")
+	cmds.alias("renamecategory", "category rename")
+	cmds.alias("deletecategory", "category delete")
+	cmds.alias("categorize", "category assign")
+	cmds.alias("uncategorize", "category unassign")
+	cmds.alias("rules", "rule")
+	cmds.alias("register", "user register")
+	cmds.alias("login", "user login")
+	cmds.alias("users", "user list")
+	cmds.alias("reset", "admin reset")
+	cmds.alias("prune", "admin prune")
+	cmds.alias("listfollows", "admin follows")
+}
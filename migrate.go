@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/joshhartwig/gator/internal/migrate"
+	"github.com/joshhartwig/gator/internal/ui"
+	"github.com/joshhartwig/gator/sql/schema"
+	sqliteschema "github.com/joshhartwig/gator/sql/sqlite/schema"
+)
+
+// newMigrator loads the embedded migrations written for the database in use
+func newMigrator(s *state) (*migrate.Migrator, error) {
+	fsys := fs.FS(schema.FS)
+	if s.dialect == migrate.SQLite {
+		fsys = sqliteschema.FS
+	}
+	migrations, err := migrate.Load(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the embedded migrations %v", err)
+	}
+	return migrate.New(s.pool, s.dialect, migrations), nil
+}
+
+// checkSchema fails when migrations are pending so commands never run against an older schema
+func checkSchema(s *state) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+	pending, err := m.Pending(context.Background())
+	if err != nil {
+		return fmt.Errorf("gator: unable to check the database schema %v", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	names := []string{}
+	for _, p := range pending {
+		names = append(names, p.Name)
+	}
+	return fmt.Errorf("gator: the database schema is behind, %d migrations are pending (%s), run 'gator migrate up'", len(pending), strings.Join(names, ", "))
+}
+
+// handlerMigrateUp applies every pending migration
+func handlerMigrateUp(s *state, cmd command) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+	s.ui.Header("Migrate Up")
+	applied, err := m.Up(context.Background())
+	for _, a := range applied {
+		s.ui.Item("Applied %s", a.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		s.ui.Info("The database schema is up to date")
+	}
+	return nil
+}
+
+// handlerMigrateDown reverts the most recently applied migration
+func handlerMigrateDown(s *state, cmd command) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+	s.ui.Header("Migrate Down")
+	reverted, err := m.Down(context.Background())
+	if err != nil {
+		return err
+	}
+	s.ui.Item("Reverted %s", reverted.Name)
+	return nil
+}
+
+// handlerMigrateRedo reverts the most recently applied migration and applies it again
+func handlerMigrateRedo(s *state, cmd command) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+	s.ui.Header("Migrate Redo")
+	redone, err := m.Redo(context.Background())
+	if err != nil {
+		return err
+	}
+	s.ui.Item("Reverted and applied %s", redone.Name)
+	return nil
+}
+
+// handlerMigrateStatus lists every migration and when it was applied
+func handlerMigrateStatus(s *state, cmd command) error {
+	m, err := newMigrator(s)
+	if err != nil {
+		return err
+	}
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		return err
+	}
+
+	t := ui.Table{Title: "Migrations", Columns: []string{"version", "name", "applied", "applied_at"}, Hidden: []string{"applied"}}
+	for _, st := range statuses {
+		appliedAt := ui.Text{Display: "pending"}
+		if st.Applied {
+			appliedAt = ui.Text{Value: st.AppliedAt, Display: "applied " + st.AppliedAt.Format(time.DateTime)}
+		}
+		t.Add(st.Version, st.Name, st.Applied, appliedAt)
+	}
+	return s.ui.Table(t)
+}
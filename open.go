@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+// handlerOpen opens a post in the browser, the post is given by handle or id or is the newest post with --latest.
+// With --copy the link is copied to the clipboard instead.
+func handlerOpen(s *state, cmd command, user database.User) error {
+	var post database.Post
+	var err error
+	switch {
+	case cmd.Bool("latest") && cmd.Has("post"):
+		return fmt.Errorf("give either a post or --latest, not both")
+	case cmd.Bool("latest"):
+		post, err = s.db.GetLatestPostForUser(context.Background(), user.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("there are no posts from the feeds you follow yet, run 'gator agg' to fetch them")
+		}
+		if err != nil {
+			return fmt.Errorf("unable to get the latest post %v", err)
+		}
+	case cmd.Has("post"):
+		post, err = getPostByRef(s, user, cmd.String("post"))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("give a post handle or id, or --latest, see 'gator help open'")
+	}
+
+	if cmd.Bool("copy") {
+		if err := copyToClipboard(post.Url); err != nil {
+			return fmt.Errorf("unable to copy %s %v", post.Url, err)
+		}
+		s.ui.Info(fmt.Sprintf("Copied %s", post.Url))
+		return nil
+	}
+
+	if err := openBrowser(post.Url); err != nil {
+		return fmt.Errorf("unable to open %s %v", post.Url, err)
+	}
+	s.ui.Info(fmt.Sprintf("Opened %s in the browser", post.Title))
+	return nil
+}
+
+// openBrowser opens a url in $BROWSER, or the system's default browser when it is not set
+func openBrowser(url string) error {
+	if browser := os.Getenv("BROWSER"); browser != "" {
+		return exec.Command(browser, url).Start()
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	}
+	return exec.Command("xdg-open", url).Start()
+}
+
+// copyToClipboard copies text with the platform's clipboard tool,
+// on linux the first of wl-copy, xclip and xsel that is installed is used
+func copyToClipboard(text string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	case "windows":
+		cmd = exec.Command("clip")
+	default:
+		for _, tool := range [][]string{{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}} {
+			if _, err := exec.LookPath(tool[0]); err == nil {
+				cmd = exec.Command(tool[0], tool[1:]...)
+				break
+			}
+		}
+	}
+	if cmd == nil {
+		return fmt.Errorf("no clipboard tool found, install wl-copy, xclip or xsel")
+	}
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/ui"
+	"golang.org/x/term"
+)
+
+// number of posts loaded for the selected feed
+const readerPostLimit = 200
+
+// panes of the reader, focus moves left and right between them
+const (
+	paneFeeds = iota
+	panePosts
+	paneContent
+)
+
+var (
+	blockTags = regexp.MustCompile(`(?i)<\s*(br|/p|p|/div|/li|/h[1-6])\b[^>]*>`)
+	allTags   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// readerFeed is a feed in the left pane, every feed is listed and the followed ones are marked
+type readerFeed struct {
+	feed     database.Feed
+	name     string
+	followed bool
+}
+
+// reader is the state of the full screen reader
+type reader struct {
+	s    *state
+	user database.User
+	out  *bufio.Writer
+
+	feeds []readerFeed
+	posts []database.GetPostsForFeedRow
+
+	focus   int
+	feedIdx int
+	postIdx int
+	scroll  int
+	status  string
+
+	width  int
+	height int
+}
+
+// handlerReader opens a three pane reader: feeds on the left, the selected feed's posts in the middle
+// and the selected post on the right. It needs an interactive terminal.
+func handlerReader(s *state, cmd command, user database.User) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("gator: the reader needs an interactive terminal")
+	}
+
+	r := &reader{s: s, user: user, out: bufio.NewWriter(os.Stdout)}
+	if err := r.loadFeeds(); err != nil {
+		return err
+	}
+	r.loadPosts()
+
+	// agg style output from refreshing a feed would draw over the screen
+	saved := s.ui
+	s.ui = ui.New(io.Discard)
+	defer func() { s.ui = saved }()
+
+	old, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("unable to start the reader %v", err)
+	}
+	defer term.Restore(fd, old)
+
+	// switch to the alternate screen and hide the cursor, both are undone on exit
+	fmt.Fprint(r.out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(r.out, "\x1b[?25h\x1b[?1049l")
+		r.out.Flush()
+	}()
+
+	buf := make([]byte, 16)
+	for {
+		if w, h, err := term.GetSize(fd); err == nil && w > 0 && h > 0 {
+			r.width, r.height = w, h
+		} else {
+			r.width, r.height = 80, 24
+		}
+		r.render()
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return nil
+		}
+		if !r.handleKey(string(buf[:n])) {
+			return nil
+		}
+	}
+}
+
+// handleKey applies a key press, it returns false when the reader should close
+func (r *reader) handleKey(key string) bool {
+	r.status = ""
+	switch key {
+	case "q", "\x03":
+		return false
+	case "j", "\x1b[B":
+		r.move(1)
+	case "k", "\x1b[A":
+		r.move(-1)
+	case "\x04", "\x1b[6~":
+		r.move(r.height / 2)
+	case "\x15", "\x1b[5~":
+		r.move(-r.height / 2)
+	case "g":
+		r.move(-1 << 30)
+	case "G":
+		r.move(1 << 30)
+	case "h", "\x1b[D", "\x1b[Z":
+		if r.focus > paneFeeds {
+			r.focus--
+		}
+	case "l", "\x1b[C", "\t", "\r":
+		if r.focus < paneContent {
+			r.focus++
+		}
+	case "o":
+		r.open()
+	case "f":
+		r.toggleFollow()
+	case "r":
+		r.refresh()
+	}
+	return true
+}
+
+// move the selection of the focused pane, the content pane scrolls instead
+func (r *reader) move(delta int) {
+	switch r.focus {
+	case paneFeeds:
+		idx := clamp(r.feedIdx+delta, 0, len(r.feeds)-1)
+		if idx != r.feedIdx {
+			r.feedIdx = idx
+			r.loadPosts()
+		}
+	case panePosts:
+		idx := clamp(r.postIdx+delta, 0, len(r.posts)-1)
+		if idx != r.postIdx {
+			r.postIdx = idx
+			r.scroll = 0
+		}
+	case paneContent:
+		r.scroll = max(r.scroll+delta, 0)
+	}
+}
+
+func (r *reader) loadFeeds() error {
+	feeds, err := r.s.db.GetAllFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to get feeds %v", err)
+	}
+	follows, err := r.s.db.GetFeedFollowsForUser(context.Background(), r.user.ID)
+	if err != nil {
+		return fmt.Errorf("unable to get feed follows %v", err)
+	}
+
+	names := make(map[uuid.UUID]string)
+	for _, f := range follows {
+		names[f.FeedID] = f.FeedName
+	}
+
+	// followed feeds come first in the user's priority order, then the rest by name
+	r.feeds = r.feeds[:0]
+	for _, f := range follows {
+		for _, feed := range feeds {
+			if feed.ID == f.FeedID {
+				r.feeds = append(r.feeds, readerFeed{feed: feed, name: f.FeedName, followed: true})
+			}
+		}
+	}
+	for _, feed := range feeds {
+		if _, ok := names[feed.ID]; !ok {
+			r.feeds = append(r.feeds, readerFeed{feed: feed, name: feed.Name})
+		}
+	}
+	r.feedIdx = clamp(r.feedIdx, 0, len(r.feeds)-1)
+	return nil
+}
+
+func (r *reader) loadPosts() {
+	r.posts, r.postIdx, r.scroll = nil, 0, 0
+	if len(r.feeds) == 0 {
+		return
+	}
+	posts, err := r.s.db.GetPostsForFeed(context.Background(), database.GetPostsForFeedParams{
+		UserID: r.user.ID,
+		FeedID: r.feeds[r.feedIdx].feed.ID,
+		Limit:  readerPostLimit,
+	})
+	if err != nil {
+		r.status = fmt.Sprintf("unable to get posts %v", err)
+		return
+	}
+	r.posts = posts
+}
+
+func (r *reader) open() {
+	if len(r.posts) == 0 {
+		return
+	}
+	url := r.posts[r.postIdx].Url
+	if err := openBrowser(url); err != nil {
+		r.status = fmt.Sprintf("unable to open %s %v", url, err)
+		return
+	}
+	r.status = "opened " + url
+}
+
+func (r *reader) toggleFollow() {
+	if len(r.feeds) == 0 {
+		return
+	}
+	f := r.feeds[r.feedIdx]
+	if f.followed {
+		collected, policy, err := unfollowFeed(r.s, r.user, f.feed.ID)
+		if err != nil {
+			r.status = fmt.Sprintf("error unfollowing %s %v", f.name, err)
+			return
+		}
+		r.status = "unfollowed " + f.name
+		switch {
+		case len(collected) == 0:
+		case policy == unfollowedDelete:
+			r.status += ", nobody follows it anymore so it was deleted"
+		default:
+			r.status += ", nobody follows it anymore so its posts were deleted"
+		}
+	} else {
+		_, err := r.s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    r.user.ID,
+			FeedID:    f.feed.ID,
+		})
+		if err != nil {
+			r.status = fmt.Sprintf("error following %s %v", f.name, err)
+			return
+		}
+		r.status = "followed " + f.name
+	}
+
+	// keep the same feed selected after the list is reordered
+	if err := r.loadFeeds(); err != nil {
+		r.status = err.Error()
+		return
+	}
+	for i, rf := range r.feeds {
+		if rf.feed.ID == f.feed.ID {
+			r.feedIdx = i
+		}
+	}
+	r.loadPosts()
+}
+
+func (r *reader) refresh() {
+	if len(r.feeds) == 0 {
+		return
+	}
+	f := r.feeds[r.feedIdx]
+	r.status = "refreshing " + f.name + "..."
+	r.render()
+
+	if err := scrapeFeed(r.s, f.feed); err != nil {
+		r.status = err.Error()
+		return
+	}
+	r.loadPosts()
+	r.status = fmt.Sprintf("refreshed %s, %d posts", f.name, len(r.posts))
+}
+
+// render draws the whole screen, the title bar, the three panes and the status line
+func (r *reader) render() {
+	leftW := max(r.width/4, 10)
+	midW := max(r.width*3/8, 10)
+	rightW := max(r.width-leftW-midW-2, 0)
+	rows := max(r.height-2, 1)
+
+	feedLines := make([]string, len(r.feeds))
+	for i, f := range r.feeds {
+		mark := "  "
+		if f.followed {
+			mark = "* "
+		}
+		feedLines[i] = mark + f.name
+	}
+	postLines := make([]string, len(r.posts))
+	for i, p := range r.posts {
+		postLines[i] = p.PublishedAt.Format(time.DateOnly) + " " + flaggedTitle(p.Title, p.Flagged)
+	}
+	content := r.content(rightW - 1)
+	r.scroll = clamp(r.scroll, 0, max(len(content)-rows, 0))
+
+	fmt.Fprint(r.out, "\x1b[H")
+	title := fmt.Sprintf(" gator reader - %s", r.user.Name)
+	fmt.Fprintf(r.out, "\x1b[7m%s\x1b[0m\r\n", fit(title, r.width))
+
+	left := r.list(feedLines, r.feedIdx, rows, leftW, paneFeeds)
+	mid := r.list(postLines, r.postIdx, rows, midW, panePosts)
+	for i := 0; i < rows; i++ {
+		right := ""
+		if i+r.scroll < len(content) {
+			right = " " + content[i+r.scroll]
+		}
+		fmt.Fprintf(r.out, "%s│%s│%s\x1b[K\r\n", left[i], mid[i], fit(right, rightW))
+	}
+
+	status := r.status
+	if status == "" {
+		status = "j/k move  h/l pane  g/G top/bottom  o open  f follow/unfollow  r refresh  q quit"
+	}
+	fmt.Fprintf(r.out, "\x1b[7m%s\x1b[0m", fit(" "+status, r.width))
+	r.out.Flush()
+}
+
+// list returns the visible rows of a list pane, scrolled so the selection is on screen
+func (r *reader) list(lines []string, selected, rows, width, pane int) []string {
+	offset := 0
+	if selected >= rows {
+		offset = selected - rows + 1
+	}
+
+	out := make([]string, rows)
+	for i := range out {
+		idx := i + offset
+		if idx >= len(lines) {
+			out[i] = fit("", width)
+			continue
+		}
+		text := fit(" "+lines[idx], width)
+		switch {
+		case idx == selected && r.focus == pane:
+			text = "\x1b[7m" + text + "\x1b[0m"
+		case idx == selected:
+			text = "\x1b[1m" + text + "\x1b[0m"
+		}
+		out[i] = text
+	}
+	return out
+}
+
+// content renders the selected post as wrapped lines
+func (r *reader) content(width int) []string {
+	if len(r.posts) == 0 || width < 10 {
+		return nil
+	}
+	p := r.posts[r.postIdx]
+
+	lines := wrap(flaggedTitle(p.Title, p.Flagged), width)
+	lines = append(lines, "")
+	meta := r.feeds[r.feedIdx].name + "  " + p.PublishedAt.Format(time.DateTime)
+	if p.Author.Valid && p.Author.String != "" {
+		meta += "  " + p.Author.String
+	}
+	lines = append(lines, wrap(meta, width)...)
+	lines = append(lines, wrap(p.Url, width)...)
+	lines = append(lines, "")
+	for _, paragraph := range strings.Split(htmlToText(p.Description.String), "\n") {
+		lines = append(lines, wrap(paragraph, width)...)
+	}
+	return lines
+}
+
+// htmlToText turns a post description into plain paragraphs separated by newlines
+func htmlToText(s string) string {
+	s = blockTags.ReplaceAllString(s, "\n")
+	s = allTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	paragraphs := []string{}
+	blank := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(sanitize(line)), " ")
+		if line == "" {
+			if !blank && len(paragraphs) > 0 {
+				paragraphs = append(paragraphs, "")
+			}
+			blank = true
+			continue
+		}
+		paragraphs = append(paragraphs, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(paragraphs, "\n"))
+}
+
+// wrap breaks text into lines of at most width runes, long words are split
+func wrap(text string, width int) []string {
+	text = sanitize(text)
+	if text == "" {
+		return []string{""}
+	}
+
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit pads or truncates text to exactly width runes
+func fit(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(sanitize(text))
+	if len(runes) > width {
+		if width == 1 {
+			return "…"
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return string(runes) + strings.Repeat(" ", width-len(runes))
+}
+
+// sanitize replaces control characters so feed content cannot move the cursor or change colors
+func sanitize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+func clamp(v, lo, hi int) int {
+	if hi < lo {
+		return lo
+	}
+	return min(max(v, lo), hi)
+}
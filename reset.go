@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/ui"
+	"golang.org/x/term"
+)
+
+// the parts of the database admin reset clears
+const (
+	resetAll   = "all"
+	resetPosts = "posts"
+	resetUser  = "user"
+	resetFeed  = "feed"
+)
+
+// resetPlan is what a reset will delete, counted before anything is deleted so it can be confirmed
+type resetPlan struct {
+	what   string
+	counts []tableCount
+	run    func(tx *state) error
+	// report, when set, tells the user what run did beyond the counted rows
+	report func(s *state)
+}
+
+// handlerReset clears part of the database after listing how many rows go and asking to confirm, --yes skips
+// the question. Everything is deleted in one transaction.
+//   - all deletes every user and with them every feed, follow and post, only admins may run it
+//   - posts deletes every post and marks every feed unfetched so agg fetches them again, only admins may run it
+//   - user <name> deletes the follows, categories, rules and post states of a user and keeps the account,
+//     the feeds nobody else follows are collected like on unfollow, users may reset themselves and admins anyone
+//   - feed <feed> deletes the posts of a feed and marks it unfetched, the user who added it and admins may run it
+func handlerReset(s *state, cmd command, user database.User) error {
+	plan, err := planReset(s, user, cmd.String("scope"), cmd.String("target"))
+	if err != nil {
+		return err
+	}
+
+	t := ui.Table{Title: fmt.Sprintf("Rows deleted by resetting %s", plan.what), Columns: []string{"table", "rows"}}
+	for _, c := range plan.counts {
+		t.Add(c.table, c.rows)
+	}
+	if err := s.ui.Table(t); err != nil {
+		return err
+	}
+
+	if !cmd.Bool("yes") {
+		ok, err := confirm(s, fmt.Sprintf("Reset %s?", plan.what))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.ui.Info("Reset cancelled, nothing was deleted")
+			return nil
+		}
+	}
+
+	if err := inTx(s, plan.run); err != nil {
+		return fmt.Errorf("error reseting %s %v", plan.what, err)
+	}
+	s.ui.Info(fmt.Sprintf("Reset %s complete", plan.what))
+	if plan.report != nil {
+		plan.report(s)
+	}
+	return nil
+}
+
+// planReset checks the user may reset scope and counts the rows it deletes
+func planReset(s *state, user database.User, scope, target string) (resetPlan, error) {
+	ctx := context.Background()
+	needsTarget := scope == resetUser || scope == resetFeed
+	if needsTarget && target == "" {
+		return resetPlan{}, fmt.Errorf("reset %s needs the %s to reset, ex gator admin reset %s <%s>", scope, scope, scope, scope)
+	}
+	if !needsTarget && target != "" {
+		return resetPlan{}, fmt.Errorf("reset %s takes no target, recieved %q", scope, target)
+	}
+
+	switch scope {
+	case resetAll:
+		if !user.IsAdmin {
+			return resetPlan{}, errNotAdmin("reset the whole database")
+		}
+		users, err := s.db.GetUsers(ctx)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to get users %v", err)
+		}
+		feeds, err := s.db.GetAllFeeds(ctx)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to get feeds %v", err)
+		}
+		follows, err := s.db.GetFeedFollows(ctx)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to get feed follows %v", err)
+		}
+		posts, err := s.db.CountPosts(ctx)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to count posts %v", err)
+		}
+		return resetPlan{
+			what: "the whole database",
+			counts: []tableCount{
+				{"users", len(users)},
+				{"feeds", len(feeds)},
+				{"follows", len(follows)},
+				{"posts", int(posts)},
+			},
+			run: func(tx *state) error {
+				if err := tx.db.DeleteAllUsers(ctx); err != nil {
+					return err
+				}
+				// feeds outlive the users who added them
+				return tx.db.DeleteAllFeeds(ctx)
+			},
+		}, nil
+
+	case resetPosts:
+		if !user.IsAdmin {
+			return resetPlan{}, errNotAdmin("reset the posts of every feed")
+		}
+		posts, err := s.db.CountPosts(ctx)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to count posts %v", err)
+		}
+		return resetPlan{
+			what:   "every post",
+			counts: []tableCount{{"posts", int(posts)}},
+			run: func(tx *state) error {
+				if _, err := tx.db.DeleteAllPosts(ctx); err != nil {
+					return err
+				}
+				return tx.db.MarkAllFeedsUnfetched(ctx)
+			},
+		}, nil
+
+	case resetUser:
+		other, err := s.db.GetUser(ctx, target)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to find user in database %s", target)
+		}
+		if other.ID != user.ID && !user.IsAdmin {
+			return resetPlan{}, errNotAdmin("reset another user")
+		}
+		follows, err := s.db.GetFeedFollowsForUser(ctx, other.ID)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to get feed follows %v", err)
+		}
+		categories, err := s.db.GetCategoriesForUser(ctx, other.ID)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to get categories %v", err)
+		}
+		rules, err := s.db.GetRulesForUser(ctx, other.ID)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to get rules %v", err)
+		}
+		states, err := s.db.CountPostStatesForUser(ctx, other.ID)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to count post states %v", err)
+		}
+		policy, err := unfollowedPolicy(s.config.Retention)
+		if err != nil {
+			return resetPlan{}, err
+		}
+		followed := map[uuid.UUID]bool{}
+		for _, f := range follows {
+			followed[f.FeedID] = true
+		}
+		collect, err := collectCounts(s, followed, policy)
+		if err != nil {
+			return resetPlan{}, err
+		}
+		var collected []collectedFeed
+		return resetPlan{
+			what: fmt.Sprintf("user %s", other.Name),
+			counts: append([]tableCount{
+				{"follows", len(follows)},
+				{"categories", len(categories)},
+				{"rules", len(rules)},
+				{"post states", int(states)},
+			}, collect...),
+			run: func(tx *state) error {
+				for _, del := range []func(context.Context, uuid.UUID) (int64, error){
+					tx.db.DeletePostStatesForUser,
+					tx.db.DeleteRulesForUser,
+					tx.db.DeleteCategoriesForUser,
+					tx.db.DeleteFeedFollowsForUser,
+				} {
+					if _, err := del(ctx, other.ID); err != nil {
+						return err
+					}
+				}
+				// the feeds nobody follows anymore go like they do on unfollow
+				collected, err = collectFeeds(tx, followed, policy, false)
+				return err
+			},
+			report: func(s *state) { reportCollected(s, collected, policy) },
+		}, nil
+
+	case resetFeed:
+		feed, err := getFeedByRef(s, user, target)
+		if err != nil {
+			return resetPlan{}, err
+		}
+		if err := checkAddedBy(feed, user, "reset"); err != nil {
+			return resetPlan{}, err
+		}
+		posts, err := s.db.CountPostsForFeed(ctx, feed.ID)
+		if err != nil {
+			return resetPlan{}, fmt.Errorf("unable to count posts %v", err)
+		}
+		return resetPlan{
+			what:   fmt.Sprintf("feed %s", feed.Name),
+			counts: []tableCount{{"posts", int(posts)}},
+			run: func(tx *state) error {
+				if _, err := tx.db.DeletePostsForFeed(ctx, feed.ID); err != nil {
+					return err
+				}
+				return tx.db.MarkFeedUnfetched(ctx, feed.ID)
+			},
+		}, nil
+	}
+	return resetPlan{}, fmt.Errorf("unknown reset %q", scope)
+}
+
+// errNotAdmin explains that only admins may do something
+func errNotAdmin(action string) error {
+	return fmt.Errorf("only admins can %s, the first user registered is an admin and can make others admins with 'gator user admin <name> true'", action)
+}
+
+// confirm asks a yes or no question and reports whether it was answered yes. It reads s.in when set and
+// the terminal otherwise, without a terminal to ask on it fails rather than assuming an answer.
+func confirm(s *state, question string) (bool, error) {
+	in := s.in
+	if in == nil {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return false, errors.New("gator: unable to ask for confirmation without a terminal, pass --yes to go ahead")
+		}
+		in = os.Stdin
+	}
+
+	s.ui.Prompt(question + " [y/N]")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("unable to read the answer %v", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
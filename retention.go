@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/joshhartwig/gator/internal/config"
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+// safety limits used when the config does not set them, prune never removes
+// the newest posts of a feed or posts that were only just fetched
+const (
+	defaultKeepNewest = 10
+	defaultMinAge     = 24 * time.Hour
+)
+
+// retentionPolicy is the resolved retention for a single feed, zero limits are disabled
+type retentionPolicy struct {
+	maxAge     time.Duration
+	maxPosts   int
+	keepNewest int
+	minAge     time.Duration
+}
+
+// policyForFeed combines the global retention from the config with the feed's own overrides
+func policyForFeed(cfg config.Retention, feed database.Feed) (retentionPolicy, error) {
+	p := retentionPolicy{
+		maxPosts:   cfg.Max_Posts_Per_Feed,
+		keepNewest: cfg.Keep_Newest,
+		minAge:     defaultMinAge,
+	}
+	if p.keepNewest <= 0 {
+		p.keepNewest = defaultKeepNewest
+	}
+
+	var err error
+	if cfg.Max_Age != "" {
+		if p.maxAge, err = time.ParseDuration(cfg.Max_Age); err != nil {
+			return p, fmt.Errorf("invalid retention max_age in config %q", cfg.Max_Age)
+		}
+	}
+	if cfg.Min_Age != "" {
+		if p.minAge, err = time.ParseDuration(cfg.Min_Age); err != nil {
+			return p, fmt.Errorf("invalid retention min_age in config %q", cfg.Min_Age)
+		}
+	}
+
+	if feed.RetentionMaxAge.Valid {
+		if p.maxAge, err = time.ParseDuration(feed.RetentionMaxAge.String); err != nil {
+			return p, fmt.Errorf("invalid retention max_age %q on feed %s", feed.RetentionMaxAge.String, feed.Name)
+		}
+	}
+	if feed.RetentionMaxPosts.Valid {
+		p.maxPosts = int(feed.RetentionMaxPosts.Int32)
+	}
+	return p, nil
+}
+
+// enabled reports whether the policy can remove anything at all
+func (p retentionPolicy) enabled() bool {
+	return p.maxAge > 0 || p.maxPosts > 0
+}
+
+// params converts the policy into the arguments of the prune queries
+func (p retentionPolicy) params(feed database.Feed, now time.Time) database.GetPrunablePostsParams {
+	params := database.GetPrunablePostsParams{
+		FeedID:       feed.ID,
+		KeepNewest:   int64(p.keepNewest),
+		MinAgeCutoff: now.Add(-p.minAge),
+		MaxAgeCutoff: time.Time{},
+		MaxPosts:     math.MaxInt64,
+	}
+	if p.maxAge > 0 {
+		params.MaxAgeCutoff = now.Add(-p.maxAge)
+	}
+	if p.maxPosts > 0 {
+		params.MaxPosts = int64(p.maxPosts)
+	}
+	return params
+}
+
+// pruneFeed removes the posts of a feed that fall outside its retention policy.
+// With dryRun set the posts are only listed and the number that would be deleted is returned.
+func pruneFeed(s *state, feed database.Feed, dryRun bool) (int64, error) {
+	policy, err := policyForFeed(s.config.Retention, feed)
+	if err != nil {
+		return 0, err
+	}
+	if !policy.enabled() {
+		return 0, nil
+	}
+
+	params := policy.params(feed, time.Now())
+	if dryRun {
+		posts, err := s.db.GetPrunablePosts(context.Background(), params)
+		if err != nil {
+			return 0, fmt.Errorf("unable to get posts to prune for %s %v", feed.Name, err)
+		}
+		for _, p := range posts {
+			s.ui.Column("  - %s\t%s\t%s\t\n", feed.Name, p.Title, p.PublishedAt)
+		}
+		return int64(len(posts)), nil
+	}
+
+	n, err := s.db.DeletePrunablePosts(context.Background(), database.DeletePrunablePostsParams(params))
+	if err != nil {
+		return 0, fmt.Errorf("error pruning posts for %s %v", feed.Name, err)
+	}
+	return n, nil
+}
+
+// handlerPrune deletes old posts from every feed according to the retention settings.
+// With --dry-run the posts are listed instead of deleted. Only admins may run it.
+func handlerPrune(s *state, cmd command, user database.User) error {
+	if !user.IsAdmin {
+		return errNotAdmin("prune the posts of every feed")
+	}
+	dryRun := cmd.Bool("dry-run")
+
+	feeds, err := s.db.GetAllFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to get feeds %v", err)
+	}
+
+	if dryRun {
+		s.ui.Header("Prune Posts (dry run)")
+	} else {
+		s.ui.Header("Prune Posts")
+	}
+
+	// every feed is pruned or none are
+	deleted := make([]int64, len(feeds))
+	err = inTx(s, func(tx *state) error {
+		for i, f := range feeds {
+			deleted[i], err = pruneFeed(tx, f, dryRun)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for i, f := range feeds {
+		if deleted[i] > 0 && !dryRun {
+			s.ui.Column("%s\t%d posts deleted\t\n", f.Name, deleted[i])
+		}
+		total += deleted[i]
+	}
+
+	if dryRun {
+		s.ui.Info(fmt.Sprintf("%d posts would be deleted", total))
+	} else {
+		s.ui.Info(fmt.Sprintf("%d posts deleted", total))
+	}
+	return nil
+}
+
+// handlerSetRetention overrides the global retention for a single feed, only the user who added the feed and
+// admins may change it. A value of default removes the override.
+func handlerSetRetention(s *state, cmd command, user database.User) error {
+	feed, err := getFeedByRef(s, user, cmd.String("feed"))
+	if err != nil {
+		return err
+	}
+	if err := checkAddedBy(feed, user, "change the retention of"); err != nil {
+		return err
+	}
+
+	maxAge := feed.RetentionMaxAge
+	maxPosts := feed.RetentionMaxPosts
+	value := cmd.String("value")
+
+	switch cmd.String("setting") {
+	case "max_age":
+		if value == "default" {
+			maxAge = sql.NullString{}
+			break
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("max_age should be a duration (ex 720h), recieved %q", value)
+		}
+		maxAge = sql.NullString{String: value, Valid: true}
+	case "max_posts":
+		if value == "default" {
+			maxPosts = sql.NullInt32{}
+			break
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("max_posts should be a positive whole number, recieved %q", value)
+		}
+		maxPosts = sql.NullInt32{Int32: int32(n), Valid: true}
+	}
+
+	s.ui.Header("Set Feed Retention")
+	updated, err := s.db.SetFeedRetention(context.Background(), database.SetFeedRetentionParams{
+		ID:                feed.ID,
+		RetentionMaxAge:   maxAge,
+		RetentionMaxPosts: maxPosts,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting feed retention %v", err)
+	}
+
+	s.ui.Item("Feed %s: max_age %s max_posts %s", updated.Name, describeNullString(updated.RetentionMaxAge), describeNullInt(updated.RetentionMaxPosts))
+	return nil
+}
+
+func describeNullString(v sql.NullString) string {
+	if !v.Valid {
+		return "default"
+	}
+	return v.String
+}
+
+func describeNullInt(v sql.NullInt32) string {
+	if !v.Valid {
+		return "default"
+	}
+	return strconv.Itoa(int(v.Int32))
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/joshhartwig/gator/internal/database"
+	"github.com/joshhartwig/gator/internal/rules"
+	"github.com/joshhartwig/gator/internal/ui"
+)
+
+// handlerListRules prints the user's rules numbered in evaluation order.
+// Rules are evaluated in the order they were added every time agg inserts a post.
+func handlerListRules(s *state, cmd command, user database.User) error {
+	dbRules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("unable to get rules for user %v", err)
+	}
+
+	if len(dbRules) == 0 && !s.ui.Structured() {
+		s.ui.Header("Rules")
+		s.ui.Info("No rules yet, add one with 'rule add'")
+		return nil
+	}
+
+	t := ui.Table{
+		Title:   "Rules",
+		Columns: []string{"number", "rule", "field", "match", "pattern", "case_sensitive", "action", "replacement"},
+		Hidden:  []string{"field", "match", "pattern", "case_sensitive", "action", "replacement"},
+	}
+	for i, r := range dbRules {
+		var replacement any
+		if r.Replacement.Valid {
+			replacement = r.Replacement.String
+		}
+		t.Add(i+1, describeRule(toRule(r)), r.Field, r.MatchType, r.Pattern, r.CaseSensitive, r.Action, replacement)
+	}
+	return s.ui.Table(t)
+}
+
+// handlerAddRule validates and stores a new rule for the user
+func handlerAddRule(s *state, cmd command, user database.User) error {
+	rule, err := parseRuleArgs(cmd.args, cmd.Bool("case-sensitive"))
+	if err != nil {
+		return err
+	}
+
+	s.ui.Header("Add Rule")
+	_, err = s.db.CreateRule(context.Background(), database.CreateRuleParams{
+		ID:            uuid.New(),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		UserID:        user.ID,
+		Field:         rule.Field,
+		MatchType:     rule.Match,
+		Pattern:       rule.Pattern,
+		CaseSensitive: rule.CaseSensitive,
+		Action:        rule.Action,
+		Replacement:   sql.NullString{String: rule.Replacement, Valid: rule.Action == rules.ActionRewrite},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating rule %v", err)
+	}
+
+	s.ui.Item("Rule created: %s", describeRule(rule))
+	s.ui.Info("New rules apply to posts fetched from now on, run 'rule apply' to update existing posts")
+	return nil
+}
+
+// handlerRemoveRule deletes a rule by its number in 'rule list'
+func handlerRemoveRule(s *state, cmd command, user database.User) error {
+	dbRules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("unable to get rules for user %v", err)
+	}
+	r, err := ruleByNumber(dbRules, cmd.String("number"))
+	if err != nil {
+		return err
+	}
+
+	s.ui.Header("Remove Rule")
+	if err := s.db.DeleteRule(context.Background(), r.ID); err != nil {
+		return fmt.Errorf("error deleting rule %v", err)
+	}
+	s.ui.Item("Rule removed: %s", describeRule(toRule(r)))
+	return nil
+}
+
+// handlerTestRules previews which of the user's posts a rule would match without changing anything.
+// With no arguments every rule is tested, a number tests one rule and a rule definition tests a rule before adding it.
+func handlerTestRules(s *state, cmd command, user database.User) error {
+	args := cmd.List("rule")
+	var set []rules.Rule
+	switch {
+	case len(args) == 0:
+		dbRules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("unable to get rules for user %v", err)
+		}
+		for _, r := range dbRules {
+			set = append(set, toRule(r))
+		}
+	case len(args) == 1:
+		dbRules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("unable to get rules for user %v", err)
+		}
+		r, err := ruleByNumber(dbRules, args[0])
+		if err != nil {
+			return err
+		}
+		set = append(set, toRule(r))
+	default:
+		rule, err := parseRuleArgs(args, cmd.Bool("case-sensitive"))
+		if err != nil {
+			return err
+		}
+		set = append(set, rule)
+	}
+
+	if err := compileRules(set); err != nil {
+		return err
+	}
+
+	posts, err := s.db.GetAllPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("unable to get posts for user %v", err)
+	}
+
+	t := ui.Table{
+		Title:   "Test Rules",
+		Columns: []string{"id", "feed_name", "title", "result"},
+		Hidden:  []string{"id"},
+		Roles:   map[string]ui.Role{"feed_name": ui.RoleFeedName},
+	}
+	for _, p := range posts {
+		res := rules.Evaluate(set, postForRules(p))
+		if !res.Changed() {
+			continue
+		}
+		t.Add(p.ID, p.FeedName, p.Title, describeResult(res))
+	}
+	if err := s.ui.Table(t); err != nil {
+		return err
+	}
+	s.ui.Info(fmt.Sprintf("%d of %d posts matched", len(t.Rows), len(posts)))
+	return nil
+}
+
+// handlerApplyRules re-evaluates the user's rules against every post already in the database.
+// Hidden, flagged and rewritten states are recomputed, deleted posts stay deleted.
+func handlerApplyRules(s *state, cmd command, user database.User) error {
+	dbRules, err := s.db.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("unable to get rules for user %v", err)
+	}
+	set := make([]rules.Rule, 0, len(dbRules))
+	for _, r := range dbRules {
+		set = append(set, toRule(r))
+	}
+	if err := compileRules(set); err != nil {
+		return err
+	}
+
+	posts, err := s.db.GetAllPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("unable to get posts for user %v", err)
+	}
+
+	s.ui.Header("Apply Rules")
+	// the states are reset and recomputed together, a failure part way keeps the old ones
+	matched := 0
+	err = inTx(s, func(tx *state) error {
+		if err := tx.db.ResetPostStatesForUser(context.Background(), user.ID); err != nil {
+			return fmt.Errorf("error resetting post states %v", err)
+		}
+		for _, p := range posts {
+			res := rules.Evaluate(set, postForRules(p))
+			if !res.Changed() {
+				continue
+			}
+			if err := savePostState(tx, user.ID, p.ID, res); err != nil {
+				return err
+			}
+			matched++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.ui.Info(fmt.Sprintf("Rules applied, %d of %d posts matched", matched, len(posts)))
+	return nil
+}
+
+// loadFeedRules loads the rules of every user following a feed, grouped by user.
+// Rules that no longer compile are skipped so one bad rule cannot stop the aggregator.
+func loadFeedRules(s *state, feedID uuid.UUID) (map[uuid.UUID][]rules.Rule, map[uuid.UUID]string, error) {
+	rows, err := s.db.GetRulesForFeed(context.Background(), feedID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get rules for feed %v", err)
+	}
+
+	byUser := make(map[uuid.UUID][]rules.Rule)
+	feedNames := make(map[uuid.UUID]string)
+	for _, r := range rows {
+		rule := rules.Rule{
+			Field:         r.Field,
+			Match:         r.MatchType,
+			Pattern:       r.Pattern,
+			CaseSensitive: r.CaseSensitive,
+			Action:        r.Action,
+			Replacement:   r.Replacement.String,
+		}
+		if err := rule.Compile(); err != nil {
+			continue
+		}
+		byUser[r.UserID] = append(byUser[r.UserID], rule)
+		feedNames[r.UserID] = r.FeedName
+	}
+	return byUser, feedNames, nil
+}
+
+// savePostState records the outcome of the rules for a single user and post
+func savePostState(s *state, userID, postID uuid.UUID, res rules.Result) error {
+	err := s.db.UpsertPostState(context.Background(), database.UpsertPostStateParams{
+		UserID:    userID,
+		PostID:    postID,
+		UpdatedAt: time.Now(),
+		Hidden:    res.Hidden,
+		Deleted:   res.Deleted,
+		Flagged:   res.Flagged,
+		Title:     sql.NullString{String: res.Title, Valid: res.Title != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("error saving post state %v", err)
+	}
+	return nil
+}
+
+// parseRuleArgs builds a rule from the arguments of 'rule add' and 'rule test'
+func parseRuleArgs(positional []string, caseSensitive bool) (rules.Rule, error) {
+	rule := rules.Rule{CaseSensitive: caseSensitive}
+
+	if len(positional) < 4 || len(positional) > 5 {
+		return rule, fmt.Errorf("a rule needs a field, match, pattern and action, see 'gator help rule add'")
+	}
+
+	rule.Field = positional[0]
+	rule.Match = positional[1]
+	rule.Pattern = positional[2]
+	rule.Action = positional[3]
+	if len(positional) == 5 {
+		rule.Replacement = positional[4]
+	}
+
+	if rule.Action == rules.ActionRewrite && len(positional) != 5 {
+		return rule, fmt.Errorf("the rewrite action needs a replacement title")
+	}
+	if rule.Action != rules.ActionRewrite && len(positional) == 5 {
+		return rule, fmt.Errorf("only the rewrite action takes a replacement")
+	}
+
+	if err := rule.Compile(); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+// ruleByNumber returns the rule at the 1 based position shown by 'rule list'
+func ruleByNumber(dbRules []database.Rule, arg string) (database.Rule, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(dbRules) {
+		return database.Rule{}, fmt.Errorf("no rule number %s, see 'rule list'", arg)
+	}
+	return dbRules[n-1], nil
+}
+
+func compileRules(set []rules.Rule) error {
+	for i := range set {
+		if err := set[i].Compile(); err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func toRule(r database.Rule) rules.Rule {
+	return rules.Rule{
+		Field:         r.Field,
+		Match:         r.MatchType,
+		Pattern:       r.Pattern,
+		CaseSensitive: r.CaseSensitive,
+		Action:        r.Action,
+		Replacement:   r.Replacement.String,
+	}
+}
+
+func postForRules(p database.GetAllPostsForUserRow) rules.Post {
+	return rules.Post{
+		Title:       p.Title,
+		Description: p.Description.String,
+		Author:      p.Author.String,
+		Category:    p.Category.String,
+		Feed:        p.FeedName,
+		URL:         p.Url,
+	}
+}
+
+func describeRule(r rules.Rule) string {
+	desc := fmt.Sprintf("%s %s %q -> %s", r.Field, r.Match, r.Pattern, r.Action)
+	if r.Action == rules.ActionRewrite {
+		desc += fmt.Sprintf(" %q", r.Replacement)
+	}
+	if r.CaseSensitive {
+		desc += " (case sensitive)"
+	}
+	return desc
+}
+
+func describeResult(res rules.Result) string {
+	actions := []string{}
+	if res.Deleted {
+		actions = append(actions, rules.ActionDelete)
+	}
+	if res.Hidden {
+		actions = append(actions, rules.ActionHide)
+	}
+	if res.Flagged {
+		actions = append(actions, rules.ActionFlag)
+	}
+	if res.Title != "" {
+		actions = append(actions, fmt.Sprintf("%s %q", rules.ActionRewrite, res.Title))
+	}
+	return strings.Join(actions, ", ")
+}
+
+// flaggedTitle marks the titles of posts flagged by a rule
+func flaggedTitle(title string, flagged bool) string {
+	if flagged {
+		return "[!] " + title
+	}
+	return title
+}
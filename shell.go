@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joshhartwig/gator/internal/ui"
+	"golang.org/x/term"
+)
+
+const (
+	historyFileName = ".gator_history"
+	maxHistory      = 1000
+)
+
+// errQuit is returned by the quit command to end a shell session
+var errQuit = errors.New("quit")
+
+// handlerShell starts an interactive session that reuses the open database connection.
+// Each line is dispatched through commands.run, quit or ctrl-d ends the session.
+// When stdin is not a terminal the lines are read and run without line editing.
+func (c *commands) handlerShell(s *state, cmd command) error {
+	if c.inShell {
+		return fmt.Errorf("gator: already in a shell session")
+	}
+	c.inShell = true
+	defer func() { c.inShell = false }()
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return c.runLines(s, os.Stdin)
+	}
+
+	history, err := loadHistory()
+	if err != nil {
+		s.ui.Warn(fmt.Sprintf("unable to load shell history %v", err))
+	}
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "")
+	t.History = history
+	t.AutoCompleteCallback = c.shellComplete(s, t)
+
+	s.ui.Info("Type 'help' for a list of commands, 'quit' or ctrl-d to leave")
+	for {
+		if width, height, err := term.GetSize(fd); err == nil && width > 0 {
+			t.SetSize(width, height)
+		}
+		t.SetPrompt(shellPrompt(s))
+
+		old, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("unable to start the shell %v", err)
+		}
+		line, err := t.ReadLine()
+		term.Restore(fd, old)
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read input %v", err)
+		}
+
+		if err := c.runLine(s, line); errors.Is(err, errQuit) {
+			return nil
+		} else if err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// runLines runs every line read from r, it stops at the first error or at quit
+func (c *commands) runLines(s *state, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if err := c.runLine(s, scanner.Text()); errors.Is(err, errQuit) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// runLine splits a line into words and runs it like a command given on the command line,
+// global flags such as --output apply to that line only
+func (c *commands) runLine(s *state, line string) error {
+	words, err := splitWords(line)
+	if err != nil {
+		return fmt.Errorf("gator: %v", err)
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	words, globals, err := extractGlobalFlags(words)
+	if err != nil {
+		return err
+	}
+	format, _ := ui.ParseFormat(globals["output"])
+	s.ui.SetFormat(format)
+	defer s.ui.SetFormat(ui.FormatTable)
+	if globals["no-color"] == "true" {
+		defer s.ui.SetColor(s.ui.Color())
+		s.ui.SetColor(false)
+	}
+	if globals["no-pager"] == "true" {
+		defer s.ui.SetPager(s.ui.Pager())
+		s.ui.SetPager("")
+	}
+
+	if len(words) == 0 {
+		return nil
+	}
+	return c.run(s, command{name: words[0], args: words[1:]})
+}
+
+// shellComplete completes the word before the cursor on tab.
+// A single candidate is filled in, otherwise the common prefix is filled in and the candidates are listed.
+func (c *commands) shellComplete(s *state, t *term.Terminal) func(string, int, rune) (string, int, bool) {
+	return func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+
+		before := line[:pos]
+		words, _ := splitWords(before)
+		if before == "" || strings.HasSuffix(before, " ") {
+			words = append(words, "")
+		}
+		cur := words[len(words)-1]
+
+		candidates := []string{}
+		for _, candidate := range c.completions(s, words) {
+			value, _, _ := strings.Cut(candidate, "\t")
+			candidates = append(candidates, value)
+		}
+		if len(candidates) == 0 {
+			return "", 0, false
+		}
+
+		replacement := commonPrefix(candidates)
+		if len(candidates) == 1 {
+			replacement = quoteWord(replacement) + " "
+		} else if replacement == cur {
+			fmt.Fprintf(t, "%s\r\n", strings.Join(candidates, "  "))
+			return "", 0, false
+		}
+
+		// the raw word may include quotes, so replace everything after the last space
+		start := strings.LastIndexAny(before, " \t") + 1
+		newLine := before[:start] + replacement + line[pos:]
+		return newLine, start + len(replacement), true
+	}
+}
+
+func shellPrompt(s *state) string {
+	if s.config.Current_User_Name == "" {
+		return "gator> "
+	}
+	return fmt.Sprintf("gator (%s)> ", s.config.Current_User_Name)
+}
+
+// splitWords splits a line into words the way a shell would, single and double quotes group words
+// and a backslash escapes the next character outside single quotes
+func splitWords(line string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if quote != 0 {
+		return words, fmt.Errorf("unterminated %c quote", quote)
+	}
+	return words, nil
+}
+
+// quoteWord quotes a completed word that contains spaces or quotes so it splits back into one word
+func quoteWord(w string) string {
+	if !strings.ContainsAny(w, " \t'\"\\") {
+		return w
+	}
+	return "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// fileHistory keeps the shell history in memory and appends every new entry to ~/.gator_history, the file
+// is rewritten with the kept entries whenever the oldest are dropped so it stays at maxHistory lines
+type fileHistory struct {
+	entries []string
+	path    string
+}
+
+func loadHistory() (*fileHistory, error) {
+	h := &fileHistory{}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return h, err
+	}
+	h.path = filepath.Join(home, historyFileName)
+
+	data, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		return h, h.save()
+	}
+	return h, nil
+}
+
+// Add records an entry, blank lines and repeats of the last entry are skipped
+func (h *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+		h.save()
+		return
+	}
+
+	if h.path == "" {
+		return
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, entry)
+}
+
+// save rewrites the history file with the entries kept in memory
+func (h *fileHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	return os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+}
+
+func (h *fileHistory) Len() int { return len(h.entries) }
+
+// At returns an entry, 0 is the most recent
+func (h *fileHistory) At(idx int) string { return h.entries[len(h.entries)-1-idx] }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistoryFileIsTrimmed(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, historyFileName)
+
+	var lines []string
+	for i := range maxHistory + 5 {
+		lines = append(lines, fmt.Sprintf("feed list %d", i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := loadHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := historyLines(t, path)
+	if len(saved) != maxHistory || saved[0] != "feed list 5" {
+		t.Errorf("wanted the file trimmed to the last %d entries got %d starting with %q", maxHistory, len(saved), saved[0])
+	}
+
+	// once full every new entry drops the oldest from the file too
+	h.Add("post browse")
+	saved = historyLines(t, path)
+	if len(saved) != maxHistory || saved[0] != "feed list 6" || saved[len(saved)-1] != "post browse" {
+		t.Errorf("wanted the file kept at %d entries ending with post browse got %d from %q to %q", maxHistory, len(saved), saved[0], saved[len(saved)-1])
+	}
+}
+
+func historyLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
@@ -0,0 +1,76 @@
+-- name: CreateCategory :one
+INSERT INTO categories (id, created_at, updated_at, name, user_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetCategoriesForUser :many
+SELECT *
+FROM categories
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetCategoryFeedCountsForUser :many
+SELECT
+  categories.name,
+  COUNT(feed_follow_categories.feed_follow_id) AS feeds
+FROM categories
+LEFT JOIN feed_follow_categories ON feed_follow_categories.category_id = categories.id
+WHERE categories.user_id = $1
+GROUP BY categories.id, categories.name
+ORDER BY categories.name;
+
+-- name: GetCategoryByName :one
+SELECT *
+FROM categories
+WHERE user_id = $1
+  AND name = $2;
+
+-- name: RenameCategory :one
+UPDATE categories
+SET
+  name = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteCategory :exec
+DELETE FROM categories
+WHERE id = $1;
+
+-- name: AddFeedFollowToCategory :exec
+INSERT INTO feed_follow_categories (feed_follow_id, category_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveFeedFollowFromCategory :exec
+DELETE FROM feed_follow_categories
+WHERE feed_follow_id = $1
+  AND category_id = $2;
+
+-- name: GetFeedFollowCategoriesForUser :many
+SELECT
+  feed_follow_categories.feed_follow_id,
+  categories.name AS category_name
+FROM feed_follow_categories
+INNER JOIN categories ON categories.id = feed_follow_categories.category_id
+WHERE categories.user_id = $1
+ORDER BY categories.name;
+
+-- name: GetAllCategories :many
+SELECT *
+FROM categories
+ORDER BY created_at, id;
+
+-- name: GetAllFeedFollowCategories :many
+SELECT *
+FROM feed_follow_categories;
+
+-- name: DeleteCategoriesForUser :execrows
+DELETE FROM categories
+WHERE user_id = $1;
@@ -20,7 +20,7 @@ SELECT
   feed_follows.user_id,
   feed_follows.feed_id,
   users.name as user_name,
-  feeds.name as feed_name
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name
 FROM feed_follows
 INNER JOIN users ON users.id = feed_follows.user_id
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id;
@@ -32,15 +32,86 @@ SELECT
   feed_follows.updated_at,
   feed_follows.user_id,
   feed_follows.feed_id,
+  feed_follows.display_name,
+  feed_follows.priority,
+  feed_follows.muted,
+  feed_follows.notify,
   users.name as user_name,
-  feeds.name as feed_name,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name,
   feeds.url as feed_url
 FROM feed_follows
 INNER JOIN users ON users.id = feed_follows.user_id
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1;
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.priority DESC, feed_name;
 
 -- name: DeleteFeedFollowForUser :exec
 DELETE FROM feed_follows 
 WHERE feed_follows.user_id = $1 
-  AND feed_follows.feed_id = $2;
\ No newline at end of file
+  AND feed_follows.feed_id = $2;
+
+-- name: GetFeedFollowsForUserInCategory :many
+SELECT
+  feed_follows.id,
+  feed_follows.created_at,
+  feed_follows.updated_at,
+  feed_follows.user_id,
+  feed_follows.feed_id,
+  feed_follows.display_name,
+  feed_follows.priority,
+  feed_follows.muted,
+  feed_follows.notify,
+  users.name as user_name,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name,
+  feeds.url as feed_url
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+INNER JOIN feed_follow_categories ON feed_follow_categories.feed_follow_id = feed_follows.id
+WHERE feed_follows.user_id = $1
+  AND feed_follow_categories.category_id = $2
+ORDER BY feed_follows.priority DESC, feed_name;
+
+-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET
+  display_name = $2,
+  priority = $3,
+  muted = $4,
+  notify = $5,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetNotifiedFollowersForFeed :many
+SELECT
+  users.name as user_name,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.feed_id = $1
+  AND feed_follows.notify
+  AND NOT feed_follows.muted;
+-- name: GetAllFeedFollows :many
+SELECT *
+FROM feed_follows
+ORDER BY created_at, id;
+
+-- name: RestoreFeedFollow :one
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, display_name, priority, muted, notify)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: DeleteFeedFollowsForUser :execrows
+DELETE FROM feed_follows
+WHERE user_id = $1;
+
+-- name: DeleteFeedFollowsForFeed :execrows
+DELETE FROM feed_follows
+WHERE feed_id = $1;
+
+-- name: CountFeedFollowsForFeed :one
+SELECT COUNT(*)
+FROM feed_follows
+WHERE feed_id = $1;
@@ -1,5 +1,5 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, added_by)
 VALUES (
     $1,
     $2,
@@ -17,21 +17,22 @@ SELECT
   feeds.updated_at,
   feeds.name,
   feeds.url,
-  feeds.user_id,
-  users.name AS user_name
+  feeds.added_by,
+  users.name AS added_by_name,
+  (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.feed_id = feeds.id) AS followers
 FROM feeds
-JOIN users ON users.id = feeds.user_id;
+LEFT JOIN users ON users.id = feeds.added_by
+ORDER BY feeds.name;
 
 -- name: GetFeedByUrl :one
-SELECT
-  feeds.id,
-  feeds.created_at,
-  feeds.updated_at,
-  feeds.name,
-  feeds.url,
-  feeds.user_id
+SELECT *
 FROM feeds
-WHERE feeds.url = $1;
+WHERE url = $1;
+
+-- name: GetFeed :one
+SELECT *
+FROM feeds
+WHERE id = $1;
 
 -- name: MarkFeedFetched :one
 UPDATE feeds
@@ -44,5 +45,75 @@ RETURNING *;
 -- name: GetNextFeedToFetch :one
 SELECT *
 FROM feeds
+WHERE EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: GetAllFeeds :many
+SELECT *
+FROM feeds
+ORDER BY name;
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET
+  retention_max_age = $2,
+  retention_max_posts = $3,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RenameFeed :one
+UPDATE feeds
+SET
+  name = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedUrl :one
+UPDATE feeds
+SET
+  url = $2,
+  last_fetched_at = NULL,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RestoreFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: MarkFeedUnfetched :exec
+UPDATE feeds
+SET
+  last_fetched_at = NULL,
+  updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkAllFeedsUnfetched :exec
+UPDATE feeds
+SET
+  last_fetched_at = NULL,
+  updated_at = NOW();
+
+-- name: TransferFeeds :execrows
+UPDATE feeds
+SET
+  added_by = sqlc.arg(to_user_id),
+  updated_at = NOW()
+WHERE added_by = sqlc.arg(from_user_id);
+
+-- name: GetUnfollowedFeeds :many
+SELECT *
+FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+ORDER BY name;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: DeleteAllFeeds :exec
+DELETE FROM feeds;
@@ -0,0 +1,85 @@
+-- name: AssignPostHandles :exec
+WITH new_posts AS (
+  SELECT
+    ids.post_id,
+    ROW_NUMBER() OVER (ORDER BY ids.position) as n
+  FROM
+    UNNEST(sqlc.arg(post_ids)::uuid[]) WITH ORDINALITY AS ids(post_id, position)
+  WHERE NOT EXISTS (
+    SELECT 1
+    FROM post_handles
+    WHERE post_handles.user_id = sqlc.arg(user_id)
+      AND post_handles.post_id = ids.post_id
+  )
+),
+counter AS (
+  INSERT INTO handle_counters (user_id, kind, last_handle)
+  VALUES (sqlc.arg(user_id), 'post', (SELECT COUNT(*) FROM new_posts))
+  ON CONFLICT (user_id, kind) DO UPDATE
+  SET last_handle = handle_counters.last_handle + EXCLUDED.last_handle
+  RETURNING last_handle - (SELECT COUNT(*) FROM new_posts) as first_handle
+)
+INSERT INTO post_handles (user_id, post_id, handle)
+SELECT
+  sqlc.arg(user_id),
+  new_posts.post_id,
+  counter.first_handle + new_posts.n
+FROM new_posts, counter;
+
+-- name: GetPostHandles :many
+SELECT post_id, handle
+FROM post_handles
+WHERE user_id = $1
+  AND post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+
+-- name: GetPostByHandle :one
+SELECT posts.*
+FROM
+  posts
+  INNER JOIN post_handles ON post_handles.post_id = posts.id
+WHERE
+  post_handles.user_id = $1
+  AND post_handles.handle = $2;
+
+-- name: AssignFeedHandles :exec
+WITH new_feeds AS (
+  SELECT
+    ids.feed_id,
+    ROW_NUMBER() OVER (ORDER BY ids.position) as n
+  FROM
+    UNNEST(sqlc.arg(feed_ids)::uuid[]) WITH ORDINALITY AS ids(feed_id, position)
+  WHERE NOT EXISTS (
+    SELECT 1
+    FROM feed_handles
+    WHERE feed_handles.user_id = sqlc.arg(user_id)
+      AND feed_handles.feed_id = ids.feed_id
+  )
+),
+counter AS (
+  INSERT INTO handle_counters (user_id, kind, last_handle)
+  VALUES (sqlc.arg(user_id), 'feed', (SELECT COUNT(*) FROM new_feeds))
+  ON CONFLICT (user_id, kind) DO UPDATE
+  SET last_handle = handle_counters.last_handle + EXCLUDED.last_handle
+  RETURNING last_handle - (SELECT COUNT(*) FROM new_feeds) as first_handle
+)
+INSERT INTO feed_handles (user_id, feed_id, handle)
+SELECT
+  sqlc.arg(user_id),
+  new_feeds.feed_id,
+  counter.first_handle + new_feeds.n
+FROM new_feeds, counter;
+
+-- name: GetFeedHandles :many
+SELECT feed_id, handle
+FROM feed_handles
+WHERE user_id = $1
+  AND feed_id = ANY(sqlc.arg(feed_ids)::uuid[]);
+
+-- name: GetFeedByHandle :one
+SELECT feeds.*
+FROM
+  feeds
+  INNER JOIN feed_handles ON feed_handles.feed_id = feeds.id
+WHERE
+  feed_handles.user_id = $1
+  AND feed_handles.handle = $2;
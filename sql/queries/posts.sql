@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, category, canonical_url, simhash, cluster_id)
 VALUES (
     $1,
     $2,
@@ -8,26 +8,248 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13
 )
+ON CONFLICT (feed_id, url) DO NOTHING
 RETURNING *;
 
 -- name: GetPostsForUser :many
+WITH visible AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    COALESCE(user_post_states.title, posts.title) as title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    COALESCE(feed_follows.display_name, feeds.name) as feed_name,
+    COALESCE(user_post_states.flagged, FALSE) as flagged,
+    ROW_NUMBER() OVER (PARTITION BY posts.cluster_id ORDER BY posts.created_at, posts.id) as cluster_position,
+    ARRAY_AGG(COALESCE(feed_follows.display_name, feeds.name)) OVER (PARTITION BY posts.cluster_id) as cluster_feeds
+  FROM
+    posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+    INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+      AND user_post_states.user_id = feed_follows.user_id
+  WHERE
+    feed_follows.user_id = $1
+    AND NOT feed_follows.muted
+    AND NOT COALESCE(user_post_states.hidden, FALSE)
+    AND NOT COALESCE(user_post_states.deleted, FALSE)
+)
+SELECT
+  id,
+  created_at,
+  updated_at,
+  title,
+  url,
+  description,
+  published_at,
+  feed_name,
+  flagged,
+  cluster_feeds
+FROM visible
+WHERE cluster_position = 1
+  AND (
+    sqlc.narg(after)::uuid IS NULL
+    OR (updated_at, id) > (SELECT posts.updated_at, posts.id FROM posts WHERE posts.id = sqlc.narg(after)::uuid)
+  )
+ORDER BY
+  updated_at ASC,
+  id ASC
+LIMIT
+  $2;
+
+-- name: GetPostsForUserInCategory :many
+WITH visible AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    COALESCE(user_post_states.title, posts.title) as title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    COALESCE(feed_follows.display_name, feeds.name) as feed_name,
+    COALESCE(user_post_states.flagged, FALSE) as flagged,
+    ROW_NUMBER() OVER (PARTITION BY posts.cluster_id ORDER BY posts.created_at, posts.id) as cluster_position,
+    ARRAY_AGG(COALESCE(feed_follows.display_name, feeds.name)) OVER (PARTITION BY posts.cluster_id) as cluster_feeds
+  FROM
+    posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+    INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    INNER JOIN feed_follow_categories ON feed_follow_categories.feed_follow_id = feed_follows.id
+    LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+      AND user_post_states.user_id = feed_follows.user_id
+  WHERE
+    feed_follows.user_id = $1
+    AND feed_follow_categories.category_id = $2
+    AND NOT feed_follows.muted
+    AND NOT COALESCE(user_post_states.hidden, FALSE)
+    AND NOT COALESCE(user_post_states.deleted, FALSE)
+)
+SELECT
+  id,
+  created_at,
+  updated_at,
+  title,
+  url,
+  description,
+  published_at,
+  feed_name,
+  flagged,
+  cluster_feeds
+FROM visible
+WHERE cluster_position = 1
+  AND (
+    sqlc.narg(after)::uuid IS NULL
+    OR (updated_at, id) > (SELECT posts.updated_at, posts.id FROM posts WHERE posts.id = sqlc.narg(after)::uuid)
+  )
+ORDER BY
+  updated_at ASC,
+  id ASC
+LIMIT
+  $3;
+
+-- name: GetAllPostsForUser :many
 SELECT
   posts.id,
-  posts.created_at,
-  posts.updated_at,
   posts.title,
   posts.url,
   posts.description,
-  posts.published_at,
-  feeds.user_id as feed_user_id
+  posts.author,
+  posts.category,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name
 FROM
   posts
   INNER JOIN feeds ON feeds.id = posts.feed_id
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE
+  feed_follows.user_id = $1
+ORDER BY
+  posts.published_at DESC;
+
+-- name: GetPrunablePosts :many
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.title,
+    posts.created_at,
+    posts.published_at,
+    ROW_NUMBER() OVER (ORDER BY posts.published_at DESC, posts.created_at DESC) AS position
+  FROM posts
+  WHERE posts.feed_id = $1
+)
+SELECT id, title, published_at
+FROM ranked
+WHERE position > sqlc.arg(keep_newest)::bigint
+  AND created_at < sqlc.arg(min_age_cutoff)::timestamp
+  AND (
+    published_at < sqlc.arg(max_age_cutoff)::timestamp
+    OR position > sqlc.arg(max_posts)::bigint
+  )
+ORDER BY published_at DESC;
+
+-- name: DeletePrunablePosts :execrows
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.published_at,
+    ROW_NUMBER() OVER (ORDER BY posts.published_at DESC, posts.created_at DESC) AS position
+  FROM posts
+  WHERE posts.feed_id = $1
+)
+DELETE FROM posts
+USING ranked
+WHERE posts.id = ranked.id
+  AND ranked.position > sqlc.arg(keep_newest)::bigint
+  AND ranked.created_at < sqlc.arg(min_age_cutoff)::timestamp
+  AND (
+    ranked.published_at < sqlc.arg(max_age_cutoff)::timestamp
+    OR ranked.position > sqlc.arg(max_posts)::bigint
+  );
+
+-- name: GetDuplicateCandidates :many
+SELECT id, title, canonical_url, simhash, cluster_id
+FROM posts
+WHERE feed_id <> $1
+  AND (canonical_url = $2 OR created_at > $3)
+ORDER BY created_at DESC
+LIMIT 500;
+-- name: GetPostsForFeed :many
+SELECT
+  posts.id,
+  COALESCE(user_post_states.title, posts.title) as title,
+  posts.url,
+  posts.description,
+  posts.author,
+  posts.published_at,
+  COALESCE(user_post_states.flagged, FALSE) as flagged
+FROM
+  posts
+  LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+    AND user_post_states.user_id = $1
 WHERE
-  feeds.user_id = $1
+  posts.feed_id = $2
+  AND NOT COALESCE(user_post_states.hidden, FALSE)
+  AND NOT COALESCE(user_post_states.deleted, FALSE)
 ORDER BY
-  posts.updated_at ASC
+  posts.published_at DESC
 LIMIT
-  $2;
\ No newline at end of file
+  $3;
+
+-- name: GetPost :one
+SELECT *
+FROM posts
+WHERE id = $1;
+
+-- name: GetLatestPostForUser :one
+SELECT posts.*
+FROM
+  posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+    AND user_post_states.user_id = feed_follows.user_id
+WHERE
+  feed_follows.user_id = $1
+  AND NOT feed_follows.muted
+  AND NOT COALESCE(user_post_states.hidden, FALSE)
+  AND NOT COALESCE(user_post_states.deleted, FALSE)
+ORDER BY
+  posts.published_at DESC
+LIMIT
+  1;
+
+-- name: GetAllPosts :many
+SELECT *
+FROM posts
+ORDER BY created_at, id;
+
+-- name: CountPosts :one
+SELECT COUNT(*)
+FROM posts;
+
+-- name: CountPostsForFeed :one
+SELECT COUNT(*)
+FROM posts
+WHERE feed_id = $1;
+
+-- name: CountPostsForFeedUrl :one
+SELECT COUNT(*)
+FROM posts
+WHERE feed_id = $1 AND url = $2;
+
+-- name: DeleteAllPosts :execrows
+DELETE FROM posts;
+
+-- name: DeletePostsForFeed :execrows
+DELETE FROM posts
+WHERE feed_id = $1;
@@ -0,0 +1,90 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, field, match_type, pattern, case_sensitive, action, replacement)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT *
+FROM rules
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteRule :exec
+DELETE FROM rules
+WHERE id = $1;
+
+-- name: GetRulesForFeed :many
+SELECT
+  rules.id,
+  rules.created_at,
+  rules.updated_at,
+  rules.user_id,
+  rules.field,
+  rules.match_type,
+  rules.pattern,
+  rules.case_sensitive,
+  rules.action,
+  rules.replacement,
+  COALESCE(feed_follows.display_name, feeds.name) as feed_name
+FROM rules
+INNER JOIN feed_follows ON feed_follows.user_id = rules.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.feed_id = $1
+ORDER BY rules.user_id, rules.created_at;
+
+-- name: UpsertPostState :exec
+INSERT INTO user_post_states (user_id, post_id, updated_at, hidden, deleted, flagged, title)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET
+  updated_at = EXCLUDED.updated_at,
+  hidden = EXCLUDED.hidden,
+  deleted = user_post_states.deleted OR EXCLUDED.deleted,
+  flagged = EXCLUDED.flagged,
+  title = EXCLUDED.title;
+
+-- name: ResetPostStatesForUser :exec
+UPDATE user_post_states
+SET
+  updated_at = NOW(),
+  hidden = FALSE,
+  flagged = FALSE,
+  title = NULL
+WHERE user_id = $1;
+-- name: GetAllRules :many
+SELECT *
+FROM rules
+ORDER BY created_at, id;
+
+-- name: GetAllPostStates :many
+SELECT *
+FROM user_post_states;
+
+-- name: RestorePostState :exec
+INSERT INTO user_post_states (user_id, post_id, updated_at, hidden, deleted, flagged, title)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: DeleteRulesForUser :execrows
+DELETE FROM rules
+WHERE user_id = $1;
+
+-- name: CountPostStatesForUser :one
+SELECT COUNT(*)
+FROM user_post_states
+WHERE user_id = $1;
+
+-- name: DeletePostStatesForUser :execrows
+DELETE FROM user_post_states
+WHERE user_id = $1;
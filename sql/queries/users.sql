@@ -1,15 +1,16 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, is_admin)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5
 )
 RETURNING *;
 
 -- name: GetUser :one
-SELECT id, created_at, updated_at, name
+SELECT *
 FROM users
 WHERE name = $1;
 
@@ -17,5 +18,25 @@ WHERE name = $1;
 DELETE FROM users;
 
 -- name: GetUsers :many
-SELECT id, created_at, updated_at, name
-FROM users;
\ No newline at end of file
+SELECT *
+FROM users;
+
+-- name: SetUserAdmin :one
+UPDATE users
+SET
+  is_admin = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RenameUser :one
+UPDATE users
+SET
+  name = $2,
+  updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
@@ -1,4 +1,4 @@
--- +goose up
+-- +goose Up
 ALTER TABLE feeds
 ADD COLUMN last_fetched_at TIMESTAMP;
 
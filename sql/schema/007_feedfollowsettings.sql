@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD COLUMN display_name TEXT,
+ADD COLUMN priority INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN muted BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN notify BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN display_name,
+DROP COLUMN priority,
+DROP COLUMN muted,
+DROP COLUMN notify;
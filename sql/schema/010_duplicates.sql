@@ -0,0 +1,37 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN canonical_url TEXT,
+ADD COLUMN simhash BIGINT,
+ADD COLUMN cluster_id UUID;
+
+-- existing posts start out in a cluster of their own
+UPDATE posts SET canonical_url = url, cluster_id = id;
+
+ALTER TABLE posts
+ALTER COLUMN canonical_url SET NOT NULL,
+ALTER COLUMN cluster_id SET NOT NULL;
+
+CREATE INDEX posts_canonical_url_idx ON posts (canonical_url);
+CREATE INDEX posts_cluster_id_idx ON posts (cluster_id);
+CREATE INDEX posts_created_at_idx ON posts (created_at);
+
+-- the same link can arrive through several feeds, each feed keeps its own copy in the cluster
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_url_key UNIQUE (feed_id, url);
+
+-- +goose Down
+-- only the earliest copy of a link is kept
+DELETE FROM posts a USING posts b
+WHERE a.url = b.url AND (a.created_at > b.created_at OR (a.created_at = b.created_at AND a.id > b.id));
+
+ALTER TABLE posts DROP CONSTRAINT posts_feed_id_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+
+DROP INDEX posts_created_at_idx;
+DROP INDEX posts_cluster_id_idx;
+DROP INDEX posts_canonical_url_idx;
+
+ALTER TABLE posts
+DROP COLUMN canonical_url,
+DROP COLUMN simhash,
+DROP COLUMN cluster_id;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- browse pages through posts in (updated_at, id) order starting after a given post
+CREATE INDEX posts_updated_at_id_idx ON posts (updated_at, id);
+
+-- +goose Down
+DROP INDEX posts_updated_at_id_idx;
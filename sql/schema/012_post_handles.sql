@@ -0,0 +1,21 @@
+-- +goose Up
+-- handles are short per user numbers standing in for ids on the command line,
+-- the counter keeps handles of deleted rows from being given out again
+CREATE TABLE handle_counters (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    last_handle INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, kind)
+);
+
+CREATE TABLE post_handles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    handle INTEGER NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    UNIQUE (user_id, handle)
+);
+
+-- +goose Down
+DROP TABLE post_handles;
+DROP TABLE handle_counters;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE feed_handles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    handle INTEGER NOT NULL,
+    PRIMARY KEY (user_id, feed_id),
+    UNIQUE (user_id, handle)
+);
+
+-- +goose Down
+DROP TABLE feed_handles;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- the user registered first looks after the installation
+UPDATE users SET is_admin = TRUE
+WHERE id = (SELECT id FROM users ORDER BY created_at, id LIMIT 1);
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN is_admin;
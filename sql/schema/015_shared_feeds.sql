@@ -0,0 +1,22 @@
+-- +goose Up
+-- feeds are shared by everyone who follows them, the user who added one is kept for the record
+-- and cleared when that user is deleted instead of deleting the feed
+ALTER TABLE feeds DROP CONSTRAINT feeds_user_id_fkey;
+ALTER TABLE feeds RENAME COLUMN user_id TO added_by;
+ALTER TABLE feeds ALTER COLUMN added_by DROP NOT NULL;
+ALTER TABLE feeds ADD CONSTRAINT feeds_added_by_fkey FOREIGN KEY (added_by) REFERENCES users(id) ON DELETE SET NULL;
+
+-- feeds are collected when their last follow goes
+CREATE INDEX feed_follows_feed_id_idx ON feed_follows (feed_id);
+
+-- +goose Down
+DROP INDEX feed_follows_feed_id_idx;
+
+-- feeds added by a deleted user go to the earliest user, without users they can't be kept
+UPDATE feeds SET added_by = (SELECT id FROM users ORDER BY created_at LIMIT 1) WHERE added_by IS NULL;
+DELETE FROM feeds WHERE added_by IS NULL;
+
+ALTER TABLE feeds DROP CONSTRAINT feeds_added_by_fkey;
+ALTER TABLE feeds ALTER COLUMN added_by SET NOT NULL;
+ALTER TABLE feeds RENAME COLUMN added_by TO user_id;
+ALTER TABLE feeds ADD CONSTRAINT feeds_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
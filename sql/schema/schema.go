@@ -0,0 +1,7 @@
+// Package schema embeds the migrations so the binary can set up and upgrade its own database
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,8 @@
+-- name: CreateFeedFollow :one
+-- sqlite has no data modifying CTEs, the names are read in the RETURNING clause instead
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES ($1,$2,$3,$4,$5)
+RETURNING
+  id, created_at, updated_at, user_id, feed_id, display_name, priority, muted, notify,
+  (SELECT feeds.name FROM feeds WHERE feeds.id = feed_follows.feed_id) as feed_name,
+  (SELECT users.name FROM users WHERE users.id = feed_follows.user_id) as user_name;
@@ -0,0 +1,68 @@
+-- the id lists are passed as json arrays. The counters are moved forward by the
+-- post_handles_counter and feed_handles_counter triggers.
+
+-- name: AssignPostHandles :exec
+WITH new_posts AS MATERIALIZED (
+  SELECT
+    ids.value as post_id,
+    ROW_NUMBER() OVER (ORDER BY ids.key) as n
+  FROM
+    json_each($1) AS ids
+  WHERE NOT EXISTS (
+    SELECT 1
+    FROM post_handles
+    WHERE post_handles.user_id = $2
+      AND post_handles.post_id = ids.value
+  )
+),
+counter AS MATERIALIZED (
+  SELECT COALESCE(
+    (SELECT last_handle FROM handle_counters WHERE user_id = $2 AND kind = 'post'),
+    0
+  ) as first_handle
+)
+INSERT INTO post_handles (user_id, post_id, handle)
+SELECT
+  $2,
+  new_posts.post_id,
+  counter.first_handle + new_posts.n
+FROM new_posts, counter;
+
+-- name: GetPostHandles :many
+SELECT post_id, handle
+FROM post_handles
+WHERE user_id = $1
+  AND post_id IN (SELECT value FROM json_each($2));
+
+-- name: AssignFeedHandles :exec
+WITH new_feeds AS MATERIALIZED (
+  SELECT
+    ids.value as feed_id,
+    ROW_NUMBER() OVER (ORDER BY ids.key) as n
+  FROM
+    json_each($1) AS ids
+  WHERE NOT EXISTS (
+    SELECT 1
+    FROM feed_handles
+    WHERE feed_handles.user_id = $2
+      AND feed_handles.feed_id = ids.value
+  )
+),
+counter AS MATERIALIZED (
+  SELECT COALESCE(
+    (SELECT last_handle FROM handle_counters WHERE user_id = $2 AND kind = 'feed'),
+    0
+  ) as first_handle
+)
+INSERT INTO feed_handles (user_id, feed_id, handle)
+SELECT
+  $2,
+  new_feeds.feed_id,
+  counter.first_handle + new_feeds.n
+FROM new_feeds, counter;
+
+-- name: GetFeedHandles :many
+SELECT feed_id, handle
+FROM feed_handles
+WHERE user_id = $1
+  AND feed_id IN (SELECT value FROM json_each($2));
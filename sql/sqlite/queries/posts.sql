@@ -0,0 +1,150 @@
+-- sqlite has no arrays, cluster_feeds is built as a postgres array literal so it scans the same way
+
+-- name: GetPostsForUser :many
+WITH visible AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    COALESCE(user_post_states.title, posts.title) as title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    COALESCE(feed_follows.display_name, feeds.name) as feed_name,
+    COALESCE(user_post_states.flagged, FALSE) as flagged,
+    ROW_NUMBER() OVER (PARTITION BY posts.cluster_id ORDER BY posts.created_at, posts.id) as cluster_position,
+    '{' || GROUP_CONCAT(
+      '"' || REPLACE(REPLACE(COALESCE(feed_follows.display_name, feeds.name), '\', '\\'), '"', '\"') || '"',
+      ','
+    ) OVER (PARTITION BY posts.cluster_id) || '}' as cluster_feeds
+  FROM
+    posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+    INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+      AND user_post_states.user_id = feed_follows.user_id
+  WHERE
+    feed_follows.user_id = $1
+    AND NOT feed_follows.muted
+    AND NOT COALESCE(user_post_states.hidden, FALSE)
+    AND NOT COALESCE(user_post_states.deleted, FALSE)
+)
+SELECT
+  id,
+  created_at,
+  updated_at,
+  title,
+  url,
+  description,
+  published_at,
+  feed_name,
+  flagged,
+  cluster_feeds
+FROM visible
+WHERE cluster_position = 1
+  AND (
+    $3 IS NULL
+    OR (updated_at, id) > (SELECT posts.updated_at, posts.id FROM posts WHERE posts.id = $3)
+  )
+ORDER BY
+  updated_at ASC,
+  id ASC
+LIMIT
+  $2;
+
+-- name: GetPostsForUserInCategory :many
+WITH visible AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.updated_at,
+    COALESCE(user_post_states.title, posts.title) as title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    COALESCE(feed_follows.display_name, feeds.name) as feed_name,
+    COALESCE(user_post_states.flagged, FALSE) as flagged,
+    ROW_NUMBER() OVER (PARTITION BY posts.cluster_id ORDER BY posts.created_at, posts.id) as cluster_position,
+    '{' || GROUP_CONCAT(
+      '"' || REPLACE(REPLACE(COALESCE(feed_follows.display_name, feeds.name), '\', '\\'), '"', '\"') || '"',
+      ','
+    ) OVER (PARTITION BY posts.cluster_id) || '}' as cluster_feeds
+  FROM
+    posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+    INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    INNER JOIN feed_follow_categories ON feed_follow_categories.feed_follow_id = feed_follows.id
+    LEFT JOIN user_post_states ON user_post_states.post_id = posts.id
+      AND user_post_states.user_id = feed_follows.user_id
+  WHERE
+    feed_follows.user_id = $1
+    AND feed_follow_categories.category_id = $2
+    AND NOT feed_follows.muted
+    AND NOT COALESCE(user_post_states.hidden, FALSE)
+    AND NOT COALESCE(user_post_states.deleted, FALSE)
+)
+SELECT
+  id,
+  created_at,
+  updated_at,
+  title,
+  url,
+  description,
+  published_at,
+  feed_name,
+  flagged,
+  cluster_feeds
+FROM visible
+WHERE cluster_position = 1
+  AND (
+    $4 IS NULL
+    OR (updated_at, id) > (SELECT posts.updated_at, posts.id FROM posts WHERE posts.id = $4)
+  )
+ORDER BY
+  updated_at ASC,
+  id ASC
+LIMIT
+  $3;
+
+-- name: GetPrunablePosts :many
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.title,
+    posts.created_at,
+    posts.published_at,
+    ROW_NUMBER() OVER (ORDER BY posts.published_at DESC, posts.created_at DESC) AS position
+  FROM posts
+  WHERE posts.feed_id = $1
+)
+SELECT id, title, published_at
+FROM ranked
+WHERE position > $2
+  AND created_at < $3
+  AND (
+    published_at < $4
+    OR position > $5
+  )
+ORDER BY published_at DESC;
+
+-- name: DeletePrunablePosts :execrows
+WITH ranked AS (
+  SELECT
+    posts.id,
+    posts.created_at,
+    posts.published_at,
+    ROW_NUMBER() OVER (ORDER BY posts.published_at DESC, posts.created_at DESC) AS position
+  FROM posts
+  WHERE posts.feed_id = $1
+)
+DELETE FROM posts
+WHERE posts.id IN (
+  SELECT ranked.id
+  FROM ranked
+  WHERE ranked.position > $2
+    AND ranked.created_at < $3
+    AND (
+      ranked.published_at < $4
+      OR ranked.position > $5
+    )
+);
@@ -0,0 +1,8 @@
+// Package queries embeds the sqlite variants of the queries in sql/queries that use postgres only syntax,
+// each is matched to the generated query by its '-- name:' line
+package queries
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
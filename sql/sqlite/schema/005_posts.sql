@@ -0,0 +1,16 @@
+-- +goose Up
+-- sqlite databases start out with a link unique per feed, postgres gets there in 010_duplicates
+CREATE TABLE posts (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    UNIQUE (feed_id, url)
+);
+
+-- +goose Down
+DROP TABLE posts;
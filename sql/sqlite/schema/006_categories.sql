@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE categories (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follow_categories (
+    feed_follow_id TEXT NOT NULL REFERENCES feed_follows(id) ON DELETE CASCADE,
+    category_id TEXT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (feed_follow_id, category_id)
+);
+
+-- +goose Down
+DROP TABLE feed_follow_categories;
+DROP TABLE categories;
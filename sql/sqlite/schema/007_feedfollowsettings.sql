@@ -0,0 +1,12 @@
+-- +goose Up
+-- sqlite adds and drops one column per statement
+ALTER TABLE feed_follows ADD COLUMN display_name TEXT;
+ALTER TABLE feed_follows ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feed_follows ADD COLUMN muted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE feed_follows ADD COLUMN notify BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN display_name;
+ALTER TABLE feed_follows DROP COLUMN priority;
+ALTER TABLE feed_follows DROP COLUMN muted;
+ALTER TABLE feed_follows DROP COLUMN notify;
@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author TEXT;
+ALTER TABLE posts ADD COLUMN category TEXT;
+
+CREATE TABLE rules (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    field TEXT NOT NULL,
+    match_type TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    case_sensitive BOOLEAN NOT NULL DEFAULT FALSE,
+    action TEXT NOT NULL,
+    replacement TEXT
+);
+
+CREATE TABLE user_post_states (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    updated_at TIMESTAMP NOT NULL,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    flagged BOOLEAN NOT NULL DEFAULT FALSE,
+    title TEXT,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE user_post_states;
+DROP TABLE rules;
+
+ALTER TABLE posts DROP COLUMN author;
+ALTER TABLE posts DROP COLUMN category;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- sqlite can not add NOT NULL to an existing column, the columns are added with
+-- a placeholder default that the update below replaces
+ALTER TABLE posts ADD COLUMN canonical_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN simhash BIGINT;
+ALTER TABLE posts ADD COLUMN cluster_id TEXT NOT NULL DEFAULT '';
+
+-- existing posts start out in a cluster of their own
+UPDATE posts SET canonical_url = url, cluster_id = id;
+
+CREATE INDEX posts_canonical_url_idx ON posts (canonical_url);
+CREATE INDEX posts_cluster_id_idx ON posts (cluster_id);
+CREATE INDEX posts_created_at_idx ON posts (created_at);
+
+-- +goose Down
+DROP INDEX posts_created_at_idx;
+DROP INDEX posts_cluster_id_idx;
+DROP INDEX posts_canonical_url_idx;
+
+ALTER TABLE posts DROP COLUMN canonical_url;
+ALTER TABLE posts DROP COLUMN simhash;
+ALTER TABLE posts DROP COLUMN cluster_id;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- handles are short per user numbers standing in for ids on the command line,
+-- the counter keeps handles of deleted rows from being given out again
+CREATE TABLE handle_counters (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    last_handle INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, kind)
+);
+
+CREATE TABLE post_handles (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    handle INTEGER NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    UNIQUE (user_id, handle)
+);
+
+-- sqlite has no data modifying CTEs so the counter is moved forward by a trigger
+-- +goose StatementBegin
+CREATE TRIGGER post_handles_counter AFTER INSERT ON post_handles
+BEGIN
+  INSERT INTO handle_counters (user_id, kind, last_handle)
+  VALUES (NEW.user_id, 'post', NEW.handle)
+  ON CONFLICT (user_id, kind) DO UPDATE
+  SET last_handle = MAX(handle_counters.last_handle, excluded.last_handle);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER post_handles_counter;
+DROP TABLE post_handles;
+DROP TABLE handle_counters;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE feed_handles (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    handle INTEGER NOT NULL,
+    PRIMARY KEY (user_id, feed_id),
+    UNIQUE (user_id, handle)
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER feed_handles_counter AFTER INSERT ON feed_handles
+BEGIN
+  INSERT INTO handle_counters (user_id, kind, last_handle)
+  VALUES (NEW.user_id, 'feed', NEW.handle)
+  ON CONFLICT (user_id, kind) DO UPDATE
+  SET last_handle = MAX(handle_counters.last_handle, excluded.last_handle);
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER feed_handles_counter;
+DROP TABLE feed_handles;
@@ -0,0 +1,48 @@
+-- +goose Up
+-- feeds are shared by everyone who follows them, the user who added one is kept for the record
+-- and cleared when that user is deleted instead of deleting the feed. sqlite can't change a
+-- foreign key in place so the table is rebuilt, the migrator turns foreign keys off while it runs.
+CREATE TABLE feeds_new (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    added_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    last_fetched_at TIMESTAMP,
+    retention_max_age TEXT,
+    retention_max_posts INTEGER
+);
+INSERT INTO feeds_new (id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts)
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, retention_max_age, retention_max_posts
+FROM feeds;
+DROP TABLE feeds;
+ALTER TABLE feeds_new RENAME TO feeds;
+
+-- feeds are collected when their last follow goes
+CREATE INDEX feed_follows_feed_id_idx ON feed_follows (feed_id);
+
+-- +goose Down
+DROP INDEX feed_follows_feed_id_idx;
+
+-- feeds added by a deleted user go to the earliest user, without users they can't be kept
+UPDATE feeds SET added_by = (SELECT id FROM users ORDER BY created_at LIMIT 1) WHERE added_by IS NULL;
+DELETE FROM posts WHERE feed_id IN (SELECT id FROM feeds WHERE added_by IS NULL);
+DELETE FROM feeds WHERE added_by IS NULL;
+
+CREATE TABLE feeds_old (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP,
+    retention_max_age TEXT,
+    retention_max_posts INTEGER
+);
+INSERT INTO feeds_old (id, created_at, updated_at, name, url, user_id, last_fetched_at, retention_max_age, retention_max_posts)
+SELECT id, created_at, updated_at, name, url, added_by, last_fetched_at, retention_max_age, retention_max_posts
+FROM feeds;
+DROP TABLE feeds;
+ALTER TABLE feeds_old RENAME TO feeds;
@@ -0,0 +1,7 @@
+// Package schema embeds the sqlite translation of the migrations in sql/schema, versions match one to one
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
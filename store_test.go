@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/joshhartwig/gator/internal/database"
+)
+
+// newSQLiteState returns a state backed by a migrated sqlite database in a temporary directory
+func newSQLiteState(t *testing.T) *state {
+	t.Helper()
+	pool, db, dialect, err := openStore("sqlite:" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	s := &state{db: db, pool: pool, dialect: dialect}
+	m, err := newMigrator(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// backends are the stores the transaction tests run against
+var backends = map[string]func(t *testing.T) *state{
+	"memory": func(t *testing.T) *state {
+		s, _ := newTestState(t)
+		return s
+	},
+	"sqlite": newSQLiteState,
+}
+
+func TestInTx(t *testing.T) {
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+
+			err := inTx(s, func(tx *state) error {
+				addUser(t, tx, "alice")
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("wanted the transaction committed got %v", err)
+			}
+
+			boom := errors.New("boom")
+			err = inTx(s, func(tx *state) error {
+				bob := addUser(t, tx, "bob")
+				addFollowedFeed(t, tx, bob, "news", "https://example.com/news")
+				return boom
+			})
+			if !errors.Is(err, boom) {
+				t.Fatalf("wanted the error from fn got %v", err)
+			}
+
+			users, err := s.db.GetUsers(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 1 || users[0].Name != "alice" {
+				t.Errorf("wanted only alice after the rollback got %+v", users)
+			}
+			if _, err := s.db.GetFeedByUrl(context.Background(), "https://example.com/news"); err == nil {
+				t.Errorf("wanted the feed rolled back")
+			}
+		})
+	}
+}
+
+func TestStorePostsSkipsStoredPosts(t *testing.T) {
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			alice := addUser(t, s, "alice")
+			feed := addFollowedFeed(t, s, alice, "news", "https://example.com/news")
+
+			items := []RSSItem{
+				{Title: "First post", Link: "https://example.com/1"},
+				{Title: "Second post", Link: "https://example.com/2"},
+			}
+			var added []RSSItem
+			err := inTx(s, func(tx *state) error {
+				var err error
+				added, err = storePosts(tx, feed, items[:1])
+				return err
+			})
+			if err != nil || len(added) != 1 {
+				t.Fatalf("wanted the first post added got %d %v", len(added), err)
+			}
+
+			// the first post is already stored, the second is still added in the same transaction
+			err = inTx(s, func(tx *state) error {
+				var err error
+				added, err = storePosts(tx, feed, items)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("wanted the stored post skipped got %v", err)
+			}
+			if len(added) != 1 || added[0].Title != "Second post" {
+				t.Errorf("wanted only the second post added got %+v", added)
+			}
+
+			posts, err := s.db.GetPostsForFeed(context.Background(), database.GetPostsForFeedParams{
+				UserID: alice.ID,
+				FeedID: feed.ID,
+				Limit:  10,
+			})
+			if err != nil || len(posts) != 2 {
+				t.Errorf("wanted two posts stored got %d %v", len(posts), err)
+			}
+		})
+	}
+}